@@ -0,0 +1,97 @@
+// Package keccak implements the legacy Keccak-256 hash used by Ethereum, which differs from
+// the standardized SHA3-256 only in its padding.
+package keccak
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// rate is the number of bytes absorbed per permutation for a 256-bit output.
+const rate = 136
+
+var roundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// rotations holds the rho offsets, indexed like the state (x + 5*y).
+var rotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// Sum256 returns the Keccak-256 digest of the concatenation of data.
+func Sum256(data ...[]byte) [32]byte {
+	var state [25]uint64
+	var block [rate]byte
+	n := 0
+	for _, d := range data {
+		for len(d) > 0 {
+			c := copy(block[n:], d)
+			n += c
+			d = d[c:]
+			if n == rate {
+				absorb(&state, &block)
+				n = 0
+			}
+		}
+	}
+
+	// Keccak padding: 0x01 ... 0x80 (SHA3 uses 0x06 instead of 0x01).
+	clear(block[n:])
+	block[n] ^= 0x01
+	block[rate-1] ^= 0x80
+	absorb(&state, &block)
+
+	var digest [32]byte
+	for i := range 4 {
+		binary.LittleEndian.PutUint64(digest[8*i:], state[i])
+	}
+	return digest
+}
+
+// absorb XORs a block into the state and applies the permutation.
+func absorb(state *[25]uint64, block *[rate]byte) {
+	for i := range rate / 8 {
+		state[i] ^= binary.LittleEndian.Uint64(block[8*i:])
+	}
+	permute(state)
+}
+
+// permute applies Keccak-f[1600].
+func permute(a *[25]uint64) {
+	var b [25]uint64
+	var c, d [5]uint64
+	for _, rc := range roundConstants {
+		// Theta.
+		for x := range 5 {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := range 5 {
+			d[x] = c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+		}
+		// Rho and pi.
+		for x := range 5 {
+			for y := range 5 {
+				i := x + 5*y
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[i]^d[x], rotations[i])
+			}
+		}
+		// Chi.
+		for y := 0; y < 25; y += 5 {
+			for x := range 5 {
+				a[y+x] = b[y+x] ^ (^b[y+(x+1)%5] & b[y+(x+2)%5])
+			}
+		}
+		// Iota.
+		a[0] ^= rc
+	}
+}
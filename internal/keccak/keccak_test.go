@@ -0,0 +1,29 @@
+package keccak
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSum256(t *testing.T) {
+	long := bytes.Repeat([]byte("abcdefghij"), 50)
+	tests := []struct {
+		name string
+		data [][]byte
+		want string
+	}{
+		{"empty", nil, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", [][]byte{[]byte("abc")}, "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"fox", [][]byte{[]byte("The quick brown fox jumps over the lazy dog")}, "4d741b6f1eb29cb2a9b9911c82f56fa8d73b04959d3d9d222895df6c0b28aa15"},
+		{"selector", [][]byte{[]byte("transfer(address,uint256)")}, "a9059cbb2ab09eb219583f4a59a5d0623ade346d962bcd4e46b11da047c9049b"},
+		{"multi-block", [][]byte{long}, "f7dd2c240bf1bc8d174a3cb62f1b14a29cd7817d83cc982bc47b1efcc8e62af2"},
+		{"split input", [][]byte{long[:100], nil, long[100:]}, "f7dd2c240bf1bc8d174a3cb62f1b14a29cd7817d83cc982bc47b1efcc8e62af2"},
+	}
+	for _, tt := range tests {
+		got := Sum256(tt.data...)
+		if hex.EncodeToString(got[:]) != tt.want {
+			t.Errorf("%s: Sum256 = %x, want %s", tt.name, got, tt.want)
+		}
+	}
+}
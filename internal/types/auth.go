@@ -0,0 +1,126 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AuthConfig describes how requests to the endpoints of an RpcTarget authenticate. All
+// configured schemes are applied, to HTTP requests and WebSocket handshakes alike.
+type AuthConfig struct {
+	// Bearer sends "Authorization: Bearer <token>".
+	Bearer *SecretRef `json:"bearer,omitempty" yaml:"bearer,omitempty"`
+	// Basic sends HTTP Basic credentials.
+	Basic *BasicAuth `json:"basic,omitempty" yaml:"basic,omitempty"`
+	// JWT sends a fresh HS256 token per request, as required by the Engine API.
+	JWT *JWTAuth `json:"jwt,omitempty" yaml:"jwt,omitempty"`
+	// Headers are sent as is, e.g. "x-api-key".
+	Headers map[string]SecretRef `json:"headers,omitempty" yaml:"headers,omitempty"`
+}
+
+// BasicAuth holds HTTP Basic credentials.
+type BasicAuth struct {
+	Username string    `json:"username" yaml:"username"`
+	Password SecretRef `json:"password" yaml:"password"`
+}
+
+// JWTAuth configures Engine API authentication: an HS256 token with an "iat" claim, signed
+// with a 32-byte hex secret such as the contents of a node's jwtsecret file.
+type JWTAuth struct {
+	Secret SecretRef `json:"secret" yaml:"secret"`
+	// ID is sent as the optional "id" claim identifying the client.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+}
+
+// SecretRef locates a secret: inline, in an environment variable or in a file. Exactly one
+// should be set. Its String, JSON and YAML forms never contain an inline value.
+type SecretRef struct {
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	Env   string `json:"env,omitempty" yaml:"env,omitempty"`
+	File  string `json:"file,omitempty" yaml:"file,omitempty"`
+}
+
+// redacted replaces secret values wherever a SecretRef is printed or serialised.
+const redacted = "[redacted]"
+
+// Resolve returns the secret. File contents are trimmed of surrounding whitespace. Errors name
+// the variable or file but never contain the secret.
+func (s SecretRef) Resolve() (string, error) {
+	switch {
+	case s.Value != "":
+		return s.Value, nil
+	case s.Env != "":
+		v, ok := os.LookupEnv(s.Env)
+		if !ok || v == "" {
+			return "", fmt.Errorf("secret environment variable %s is not set", s.Env)
+		}
+		return v, nil
+	case s.File != "":
+		b, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file %s: %w", s.File, err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	default:
+		return "", fmt.Errorf("empty secret reference")
+	}
+}
+
+// String describes where the secret comes from without revealing it.
+func (s SecretRef) String() string {
+	switch {
+	case s.Value != "":
+		return redacted
+	case s.Env != "":
+		return "env:" + s.Env
+	case s.File != "":
+		return "file:" + s.File
+	default:
+		return "<empty>"
+	}
+}
+
+// GoString keeps %#v from printing the secret.
+func (s SecretRef) GoString() string {
+	return "SecretRef(" + s.String() + ")"
+}
+
+// MarshalJSON serialises the reference with any inline value redacted.
+func (s SecretRef) MarshalJSON() ([]byte, error) {
+	type plain SecretRef
+	if s.Value != "" {
+		s.Value = redacted
+	}
+	return json.Marshal(plain(s))
+}
+
+// MarshalYAML serialises the reference with any inline value redacted.
+func (s SecretRef) MarshalYAML() (any, error) {
+	type plain SecretRef
+	if s.Value != "" {
+		s.Value = redacted
+	}
+	return plain(s), nil
+}
+
+// AuthFor returns the auth configuration of the RpcTarget that lists url, or nil.
+func (c Chain) AuthFor(url string) *AuthConfig {
+	for _, target := range c.RPCUrls {
+		if target.Auth == nil {
+			continue
+		}
+		for _, u := range target.Http {
+			if u == url {
+				return target.Auth
+			}
+		}
+		for _, u := range target.WebSocket {
+			if u == url {
+				return target.Auth
+			}
+		}
+	}
+	return nil
+}
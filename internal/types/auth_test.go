@@ -0,0 +1,102 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSecretRef_Resolve(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TYPES_TEST_SECRET", "from-env")
+
+	tests := []struct {
+		name    string
+		ref     SecretRef
+		want    string
+		wantErr bool
+	}{
+		{name: "inline", ref: SecretRef{Value: "inline"}, want: "inline"},
+		{name: "env", ref: SecretRef{Env: "TYPES_TEST_SECRET"}, want: "from-env"},
+		{name: "file", ref: SecretRef{File: file}, want: "from-file"},
+		{name: "missing env", ref: SecretRef{Env: "TYPES_TEST_UNSET"}, wantErr: true},
+		{name: "missing file", ref: SecretRef{File: file + ".missing"}, wantErr: true},
+		{name: "empty", ref: SecretRef{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.ref.Resolve()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretRef_NeverPrinted(t *testing.T) {
+	const secret = "s3cr3t-value"
+	auth := AuthConfig{
+		Bearer:  &SecretRef{Value: secret},
+		Basic:   &BasicAuth{Username: "user", Password: SecretRef{Value: secret}},
+		JWT:     &JWTAuth{Secret: SecretRef{Value: secret}},
+		Headers: map[string]SecretRef{"x-api-key": {Value: secret}},
+	}
+
+	encoded, err := json.Marshal(auth)
+	if err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+	encodedYAML, err := yaml.Marshal(RpcTarget{Http: []string{"https://rpc.example.test"}, Auth: &auth})
+	if err != nil {
+		t.Fatalf("yaml.Marshal error = %v", err)
+	}
+	outputs := []string{
+		string(encoded),
+		string(encodedYAML),
+		fmt.Sprintf("%v", auth),
+		fmt.Sprintf("%+v", auth),
+		fmt.Sprintf("%v %+v %#v", *auth.Basic, *auth.JWT, *auth.Bearer),
+		fmt.Sprint(auth.Headers),
+	}
+	for _, out := range outputs {
+		if strings.Contains(out, secret) {
+			t.Errorf("secret leaked: %s", out)
+		}
+	}
+	if !strings.Contains(string(encoded), `"x-api-key":{"value":"[redacted]"}`) {
+		t.Errorf("Marshal = %s", encoded)
+	}
+	if !strings.Contains(string(encodedYAML), "value: '[redacted]'") {
+		t.Errorf("yaml.Marshal = %s", encodedYAML)
+	}
+	if got := (SecretRef{Env: "API_KEY"}).String(); got != "env:API_KEY" {
+		t.Errorf("String() = %s", got)
+	}
+}
+
+func TestChain_AuthFor(t *testing.T) {
+	auth := &AuthConfig{Bearer: &SecretRef{Env: "TOKEN"}}
+	chain := Chain{RPCUrls: map[string]RpcTarget{
+		"private": {Http: []string{"https://private"}, WebSocket: []string{"wss://private"}, Auth: auth},
+		"public":  {Http: []string{"https://public"}},
+	}}
+
+	if chain.AuthFor("https://private") != auth || chain.AuthFor("wss://private") != auth {
+		t.Error("AuthFor should return the target's auth for its HTTP and WebSocket URLs")
+	}
+	if chain.AuthFor("https://public") != nil || chain.AuthFor("https://unknown") != nil {
+		t.Error("AuthFor should return nil for endpoints without auth")
+	}
+}
@@ -1,6 +1,9 @@
 package types
 
-import "math/big"
+import (
+	"math/big"
+	"time"
+)
 
 // NativeCurrency represents the native currency of the chain.
 // Example: Ether for Ethereum Mainnet (Symbol: ETH, Decimals: 18).
@@ -12,8 +15,17 @@ type NativeCurrency struct {
 
 // RpcTarget holds the RPC endpoints for a network provider (e.g., default, infura).
 type RpcTarget struct {
-	Http      []string `json:"http,omitempty" yaml:"http,omitempty"`
-	WebSocket []string `json:"webSocket,omitempty" yaml:"webSocket,omitempty"`
+	Http      []string   `json:"http,omitempty" yaml:"http,omitempty"`
+	WebSocket []string   `json:"webSocket,omitempty" yaml:"webSocket,omitempty"`
+	RateLimit *RateLimit `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	// Auth, if set, authenticates requests to every URL of this target.
+	Auth *AuthConfig `json:"auth,omitempty" yaml:"auth,omitempty"`
+}
+
+// RateLimit describes the request budget of an RPC host.
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond" yaml:"requestsPerSecond"`
+	Burst             int     `json:"burst" yaml:"burst"`
 }
 
 // BlockExplorer represents a block explorer for the chain.
@@ -44,4 +56,6 @@ type Chain struct {
 	IsTestnet            bool                     `json:"isTestnet,omitempty" yaml:"isTestnet,omitempty"`
 	EnsRegistry          *Contract                `json:"ensRegistry,omitempty" yaml:"ensRegistry,omitempty"`
 	EnsUniversalResolver *Contract                `json:"ensUniversalResolver,omitempty" yaml:"ensUniversalResolver,omitempty"`
+	// BlockTime is the chain's target block interval (0 if unknown). It paces polling loops.
+	BlockTime time.Duration `json:"blockTime,omitempty" yaml:"blockTime,omitempty"`
 }
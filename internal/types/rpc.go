@@ -1,9 +1,13 @@
 package types
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/big"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,6 +20,140 @@ type RPCStatus struct {
 	Latency     time.Duration `json:"latency"`
 	BlockNumber *big.Int      `json:"blockNumber,omitempty"`
 	Error       error         `json:"error,omitempty"`
+
+	// ColdLatency is the latency of a check that had to open a new connection, including the
+	// TCP, TLS and WebSocket handshakes (zero if an idle connection was reused).
+	ColdLatency time.Duration `json:"coldLatency,omitempty"`
+	// WarmLatency is the latency of a request over an established connection. Latency is set to
+	// it whenever it was measured.
+	WarmLatency time.Duration `json:"warmLatency,omitempty"`
+
+	// BlockLag is the number of blocks this endpoint trails the chain's consensus head.
+	BlockLag uint64 `json:"blockLag"`
+	// IsSyncing reports whether eth_syncing returned a sync progress object.
+	IsSyncing bool `json:"isSyncing,omitempty"`
+	// BlockTimestamp is the timestamp of the endpoint's latest block, if it was requested.
+	BlockTimestamp time.Time `json:"blockTimestamp,omitempty"`
+	// SupportsBatch reports whether the endpoint answered a JSON-RPC batch, if that was checked.
+	SupportsBatch bool `json:"supportsBatch,omitempty"`
+	// IsRateLimited reports whether the endpoint rejected the check because of rate limiting.
+	IsRateLimited bool `json:"isRateLimited,omitempty"`
+	// CheckError is the failure of a follow-up check (eth_syncing or the latest block) of an
+	// endpoint that answered eth_blockNumber. Unlike Error, it leaves the endpoint available and
+	// marks it degraded.
+	CheckError error `json:"checkError,omitempty"`
+	// IsDegraded marks an endpoint that answered but is lagging, syncing or serving a stale head.
+	IsDegraded bool `json:"isDegraded,omitempty"`
+	// DegradedReason describes why IsDegraded was set.
+	DegradedReason string `json:"degradedReason,omitempty"`
+}
+
+// JsonRPCID is a JSON-RPC request ID, which may be a number, a string or null. The zero value
+// is an absent ID, as in notifications. IDs are comparable, so they can be used as map keys:
+// numbers and strings are kept in a canonical encoding, so 1 and 1.0 are the same ID but 1 and
+// "1" are not.
+type JsonRPCID struct {
+	raw string
+}
+
+// NullID is the explicit null ID.
+var NullID = JsonRPCID{raw: "null"}
+
+// NumberID returns a numeric ID.
+func NumberID(n int64) JsonRPCID {
+	return JsonRPCID{raw: strconv.FormatInt(n, 10)}
+}
+
+// StringID returns a string ID.
+func StringID(s string) JsonRPCID {
+	raw, _ := json.Marshal(s)
+	return JsonRPCID{raw: string(raw)}
+}
+
+// IsAbsent reports whether the message carried no ID at all.
+func (id JsonRPCID) IsAbsent() bool {
+	return id.raw == ""
+}
+
+// IsNull reports whether the ID is an explicit null.
+func (id JsonRPCID) IsNull() bool {
+	return id.raw == "null"
+}
+
+// Int64 returns the value of an integral numeric ID.
+func (id JsonRPCID) Int64() (int64, bool) {
+	n, err := strconv.ParseInt(id.raw, 10, 64)
+	return n, err == nil
+}
+
+// Str returns the value of a string ID.
+func (id JsonRPCID) Str() (string, bool) {
+	if !strings.HasPrefix(id.raw, `"`) {
+		return "", false
+	}
+	var s string
+	return s, json.Unmarshal([]byte(id.raw), &s) == nil
+}
+
+// Matches reports whether a response ID answers a request ID. Besides exact equality it accepts a
+// string holding the decimal form of a numeric ID, which some proxies echo instead.
+func (id JsonRPCID) Matches(request JsonRPCID) bool {
+	if id == request {
+		return true
+	}
+	n, ok := request.Int64()
+	return ok && id == StringID(strconv.FormatInt(n, 10))
+}
+
+// String returns the JSON encoding of the ID, or "<absent>".
+func (id JsonRPCID) String() string {
+	if id.IsAbsent() {
+		return "<absent>"
+	}
+	return id.raw
+}
+
+// MarshalJSON encodes the ID. An absent ID is encoded as null.
+func (id JsonRPCID) MarshalJSON() ([]byte, error) {
+	if id.IsAbsent() {
+		return []byte("null"), nil
+	}
+	return []byte(id.raw), nil
+}
+
+// UnmarshalJSON decodes a number, string or null ID.
+func (id *JsonRPCID) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0:
+		return fmt.Errorf("empty json-rpc id")
+	case string(data) == "null":
+		*id = NullID
+	case data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("invalid json-rpc id %s: %w", data, err)
+		}
+		*id = StringID(s)
+	default:
+		var n json.Number
+		if err := json.Unmarshal(data, &n); err != nil {
+			return fmt.Errorf("invalid json-rpc id %s: must be a number, string or null", data)
+		}
+		*id = canonicalNumberID(n)
+	}
+	return nil
+}
+
+// canonicalNumberID normalises integral numbers such as 1.0 or 1e2 to their integer form.
+func canonicalNumberID(n json.Number) JsonRPCID {
+	if i, err := n.Int64(); err == nil {
+		return NumberID(i)
+	}
+	if f, err := n.Float64(); err == nil && f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+		return NumberID(int64(f))
+	}
+	return JsonRPCID{raw: n.String()}
 }
 
 // JsonRPCRequest represents a JSON-RPC request object.
@@ -23,13 +161,13 @@ type JsonRPCRequest struct {
 	Version string        `json:"jsonrpc"`
 	Method  string        `json:"method"`
 	Params  []interface{} `json:"params"`
-	ID      int           `json:"id"`
+	ID      JsonRPCID     `json:"id"`
 }
 
 // JsonRPCResponse represents a JSON-RPC response object.
 type JsonRPCResponse struct {
 	Version string          `json:"jsonrpc"`
-	ID      int             `json:"id"`
+	ID      JsonRPCID       `json:"id"`
 	Result  json.RawMessage `json:"result,omitempty"`
 	Error   *JsonRPCError   `json:"error,omitempty"`
 }
@@ -38,8 +176,56 @@ type JsonRPCResponse struct {
 type JsonRPCError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	// Data holds the optional error data undecoded, e.g. the revert data of eth_call.
+	Data json.RawMessage `json:"data,omitempty"`
 }
 
 func (e *JsonRPCError) Error() string {
 	return fmt.Sprintf("RPC error %d: %s", e.Code, e.Message)
 }
+
+// JsonRPCNotification represents a server-sent notification, such as an eth_subscription event.
+type JsonRPCNotification struct {
+	Version string              `json:"jsonrpc"`
+	Method  string              `json:"method"`
+	Params  JsonRPCSubscription `json:"params"`
+}
+
+// JsonRPCSubscription holds the params of an eth_subscription notification.
+type JsonRPCSubscription struct {
+	Subscription string          `json:"subscription"`
+	Result       json.RawMessage `json:"result"`
+}
+
+// JsonRPCMessage is any frame received from a node: a response (with an ID, possibly null) or
+// a notification (with a method and no ID). Use it where both can arrive, e.g. on WebSockets.
+type JsonRPCMessage struct {
+	Version string          `json:"jsonrpc"`
+	ID      JsonRPCID       `json:"id"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JsonRPCError   `json:"error,omitempty"`
+}
+
+// IsNotification reports whether the message is a notification rather than a response.
+func (m JsonRPCMessage) IsNotification() bool {
+	return m.ID.IsAbsent() && m.Method != ""
+}
+
+// Response returns the message as a response.
+func (m JsonRPCMessage) Response() JsonRPCResponse {
+	return JsonRPCResponse{Version: m.Version, ID: m.ID, Result: m.Result, Error: m.Error}
+}
+
+// Notification returns the message as a subscription notification.
+func (m JsonRPCMessage) Notification() (JsonRPCNotification, error) {
+	if !m.IsNotification() {
+		return JsonRPCNotification{}, fmt.Errorf("json-rpc message with id %s is not a notification", m.ID)
+	}
+	n := JsonRPCNotification{Version: m.Version, Method: m.Method}
+	if err := json.Unmarshal(m.Params, &n.Params); err != nil {
+		return JsonRPCNotification{}, fmt.Errorf("failed to unmarshal %s notification params: %w", m.Method, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,147 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJsonRPCID_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     JsonRPCID
+		wantJSON string
+		wantErr  bool
+	}{
+		{name: "number", input: `7`, want: NumberID(7), wantJSON: `7`},
+		{name: "negative number", input: `-3`, want: NumberID(-3), wantJSON: `-3`},
+		{name: "integral float", input: `7.0`, want: NumberID(7), wantJSON: `7`},
+		{name: "exponent", input: `1e2`, want: NumberID(100), wantJSON: `100`},
+		{name: "fraction", input: `1.5`, want: JsonRPCID{raw: "1.5"}, wantJSON: `1.5`},
+		{name: "string", input: `"abc"`, want: StringID("abc"), wantJSON: `"abc"`},
+		{name: "escaped string", input: `"\u0061bc"`, want: StringID("abc"), wantJSON: `"abc"`},
+		{name: "numeric string", input: `"7"`, want: StringID("7"), wantJSON: `"7"`},
+		{name: "null", input: `null`, want: NullID, wantJSON: `null`},
+		{name: "object", input: `{}`, wantErr: true},
+		{name: "bool", input: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var id JsonRPCID
+			err := json.Unmarshal([]byte(tt.input), &id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal(%s) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if id != tt.want {
+				t.Errorf("Unmarshal(%s) = %s, want %s", tt.input, id, tt.want)
+			}
+			encoded, err := json.Marshal(id)
+			if err != nil {
+				t.Fatalf("Marshal(%s) error = %v", id, err)
+			}
+			if string(encoded) != tt.wantJSON {
+				t.Errorf("Marshal(%s) = %s, want %s", id, encoded, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestJsonRPCID_Accessors(t *testing.T) {
+	if n, ok := NumberID(42).Int64(); !ok || n != 42 {
+		t.Errorf("NumberID(42).Int64() = %d, %v", n, ok)
+	}
+	if _, ok := StringID("42").Int64(); ok {
+		t.Error("StringID(42).Int64() should not be numeric")
+	}
+	if s, ok := StringID("sub").Str(); !ok || s != "sub" {
+		t.Errorf(`StringID("sub").Str() = %q, %v`, s, ok)
+	}
+	if !(JsonRPCID{}).IsAbsent() || NullID.IsAbsent() || !NullID.IsNull() {
+		t.Error("absent and null IDs must be distinguishable")
+	}
+	if !StringID("5").Matches(NumberID(5)) || !NumberID(5).Matches(NumberID(5)) {
+		t.Error("a numeric ID should match itself and its decimal string")
+	}
+	if NumberID(5).Matches(StringID("5")) || StringID("x").Matches(NumberID(5)) {
+		t.Error("Matches should not accept other IDs")
+	}
+}
+
+func TestJsonRPCMessage(t *testing.T) {
+	tests := []struct {
+		name             string
+		input            string
+		wantNotification bool
+		wantID           JsonRPCID
+		wantErrData      string
+	}{
+		{name: "numeric id", input: `{"jsonrpc":"2.0","id":1,"result":"0x1"}`, wantID: NumberID(1)},
+		{name: "string id", input: `{"jsonrpc":"2.0","id":"req-1","result":"0x1"}`, wantID: StringID("req-1")},
+		{name: "null id error", input: `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`, wantID: NullID},
+		{
+			name:        "error with data",
+			input:       `{"jsonrpc":"2.0","id":2,"error":{"code":3,"message":"execution reverted","data":"0x08c379a0"}}`,
+			wantID:      NumberID(2),
+			wantErrData: `"0x08c379a0"`,
+		},
+		{
+			name:             "subscription notification",
+			input:            `{"jsonrpc":"2.0","method":"eth_subscription","params":{"subscription":"0xab","result":{"number":"0x10"}}}`,
+			wantNotification: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg JsonRPCMessage
+			if err := json.Unmarshal([]byte(tt.input), &msg); err != nil {
+				t.Fatalf("Unmarshal error = %v", err)
+			}
+			if msg.IsNotification() != tt.wantNotification {
+				t.Fatalf("IsNotification() = %v, want %v", msg.IsNotification(), tt.wantNotification)
+			}
+
+			if tt.wantNotification {
+				n, err := msg.Notification()
+				if err != nil {
+					t.Fatalf("Notification() error = %v", err)
+				}
+				if n.Method != "eth_subscription" || n.Params.Subscription != "0xab" || string(n.Params.Result) != `{"number":"0x10"}` {
+					t.Errorf("Notification() = %+v", n)
+				}
+				return
+			}
+
+			if _, err := msg.Notification(); err == nil {
+				t.Error("Notification() on a response should fail")
+			}
+			resp := msg.Response()
+			if resp.ID != tt.wantID {
+				t.Errorf("ID = %s, want %s", resp.ID, tt.wantID)
+			}
+			if tt.wantErrData != "" && (resp.Error == nil || string(resp.Error.Data) != tt.wantErrData) {
+				t.Errorf("Error = %+v, want data %s", resp.Error, tt.wantErrData)
+			}
+		})
+	}
+}
+
+func TestJsonRPCResponse_RoundTrip(t *testing.T) {
+	resp := JsonRPCResponse{
+		Version: "2.0",
+		ID:      StringID("a"),
+		Error:   &JsonRPCError{Code: 3, Message: "execution reverted", Data: json.RawMessage(`"0x"`)},
+	}
+	encoded, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+	want := `{"jsonrpc":"2.0","id":"a","error":{"code":3,"message":"execution reverted","data":"0x"}}`
+	if string(encoded) != want {
+		t.Errorf("Marshal = %s, want %s", encoded, want)
+	}
+}
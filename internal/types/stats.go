@@ -0,0 +1,35 @@
+package types
+
+import (
+	"math/big"
+	"time"
+)
+
+// EndpointStats holds rolling health statistics for a single RPC endpoint.
+type EndpointStats struct {
+	ChainID     *big.Int `json:"chainId"`
+	URL         string   `json:"url"`
+	IsHTTP      bool     `json:"isHttp"`
+	IsWebSocket bool     `json:"isWebSocket"`
+
+	// LastStatus is the most recent probe result.
+	LastStatus RPCStatus `json:"lastStatus"`
+	// LatencyEWMA is the exponentially weighted moving average of successful probe latencies.
+	LatencyEWMA time.Duration `json:"latencyEwma"`
+	// LatencyP50 and LatencyP95 are percentiles over the rolling sample window.
+	LatencyP50 time.Duration `json:"latencyP50"`
+	LatencyP95 time.Duration `json:"latencyP95"`
+	// SuccessRatio is the fraction of successful probes in the rolling sample window.
+	SuccessRatio float64 `json:"successRatio"`
+	// Samples is the number of probes in the rolling sample window.
+	Samples             int       `json:"samples"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastSeenBlock       *big.Int  `json:"lastSeenBlock,omitempty"`
+	LastChecked         time.Time `json:"lastChecked"`
+	LastSuccess         time.Time `json:"lastSuccess,omitempty"`
+}
+
+// IsHealthy reports whether the last probe succeeded and the endpoint was not degraded.
+func (s EndpointStats) IsHealthy() bool {
+	return s.LastStatus.IsAvailable && !s.LastStatus.IsDegraded
+}
@@ -0,0 +1,91 @@
+// Package abi encodes and decodes Solidity ABI data without depending on go-ethereum. It parses
+// human-readable signatures such as "function balanceOf(address owner) view returns (uint256)"
+// or "event Transfer(address indexed from, address indexed to, uint256 value)".
+//
+// Values map to Go as follows: integers decode to *big.Int and encode from *big.Int or any Go
+// integer; address is ethtypes.Address; bool is bool; bytes and bytesN decode to []byte and
+// encode from any byte slice or array of the right length; string is string; arrays, slices
+// and tuples decode to []any and encode from any slice or array of matching values.
+package abi
+
+import (
+	"errors"
+	"fmt"
+
+	"go-ethereum-chains/internal/keccak"
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+var (
+	// ErrInvalidType is wrapped into errors parsing types and signatures.
+	ErrInvalidType = errors.New("invalid ABI type")
+	// ErrInvalidValue is wrapped into errors encoding a value that does not fit its type.
+	ErrInvalidValue = errors.New("invalid ABI value")
+	// ErrInvalidData is wrapped into errors decoding malformed ABI data.
+	ErrInvalidData = errors.New("invalid ABI data")
+)
+
+// wordSize is the size of an ABI word.
+const wordSize = 32
+
+// Keccak256 returns the Keccak-256 hash of the concatenation of data.
+func Keccak256(data ...[]byte) ethtypes.Hash {
+	return ethtypes.Hash(keccak.Sum256(data...))
+}
+
+// Selector returns the 4-byte selector of a canonical signature such as "transfer(address,uint256)".
+func Selector(signature string) [4]byte {
+	hash := keccak.Sum256([]byte(signature))
+	return [4]byte(hash[:4])
+}
+
+// Argument is a named parameter of a function or event.
+type Argument struct {
+	Name    string
+	Type    Type
+	Indexed bool
+}
+
+// Arguments is a parameter list, encoded as a tuple.
+type Arguments []Argument
+
+// Types returns the types of the arguments.
+func (args Arguments) Types() []Type {
+	types := make([]Type, len(args))
+	for i, arg := range args {
+		types[i] = arg.Type
+	}
+	return types
+}
+
+// Encode encodes values as a tuple of the argument types.
+func (args Arguments) Encode(values ...any) ([]byte, error) {
+	return Encode(args.Types(), values...)
+}
+
+// Decode decodes a tuple of the argument types.
+func (args Arguments) Decode(data []byte) ([]any, error) {
+	return Decode(args.Types(), data)
+}
+
+// signature returns the canonical "(type1,type2)" list of the argument types.
+func (args Arguments) signature() string {
+	return Type{Kind: KindTuple, Components: args.Types()}.String()
+}
+
+// Encode encodes values as a tuple of types.
+func Encode(types []Type, values ...any) ([]byte, error) {
+	if len(values) != len(types) {
+		return nil, fmt.Errorf("%w: %d values for %d types", ErrInvalidValue, len(values), len(types))
+	}
+	return encodeTuple(types, values)
+}
+
+// Decode decodes a tuple of types.
+func Decode(types []Type, data []byte) ([]any, error) {
+	values, err := decodeTuple(types, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,227 @@
+package abi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+// words joins hex words, ignoring whitespace, for readable expected encodings.
+func words(ws ...string) string {
+	return strings.Join(ws, "")
+}
+
+func word(hexValue string) string {
+	return strings.Repeat("0", 64-len(hexValue)) + hexValue
+}
+
+// TestEncodeCall checks the examples of the Solidity ABI specification.
+func TestEncodeCall(t *testing.T) {
+	tests := []struct {
+		signature string
+		args      []any
+		want      string
+	}{
+		{
+			"baz(uint32 x, bool y)", []any{69, true},
+			words("cdcd77c0", word("45"), word("1")),
+		},
+		{
+			"bar(bytes3[2])", []any{[][]byte{[]byte("abc"), []byte("def")}},
+			words("fce353f6", "616263"+strings.Repeat("0", 58), "646566"+strings.Repeat("0", 58)),
+		},
+		{
+			"sam(bytes,bool,uint[])", []any{[]byte("dave"), true, []int{1, 2, 3}},
+			words("a5643bf2", word("60"), word("1"), word("a0"),
+				word("4"), "64617665"+strings.Repeat("0", 56),
+				word("3"), word("1"), word("2"), word("3")),
+		},
+		{
+			"f(uint256,uint32[],bytes10,bytes)", []any{0x123, []uint32{0x456, 0x789}, []byte("1234567890"), []byte("Hello, world!")},
+			words("8be65246", word("123"), word("80"), "31323334353637383930"+strings.Repeat("0", 44), word("e0"),
+				word("2"), word("456"), word("789"),
+				word("d"), "48656c6c6f2c20776f726c6421"+strings.Repeat("0", 38)),
+		},
+		{
+			"g(uint256[][],string[])", []any{[][]int{{1, 2}, {3}}, []string{"one", "two", "three"}},
+			words("2289b18c", word("40"), word("140"),
+				word("2"), word("40"), word("a0"), word("2"), word("1"), word("2"), word("1"), word("3"),
+				word("3"), word("60"), word("a0"), word("e0"),
+				word("3"), "6f6e65"+strings.Repeat("0", 58),
+				word("3"), "74776f"+strings.Repeat("0", 58),
+				word("5"), "7468726565"+strings.Repeat("0", 54)),
+		},
+		{
+			"transfer(address,uint256)", []any{ethtypes.MustParseAddress("0x00000000000000000000000000000000000000aa"), big.NewInt(-1 + 1e9)},
+			words("a9059cbb", word("aa"), word("3b9ac9ff")),
+		},
+	}
+	for _, tt := range tests {
+		f := MustParseFunction(tt.signature)
+		got, err := f.EncodeCall(tt.args...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.signature, err)
+			continue
+		}
+		if hex.EncodeToString(got) != tt.want {
+			t.Errorf("%s:\n got %x\nwant %s", tt.signature, got, tt.want)
+		}
+		if _, err := f.DecodeCall(got); err != nil {
+			t.Errorf("%s: decoding own encoding: %v", tt.signature, err)
+		}
+	}
+}
+
+// TestRoundTrip checks that decoding an encoding returns the normalized input values.
+func TestRoundTrip(t *testing.T) {
+	addr := ethtypes.MustParseAddress("0xca11bde05977b3631167028862be2a173976ca11")
+	tests := []struct {
+		typ   string
+		value any
+		want  any
+	}{
+		{"uint8", 255, big.NewInt(255)},
+		{"uint256", new(big.Int).Sub(tt256, big.NewInt(1)), new(big.Int).Sub(tt256, big.NewInt(1))},
+		{"int8", -128, big.NewInt(-128)},
+		{"int256", big.NewInt(-1), big.NewInt(-1)},
+		{"int64", int64(-42), big.NewInt(-42)},
+		{"address", addr, addr},
+		{"bool", false, false},
+		{"bytes4", [4]byte{1, 2, 3, 4}, []byte{1, 2, 3, 4}},
+		{"bytes32", ethtypes.Hash{31: 1}, append(make([]byte, 31), 1)},
+		{"bytes", []byte{}, []byte{}},
+		{"bytes", ethtypes.Bytes(bytes.Repeat([]byte{0xab}, 33)), bytes.Repeat([]byte{0xab}, 33)},
+		{"string", "héllo", "héllo"},
+		{"address[]", []ethtypes.Address{addr, {}}, []any{addr, ethtypes.Address{}}},
+		{"string[2]", []string{"a", ""}, []any{"a", ""}},
+		{"(address,bool,bytes)[]", []any{[]any{addr, true, []byte{0x12}}}, []any{[]any{addr, true, []byte{0x12}}}},
+		{"(bool,uint16[2])", []any{true, []int{1, 2}}, []any{true, []any{big.NewInt(1), big.NewInt(2)}}},
+		{"uint[][]", [][]int{{}, {7}}, []any{[]any{}, []any{big.NewInt(7)}}},
+	}
+	for _, tt := range tests {
+		types := []Type{MustParseType(tt.typ)}
+		enc, err := Encode(types, tt.value)
+		if err != nil {
+			t.Errorf("Encode(%s, %v): %v", tt.typ, tt.value, err)
+			continue
+		}
+		got, err := Decode(types, enc)
+		if err != nil {
+			t.Errorf("Decode(%s, %x): %v", tt.typ, enc, err)
+			continue
+		}
+		if !reflect.DeepEqual(got[0], tt.want) {
+			t.Errorf("%s: round trip of %v = %#v, want %#v", tt.typ, tt.value, got[0], tt.want)
+		}
+	}
+}
+
+func TestEncode_Invalid(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value any
+	}{
+		{"uint8", 256},
+		{"uint256", -1},
+		{"int8", 128},
+		{"int8", -129},
+		{"uint256", "1"},
+		{"address", []byte{1}},
+		{"bool", 1},
+		{"bytes4", []byte{1, 2, 3}},
+		{"string", []byte("x")},
+		{"uint[2]", []int{1}},
+		{"(bool,bool)", []any{true}},
+		{"uint[]", 1},
+	}
+	for _, tt := range tests {
+		if _, err := Encode([]Type{MustParseType(tt.typ)}, tt.value); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("Encode(%s, %#v) = %v, want ErrInvalidValue", tt.typ, tt.value, err)
+		}
+	}
+	if _, err := Encode([]Type{MustParseType("bool")}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Encode with missing value = %v, want ErrInvalidValue", err)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	tests := []struct {
+		typ  string
+		data string
+	}{
+		{"uint256", word("1")[:62]},
+		{"uint8", word("100")},
+		{"int8", word("80")},
+		{"bool", word("2")},
+		{"address", "01" + word("aa")[2:]},
+		{"bytes4", "0102030405" + strings.Repeat("0", 54)},
+		{"bytes", word("20") + word("21") + word("0")},
+		{"string", word("ffffffff")},
+		{"uint[]", word("20") + word("ffffffffffff")},
+		{"uint[3]", word("1") + word("2")},
+	}
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.data)
+		if v, err := Decode([]Type{MustParseType(tt.typ)}, data); !errors.Is(err, ErrInvalidData) {
+			t.Errorf("Decode(%s, %s) = %v, %v, want ErrInvalidData", tt.typ, tt.data, v, err)
+		}
+	}
+}
+
+// FuzzDecode checks that decoding arbitrary data never panics and that whatever decodes
+// re-encodes to data that decodes to the same values.
+func FuzzDecode(f *testing.F) {
+	f.Add("(uint256,string,bytes32)", []byte{})
+	f.Add("uint[][]", bytes.Repeat([]byte{0}, 64))
+	f.Add("(address,bool,bytes)[]", []byte{})
+	enc, _ := MustParseFunction("g(uint256[][],string[])").EncodeCall([][]int{{1, 2}, {3}}, []string{"one", "two", "three"})
+	f.Add("(uint256[][],string[])", enc[4:])
+
+	f.Fuzz(func(t *testing.T, typeName string, data []byte) {
+		typ, err := ParseType(typeName)
+		if err != nil || typ.Kind != KindTuple {
+			return
+		}
+		values, err := Decode(typ.Components, data)
+		if err != nil {
+			return
+		}
+		reencoded, err := Encode(typ.Components, values...)
+		if err != nil {
+			t.Fatalf("re-encoding %#v as %s: %v", values, typ, err)
+		}
+		again, err := Decode(typ.Components, reencoded)
+		if err != nil {
+			t.Fatalf("decoding re-encoded %x as %s: %v", reencoded, typ, err)
+		}
+		if !reflect.DeepEqual(values, again) {
+			t.Fatalf("%s: %#v re-decoded as %#v", typ, values, again)
+		}
+	})
+}
+
+// FuzzParseType checks that parsed types print as signatures that parse to the same type.
+func FuzzParseType(f *testing.F) {
+	for _, s := range []string{"uint", "(address,bool,bytes)[]", "tuple(string a, int8[2] b)[][3]", "bytes32"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		typ, err := ParseType(s)
+		if err != nil {
+			return
+		}
+		again, err := ParseType(typ.String())
+		if err != nil {
+			t.Fatalf("canonical %q of %q does not parse: %v", typ, s, err)
+		}
+		if again.String() != typ.String() {
+			t.Fatalf("canonical %q of %q parses as %q", typ, s, again)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package abi
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+// decodeTuple decodes a tuple whose head starts at data[0]; offsets are relative to data.
+func decodeTuple(types []Type, data []byte) ([]any, error) {
+	values := make([]any, len(types))
+	pos := 0
+	for i, t := range types {
+		var err error
+		if t.IsDynamic() {
+			var offset int
+			if offset, err = readOffset(data, pos); err != nil {
+				return nil, err
+			}
+			values[i], err = decodeValue(t, data[offset:])
+		} else {
+			if pos+t.headSize() > len(data) {
+				return nil, fmt.Errorf("%s at %d out of bounds (%d bytes)", t, pos, len(data))
+			}
+			values[i], err = decodeValue(t, data[pos:])
+		}
+		if err != nil {
+			return nil, err
+		}
+		pos += t.headSize()
+	}
+	return values, nil
+}
+
+// decodeValue decodes a value of type t that starts at data[0].
+func decodeValue(t Type, data []byte) (any, error) {
+	switch t.Kind {
+	case KindUint, KindInt, KindAddress, KindBool, KindFixedBytes:
+		if len(data) < wordSize {
+			return nil, fmt.Errorf("%s out of bounds (%d bytes)", t, len(data))
+		}
+		return decodeWord(t, data[:wordSize])
+
+	case KindBytes, KindString:
+		length, err := readOffset(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		if wordSize+length > len(data) {
+			return nil, fmt.Errorf("%s of %d bytes out of bounds (%d bytes)", t, length, len(data))
+		}
+		b := bytes.Clone(data[wordSize : wordSize+length])
+		if b == nil {
+			b = []byte{}
+		}
+		if t.Kind == KindString {
+			return string(b), nil
+		}
+		return b, nil
+
+	case KindSlice, KindArray:
+		length := t.Size
+		if t.Kind == KindSlice {
+			var err error
+			if length, err = readOffset(data, 0); err != nil {
+				return nil, err
+			}
+			data = data[wordSize:]
+		}
+		// Elements take at least one word, which bounds allocations by the input size.
+		if length > len(data)/max(t.Elem.headSize(), 1) {
+			return nil, fmt.Errorf("%s of %d elements out of bounds (%d bytes)", t, length, len(data))
+		}
+		types := make([]Type, length)
+		for i := range types {
+			types[i] = *t.Elem
+		}
+		return decodeTuple(types, data)
+
+	case KindTuple:
+		return decodeTuple(t.Components, data)
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// decodeWord decodes a single-word value and checks its padding.
+func decodeWord(t Type, word []byte) (any, error) {
+	switch t.Kind {
+	case KindUint:
+		n := new(big.Int).SetBytes(word)
+		if n.BitLen() > t.Size {
+			return nil, fmt.Errorf("%s overflows %s", n, t)
+		}
+		return n, nil
+
+	case KindInt:
+		n := new(big.Int).SetBytes(word)
+		if word[0]&0x80 != 0 {
+			n.Sub(n, tt256)
+		}
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("%s overflows %s", n, t)
+		}
+		return n, nil
+
+	case KindAddress:
+		if !isZero(word[:wordSize-20]) {
+			return nil, fmt.Errorf("address word %x has dirty high bytes", word)
+		}
+		return ethtypes.BytesToAddress(word[wordSize-20:]), nil
+
+	case KindBool:
+		if !isZero(word[:wordSize-1]) || word[wordSize-1] > 1 {
+			return nil, fmt.Errorf("bool word %x is neither 0 nor 1", word)
+		}
+		return word[wordSize-1] == 1, nil
+
+	case KindFixedBytes:
+		if !isZero(word[t.Size:]) {
+			return nil, fmt.Errorf("%s word %x has dirty low bytes", t, word)
+		}
+		return bytes.Clone(word[:t.Size]), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// readOffset reads an offset or length word that must lie within data.
+func readOffset(data []byte, pos int) (int, error) {
+	if pos < 0 || pos+wordSize > len(data) {
+		return 0, fmt.Errorf("word at %d out of bounds (%d bytes)", pos, len(data))
+	}
+	v := new(big.Int).SetBytes(data[pos : pos+wordSize])
+	if !v.IsUint64() || v.Uint64() > uint64(len(data)) {
+		return 0, fmt.Errorf("offset %s at %d out of bounds (%d bytes)", v, pos, len(data))
+	}
+	return int(v.Uint64()), nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,204 @@
+package abi
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+// tt256 is 2^256, added to negative integers for their two's complement.
+var tt256 = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// encodeTuple encodes values as the head and tail of a tuple.
+func encodeTuple(types []Type, values []any) ([]byte, error) {
+	headSize := 0
+	for _, t := range types {
+		headSize += t.headSize()
+	}
+
+	head := make([]byte, 0, headSize)
+	var tail []byte
+	for i, t := range types {
+		enc, err := encodeValue(t, values[i])
+		if err != nil {
+			return nil, err
+		}
+		if t.IsDynamic() {
+			head = append(head, uintWord(uint64(headSize+len(tail)))...)
+			tail = append(tail, enc...)
+		} else {
+			head = append(head, enc...)
+		}
+	}
+	return append(head, tail...), nil
+}
+
+// encodeValue encodes a single value: in place for static types, as its tail for dynamic ones.
+func encodeValue(t Type, v any) ([]byte, error) {
+	switch t.Kind {
+	case KindUint, KindInt:
+		n, err := toBig(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w for %s: %v", ErrInvalidValue, t, err)
+		}
+		return encodeInt(t, n)
+
+	case KindAddress:
+		b, ok := byteArray(v, 20)
+		if !ok {
+			return nil, fmt.Errorf("%w for address: %T", ErrInvalidValue, v)
+		}
+		return leftPad(b), nil
+
+	case KindBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w for bool: %T", ErrInvalidValue, v)
+		}
+		if b {
+			return uintWord(1), nil
+		}
+		return uintWord(0), nil
+
+	case KindFixedBytes:
+		b, ok := byteArray(v, t.Size)
+		if !ok {
+			return nil, fmt.Errorf("%w for %s: %T of another length", ErrInvalidValue, t, v)
+		}
+		return rightPad(b), nil
+
+	case KindBytes, KindString:
+		var b []byte
+		if s, ok := v.(string); ok && t.Kind == KindString {
+			b = []byte(s)
+		} else if b, ok = byteArray(v, -1); !ok || t.Kind == KindString {
+			return nil, fmt.Errorf("%w for %s: %T", ErrInvalidValue, t, v)
+		}
+		return append(uintWord(uint64(len(b))), rightPad(b)...), nil
+
+	case KindSlice, KindArray, KindTuple:
+		elems, ok := toSlice(v)
+		if !ok {
+			return nil, fmt.Errorf("%w for %s: %T", ErrInvalidValue, t, v)
+		}
+		types := t.Components
+		if t.Kind != KindTuple {
+			if t.Kind == KindArray && len(elems) != t.Size {
+				return nil, fmt.Errorf("%w for %s: %d elements", ErrInvalidValue, t, len(elems))
+			}
+			types = make([]Type, len(elems))
+			for i := range types {
+				types[i] = *t.Elem
+			}
+		} else if len(elems) != len(types) {
+			return nil, fmt.Errorf("%w for %s: %d elements", ErrInvalidValue, t, len(elems))
+		}
+		enc, err := encodeTuple(types, elems)
+		if err != nil {
+			return nil, err
+		}
+		if t.Kind == KindSlice {
+			enc = append(uintWord(uint64(len(elems))), enc...)
+		}
+		return enc, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidType, t)
+}
+
+// encodeInt encodes n as a two's complement word after checking it fits t.
+func encodeInt(t Type, n *big.Int) ([]byte, error) {
+	if t.Kind == KindUint {
+		if n.Sign() < 0 || n.BitLen() > t.Size {
+			return nil, fmt.Errorf("%w: %s overflows %s", ErrInvalidValue, n, t)
+		}
+	} else {
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+		if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+			return nil, fmt.Errorf("%w: %s overflows %s", ErrInvalidValue, n, t)
+		}
+		if n.Sign() < 0 {
+			n = new(big.Int).Add(n, tt256)
+		}
+	}
+	return n.FillBytes(make([]byte, wordSize)), nil
+}
+
+// toBig converts *big.Int, big.Int, ethtypes.Big and Go integers.
+func toBig(v any) (*big.Int, error) {
+	switch n := v.(type) {
+	case *big.Int:
+		if n == nil {
+			return nil, fmt.Errorf("nil *big.Int")
+		}
+		return n, nil
+	case big.Int:
+		return &n, nil
+	case *ethtypes.Big:
+		if n == nil {
+			return nil, fmt.Errorf("nil *ethtypes.Big")
+		}
+		return n.ToInt(), nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return new(big.Int).SetUint64(rv.Uint()), nil
+	}
+	return nil, fmt.Errorf("%T is not an integer", v)
+}
+
+// byteArray returns the contents of a byte slice or array of length n (any length if n < 0).
+func byteArray(v any, n int) ([]byte, bool) {
+	if b, ok := v.([]byte); ok {
+		return b, n < 0 || len(b) == n
+	}
+	rv := reflect.ValueOf(v)
+	if (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, false
+	}
+	if n >= 0 && rv.Len() != n {
+		return nil, false
+	}
+	b := make([]byte, rv.Len())
+	reflect.Copy(reflect.ValueOf(b), rv)
+	return b, true
+}
+
+// toSlice returns the elements of a slice or array.
+func toSlice(v any) ([]any, bool) {
+	if elems, ok := v.([]any); ok {
+		return elems, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	elems := make([]any, rv.Len())
+	for i := range elems {
+		elems[i] = rv.Index(i).Interface()
+	}
+	return elems, true
+}
+
+// uintWord encodes v as a 32-byte big-endian word.
+func uintWord(v uint64) []byte {
+	return new(big.Int).SetUint64(v).FillBytes(make([]byte, wordSize))
+}
+
+// leftPad pads b with leading zeros to a full word.
+func leftPad(b []byte) []byte {
+	word := make([]byte, wordSize)
+	copy(word[wordSize-len(b):], b)
+	return word
+}
+
+// rightPad pads b with trailing zeros to a multiple of the word size.
+func rightPad(b []byte) []byte {
+	padded := make([]byte, (len(b)+wordSize-1)/wordSize*wordSize)
+	copy(padded, b)
+	return padded
+}
@@ -0,0 +1,77 @@
+package abi
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Error is a parsed custom error signature.
+type Error struct {
+	Name   string
+	Inputs Arguments
+}
+
+// ParseError parses a human-readable custom error signature such as
+// "error InsufficientBalance(uint256 available, uint256 required)". The "error" keyword and
+// parameter names are optional.
+func ParseError(signature string) (Error, error) {
+	p := &parser{src: signature}
+	e, err := p.parseError()
+	if err != nil {
+		return Error{}, fmt.Errorf("%w: error %q: %v", ErrInvalidType, signature, err)
+	}
+	return e, nil
+}
+
+// MustParseError is like ParseError but panics on error.
+func MustParseError(signature string) Error {
+	e, err := ParseError(signature)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func (p *parser) parseError() (Error, error) {
+	name, err := p.parseHeader("error")
+	if err != nil {
+		return Error{}, err
+	}
+	e := Error{Name: name}
+	if e.Inputs, err = p.parseParams(false); err != nil {
+		return Error{}, err
+	}
+	if !p.done() {
+		return Error{}, p.errorf("unexpected %q", p.rest())
+	}
+	return e, nil
+}
+
+// Signature returns the canonical signature, such as "InsufficientBalance(uint256,uint256)".
+func (e Error) Signature() string {
+	return e.Name + e.Inputs.signature()
+}
+
+// Selector returns the 4-byte selector that starts revert data of the error.
+func (e Error) Selector() [4]byte {
+	return Selector(e.Signature())
+}
+
+// Encode encodes revert data of the error with args.
+func (e Error) Encode(args ...any) ([]byte, error) {
+	enc, err := e.Inputs.Encode(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.Name, err)
+	}
+	sel := e.Selector()
+	return append(sel[:], enc...), nil
+}
+
+// Decode decodes the arguments of revert data of the error.
+func (e Error) Decode(data []byte) ([]any, error) {
+	sel := e.Selector()
+	if !bytes.HasPrefix(data, sel[:]) {
+		return nil, fmt.Errorf("%w: revert data is not a %s error", ErrInvalidData, e.Signature())
+	}
+	return e.Inputs.Decode(data[len(sel):])
+}
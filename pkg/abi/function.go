@@ -0,0 +1,229 @@
+package abi
+
+import (
+	"bytes"
+	"fmt"
+
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+// Function is a parsed function signature.
+type Function struct {
+	Name    string
+	Inputs  Arguments
+	Outputs Arguments
+}
+
+// ParseFunction parses a human-readable function signature such as
+// "function balanceOf(address owner) view returns (uint256)". The "function" keyword, parameter
+// names, data locations and state mutability are optional.
+func ParseFunction(signature string) (Function, error) {
+	p := &parser{src: signature}
+	f, err := p.parseFunction()
+	if err != nil {
+		return Function{}, fmt.Errorf("%w: function %q: %v", ErrInvalidType, signature, err)
+	}
+	return f, nil
+}
+
+// MustParseFunction is like ParseFunction but panics on error.
+func MustParseFunction(signature string) Function {
+	f, err := ParseFunction(signature)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func (p *parser) parseFunction() (Function, error) {
+	name, err := p.parseHeader("function")
+	if err != nil {
+		return Function{}, err
+	}
+	f := Function{Name: name}
+	if f.Inputs, err = p.parseParams(false); err != nil {
+		return Function{}, err
+	}
+	for !p.done() {
+		switch word := p.ident(); word {
+		case "view", "pure", "payable", "nonpayable", "external", "public":
+		case "returns":
+			if f.Outputs, err = p.parseParams(false); err != nil {
+				return Function{}, err
+			}
+		default:
+			return Function{}, p.errorf("unexpected %q", p.rest())
+		}
+	}
+	return f, nil
+}
+
+// Signature returns the canonical signature, such as "transfer(address,uint256)".
+func (f Function) Signature() string {
+	return f.Name + f.Inputs.signature()
+}
+
+// Selector returns the 4-byte function selector.
+func (f Function) Selector() [4]byte {
+	return Selector(f.Signature())
+}
+
+// EncodeCall encodes a call of the function with args.
+func (f Function) EncodeCall(args ...any) ([]byte, error) {
+	enc, err := f.Inputs.Encode(args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	sel := f.Selector()
+	return append(sel[:], enc...), nil
+}
+
+// DecodeCall decodes the arguments of calldata that calls the function.
+func (f Function) DecodeCall(calldata []byte) ([]any, error) {
+	sel := f.Selector()
+	if !bytes.HasPrefix(calldata, sel[:]) {
+		return nil, fmt.Errorf("%w: calldata does not start with the selector of %s", ErrInvalidData, f.Signature())
+	}
+	return f.Inputs.Decode(calldata[len(sel):])
+}
+
+// DecodeOutput decodes the return data of the function.
+func (f Function) DecodeOutput(data []byte) ([]any, error) {
+	return f.Outputs.Decode(data)
+}
+
+// Event is a parsed event signature.
+type Event struct {
+	Name      string
+	Inputs    Arguments
+	Anonymous bool
+}
+
+// ParseEvent parses a human-readable event signature such as
+// "event Transfer(address indexed from, address indexed to, uint256 value)". The "event"
+// keyword and parameter names are optional.
+func ParseEvent(signature string) (Event, error) {
+	p := &parser{src: signature}
+	e, err := p.parseEvent()
+	if err != nil {
+		return Event{}, fmt.Errorf("%w: event %q: %v", ErrInvalidType, signature, err)
+	}
+	return e, nil
+}
+
+// MustParseEvent is like ParseEvent but panics on error.
+func MustParseEvent(signature string) Event {
+	e, err := ParseEvent(signature)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func (p *parser) parseEvent() (Event, error) {
+	name, err := p.parseHeader("event")
+	if err != nil {
+		return Event{}, err
+	}
+	e := Event{Name: name}
+	if e.Inputs, err = p.parseParams(true); err != nil {
+		return Event{}, err
+	}
+	if p.peekIdent() == "anonymous" {
+		p.ident()
+		e.Anonymous = true
+	}
+	if !p.done() {
+		return Event{}, p.errorf("unexpected %q", p.rest())
+	}
+
+	indexed := 0
+	for _, arg := range e.Inputs {
+		if arg.Indexed {
+			indexed++
+		}
+	}
+	// Logs have four topics, one of which holds the signature of non-anonymous events.
+	limit := 3
+	if e.Anonymous {
+		limit = 4
+	}
+	if indexed > limit {
+		return Event{}, fmt.Errorf("%d indexed parameters", indexed)
+	}
+	return e, nil
+}
+
+// Signature returns the canonical signature, such as "Transfer(address,address,uint256)".
+func (e Event) Signature() string {
+	return e.Name + e.Inputs.signature()
+}
+
+// Topic returns the first topic of logs of the event, the hash of its signature.
+func (e Event) Topic() ethtypes.Hash {
+	return Keccak256([]byte(e.Signature()))
+}
+
+// DecodeLog decodes the inputs of a log of the event, in declaration order. Indexed inputs of
+// dynamic types (bytes, string, arrays and tuples) are only available as the ethtypes.Hash of
+// their encoding.
+func (e Event) DecodeLog(topics []ethtypes.Hash, data []byte) ([]any, error) {
+	if !e.Anonymous {
+		if len(topics) == 0 || topics[0] != e.Topic() {
+			return nil, fmt.Errorf("%w: log is not a %s event", ErrInvalidData, e.Signature())
+		}
+		topics = topics[1:]
+	}
+
+	var nonIndexed Arguments
+	for _, arg := range e.Inputs {
+		if !arg.Indexed {
+			nonIndexed = append(nonIndexed, arg)
+		}
+	}
+	dataValues, err := nonIndexed.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s data: %w", e.Name, err)
+	}
+
+	values := make([]any, 0, len(e.Inputs))
+	for _, arg := range e.Inputs {
+		if !arg.Indexed {
+			values = append(values, dataValues[0])
+			dataValues = dataValues[1:]
+			continue
+		}
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("%w: %s log misses topic for %s", ErrInvalidData, e.Name, arg.Name)
+		}
+		topic := topics[0]
+		topics = topics[1:]
+		if arg.Type.IsDynamic() || arg.Type.Kind == KindArray || arg.Type.Kind == KindTuple {
+			values = append(values, topic)
+			continue
+		}
+		v, err := decodeWord(arg.Type, topic[:])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s topic %s: %v", ErrInvalidData, e.Name, arg.Name, err)
+		}
+		values = append(values, v)
+	}
+	if len(topics) != 0 {
+		return nil, fmt.Errorf("%w: %s log has %d extra topics", ErrInvalidData, e.Name, len(topics))
+	}
+	return values, nil
+}
+
+// DecodeLog finds the event among events whose topic matches the log and decodes the log.
+// Anonymous events are never matched.
+func DecodeLog(events []Event, topics []ethtypes.Hash, data []byte) (Event, []any, error) {
+	if len(topics) > 0 {
+		for _, e := range events {
+			if !e.Anonymous && e.Topic() == topics[0] {
+				values, err := e.DecodeLog(topics, data)
+				return e, values, err
+			}
+		}
+	}
+	return Event{}, nil, fmt.Errorf("%w: no known event matches the log", ErrInvalidData)
+}
@@ -0,0 +1,159 @@
+package abi
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+func TestParseFunction(t *testing.T) {
+	tests := []struct {
+		in        string
+		signature string
+		selector  string
+		outputs   string
+	}{
+		{"transfer(address,uint256)", "transfer(address,uint256)", "a9059cbb", "()"},
+		{"function balanceOf(address owner) external view returns (uint256)", "balanceOf(address)", "70a08231", "(uint256)"},
+		{"function aggregate3((address target, bool allowFailure, bytes callData)[] calldata calls) payable returns ((bool success, bytes returnData)[] returnData)",
+			"aggregate3((address,bool,bytes)[])", "82ad56cb", "((bool,bytes)[])"},
+		{"resolve(bytes name, bytes data) view returns (bytes, address)", "resolve(bytes,bytes)", "9061b923", "(bytes,address)"},
+		{"totalSupply()", "totalSupply()", "18160ddd", "()"},
+	}
+	for _, tt := range tests {
+		f, err := ParseFunction(tt.in)
+		if err != nil {
+			t.Errorf("ParseFunction(%q): %v", tt.in, err)
+			continue
+		}
+		sel := f.Selector()
+		if f.Signature() != tt.signature || hex.EncodeToString(sel[:]) != tt.selector || f.Outputs.signature() != tt.outputs {
+			t.Errorf("ParseFunction(%q) = %s %x returns %s, want %s %s returns %s",
+				tt.in, f.Signature(), sel, f.Outputs.signature(), tt.signature, tt.selector, tt.outputs)
+		}
+	}
+
+	for _, in := range []string{"", "(uint)", "f(", "f(uint) returns", "f(uint) frobs", "f(uint indexed a)", "f(uint a b)"} {
+		if _, err := ParseFunction(in); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("ParseFunction(%q) = %v, want ErrInvalidType", in, err)
+		}
+	}
+}
+
+func TestFunction_DecodeOutput(t *testing.T) {
+	f := MustParseFunction("reverse(bytes) view returns (string, address, address, address)")
+	addr := ethtypes.MustParseAddress("0x00000000000000000000000000000000000000a1")
+	data, err := Encode(f.Outputs.Types(), "alice.eth", addr, ethtypes.Address{}, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.DecodeOutput(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{"alice.eth", addr, ethtypes.Address{}, addr}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeOutput = %#v, want %#v", got, want)
+	}
+
+	if _, err := f.DecodeCall([]byte{1, 2, 3, 4}); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("DecodeCall with wrong selector = %v, want ErrInvalidData", err)
+	}
+}
+
+func TestEvent_DecodeLog(t *testing.T) {
+	transfer := MustParseEvent("event Transfer(address indexed from, address indexed to, uint256 value)")
+	if got, want := transfer.Topic().Hex(), "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"; got != want {
+		t.Errorf("Transfer topic = %s, want %s", got, want)
+	}
+
+	from := ethtypes.MustParseAddress("0x00000000000000000000000000000000000000a1")
+	to := ethtypes.MustParseAddress("0x00000000000000000000000000000000000000b0")
+	topics := []ethtypes.Hash{transfer.Topic(), ethtypes.BytesToHash(from[:]), ethtypes.BytesToHash(to[:])}
+	data, _ := hex.DecodeString(word("3e8"))
+
+	approval := MustParseEvent("Approval(address indexed owner, address indexed spender, uint256 value)")
+	e, got, err := DecodeLog([]Event{approval, transfer}, topics, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{from, to, big.NewInt(1000)}; e.Name != "Transfer" || !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeLog = %s %#v, want Transfer %#v", e.Name, got, want)
+	}
+
+	// Indexed dynamic values are only available as their hash.
+	named := MustParseEvent("NameChanged(string indexed name, bytes32 indexed node, bool flag)")
+	nameHash := Keccak256([]byte("alice"))
+	node := ethtypes.Hash{31: 7}
+	flag, _ := hex.DecodeString(word("1"))
+	got, err = named.DecodeLog([]ethtypes.Hash{named.Topic(), nameHash, node}, flag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{nameHash, node[:], true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeLog = %#v, want %#v", got, want)
+	}
+
+	invalid := []struct {
+		name   string
+		events []Event
+		topics []ethtypes.Hash
+		data   []byte
+	}{
+		{"unknown topic", []Event{approval}, topics, data},
+		{"no topics", []Event{transfer}, nil, data},
+		{"missing topic", []Event{transfer}, topics[:2], data},
+		{"extra topic", []Event{transfer}, append(topics, ethtypes.Hash{}), data},
+		{"short data", []Event{transfer}, topics, data[:31]},
+		{"dirty address topic", []Event{transfer}, []ethtypes.Hash{transfer.Topic(), {0: 1}, topics[2]}, data},
+	}
+	for _, tt := range invalid {
+		if _, _, err := DecodeLog(tt.events, tt.topics, tt.data); !errors.Is(err, ErrInvalidData) {
+			t.Errorf("%s: DecodeLog = %v, want ErrInvalidData", tt.name, err)
+		}
+	}
+}
+
+func TestParseEvent(t *testing.T) {
+	e, err := ParseEvent("event Anon(uint indexed a, uint indexed b, uint indexed c, uint indexed d) anonymous")
+	if err != nil || !e.Anonymous || e.Signature() != "Anon(uint256,uint256,uint256,uint256)" {
+		t.Errorf("ParseEvent = %+v, %v", e, err)
+	}
+	for _, in := range []string{"E(uint indexed a, uint indexed b, uint indexed c, uint indexed d)", "E(uint) view", "E"} {
+		if _, err := ParseEvent(in); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("ParseEvent(%q) = %v, want ErrInvalidType", in, err)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	e := MustParseError("error InsufficientBalance(uint256 available, uint256 required)")
+	if sel := e.Selector(); e.Signature() != "InsufficientBalance(uint256,uint256)" || hex.EncodeToString(sel[:]) != "cf479181" {
+		t.Errorf("InsufficientBalance = %s %x", e.Signature(), sel)
+	}
+	data, err := e.Encode(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := e.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{big.NewInt(1), big.NewInt(2)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode = %#v, want %#v", got, want)
+	}
+
+	reason := MustParseError("Error(string)")
+	if sel := reason.Selector(); hex.EncodeToString(sel[:]) != "08c379a0" {
+		t.Errorf("Error(string) selector = %x", sel)
+	}
+	if _, err := reason.Decode(data); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Decode of another error = %v, want ErrInvalidData", err)
+	}
+	if _, err := ParseError("error E(uint) view"); !errors.Is(err, ErrInvalidType) {
+		t.Errorf("ParseError with trailing words = %v, want ErrInvalidType", err)
+	}
+}
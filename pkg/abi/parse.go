@@ -0,0 +1,216 @@
+package abi
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// maxArrayLength bounds the length of fixed-size arrays.
+	maxArrayLength = 1 << 20
+	// maxStaticSize bounds the encoded size of static types, which keeps head sizes in range.
+	maxStaticSize = 1 << 26
+	// maxNesting bounds how deeply tuples and arrays nest.
+	maxNesting = 32
+)
+
+// parser is a recursive descent parser over human-readable types and signatures.
+type parser struct {
+	src   string
+	pos   int
+	depth int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: "+format, append([]any{p.pos}, args...)...)
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *parser) done() bool {
+	p.skipSpace()
+	return p.pos == len(p.src)
+}
+
+func (p *parser) rest() string {
+	return p.src[p.pos:]
+}
+
+// peek returns the next non-space byte, or 0 at the end of the input.
+func (p *parser) peek() byte {
+	p.skipSpace()
+	if p.pos == len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// consume skips c if it is the next non-space byte.
+func (p *parser) consume(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+	p.pos++
+	return true
+}
+
+func (p *parser) expect(c byte) error {
+	if !p.consume(c) {
+		return p.errorf("expected %q", c)
+	}
+	return nil
+}
+
+// ident reads an identifier, or returns "" if there is none.
+func (p *parser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == '_' || c == '$' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || p.pos > start && '0' <= c && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.src[start:p.pos]
+}
+
+// peekIdent returns the next identifier without consuming it.
+func (p *parser) peekIdent() string {
+	pos := p.pos
+	id := p.ident()
+	p.pos = pos
+	return id
+}
+
+// parseType parses a type with any array suffixes.
+func (p *parser) parseType() (Type, error) {
+	defer func(depth int) { p.depth = depth }(p.depth)
+	if p.depth++; p.depth > maxNesting {
+		return Type{}, p.errorf("types nested deeper than %d", maxNesting)
+	}
+
+	var t Type
+	if p.peek() == '(' || p.peekIdent() == "tuple" {
+		p.ident()
+		components, err := p.parseParams(false)
+		if err != nil {
+			return Type{}, err
+		}
+		if len(components) == 0 {
+			return Type{}, p.errorf("empty tuple")
+		}
+		t = Type{Kind: KindTuple, Components: make([]Type, len(components))}
+		for i, c := range components {
+			t.Components[i] = c.Type
+		}
+	} else {
+		name := p.ident()
+		if name == "" {
+			return Type{}, p.errorf("expected type")
+		}
+		var err error
+		if t, err = elementary(name); err != nil {
+			return Type{}, p.errorf("%v", err)
+		}
+	}
+
+	for p.consume('[') {
+		start := p.pos
+		for p.pos < len(p.src) && '0' <= p.src[p.pos] && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+		digits := p.src[start:p.pos]
+		if err := p.expect(']'); err != nil {
+			return Type{}, err
+		}
+		elem := t
+		if digits == "" {
+			t = Type{Kind: KindSlice, Elem: &elem}
+			continue
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil || n == 0 || n > maxArrayLength {
+			return Type{}, p.errorf("invalid array length %q", digits)
+		}
+		t = Type{Kind: KindArray, Size: n, Elem: &elem}
+		if p.depth++; p.depth > maxNesting {
+			return Type{}, p.errorf("types nested deeper than %d", maxNesting)
+		}
+		if !t.IsDynamic() && t.headSize() > maxStaticSize {
+			return Type{}, p.errorf("static size of %s exceeds %d bytes", t, maxStaticSize)
+		}
+	}
+	if t.Kind == KindTuple && !t.IsDynamic() && t.headSize() > maxStaticSize {
+		return Type{}, p.errorf("static size of %s exceeds %d bytes", t, maxStaticSize)
+	}
+	return t, nil
+}
+
+// parseParams parses a parenthesized, comma-separated parameter list.
+func (p *parser) parseParams(allowIndexed bool) (Arguments, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var args Arguments
+	if p.consume(')') {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseParam(allowIndexed)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.consume(')') {
+			return args, nil
+		}
+		if err := p.expect(','); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseParam parses a type followed by optional modifiers and a name.
+func (p *parser) parseParam(allowIndexed bool) (Argument, error) {
+	t, err := p.parseType()
+	if err != nil {
+		return Argument{}, err
+	}
+	arg := Argument{Type: t}
+	for {
+		switch word := p.peekIdent(); word {
+		case "":
+			return arg, nil
+		case "indexed":
+			if !allowIndexed {
+				return Argument{}, p.errorf("indexed outside an event")
+			}
+			arg.Indexed = true
+		case "memory", "calldata", "storage":
+		default:
+			if arg.Name != "" {
+				return Argument{}, p.errorf("unexpected %q", word)
+			}
+			arg.Name = word
+		}
+		p.ident()
+	}
+}
+
+// parseHeader parses an optional keyword followed by a name.
+func (p *parser) parseHeader(keyword string) (string, error) {
+	name := p.ident()
+	if name == keyword && (p.peek() != '(') {
+		name = p.ident()
+	}
+	if name == "" {
+		return "", p.errorf("expected name")
+	}
+	return name, nil
+}
@@ -0,0 +1,164 @@
+package abi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind is the kind of an ABI type.
+type Kind int
+
+const (
+	KindUint Kind = iota
+	KindInt
+	KindAddress
+	KindBool
+	KindFixedBytes
+	KindBytes
+	KindString
+	KindSlice
+	KindArray
+	KindTuple
+)
+
+// Type is a parsed ABI type.
+type Type struct {
+	Kind Kind
+	// Size is the bit size of KindUint and KindInt, the byte size of KindFixedBytes and the
+	// length of KindArray.
+	Size int
+	// Elem is the element type of KindSlice and KindArray.
+	Elem *Type
+	// Components are the element types of KindTuple.
+	Components []Type
+}
+
+// ParseType parses a canonical or shorthand type such as "uint", "bytes32", "address[]" or
+// "(uint256,string)[2]". A "tuple" prefix before a parenthesized tuple is accepted.
+func ParseType(s string) (Type, error) {
+	p := &parser{src: s}
+	t, err := p.parseType()
+	if err == nil && !p.done() {
+		err = p.errorf("unexpected %q", p.rest())
+	}
+	if err != nil {
+		return Type{}, fmt.Errorf("%w %q: %v", ErrInvalidType, s, err)
+	}
+	return t, nil
+}
+
+// MustParseType is like ParseType but panics on error.
+func MustParseType(s string) Type {
+	t, err := ParseType(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// String returns the canonical type name used in signatures.
+func (t Type) String() string {
+	switch t.Kind {
+	case KindUint:
+		return "uint" + strconv.Itoa(t.Size)
+	case KindInt:
+		return "int" + strconv.Itoa(t.Size)
+	case KindAddress:
+		return "address"
+	case KindBool:
+		return "bool"
+	case KindFixedBytes:
+		return "bytes" + strconv.Itoa(t.Size)
+	case KindBytes:
+		return "bytes"
+	case KindString:
+		return "string"
+	case KindSlice:
+		return t.Elem.String() + "[]"
+	case KindArray:
+		return t.Elem.String() + "[" + strconv.Itoa(t.Size) + "]"
+	case KindTuple:
+		names := make([]string, len(t.Components))
+		for i, c := range t.Components {
+			names[i] = c.String()
+		}
+		return "(" + strings.Join(names, ",") + ")"
+	}
+	return fmt.Sprintf("invalid(%d)", int(t.Kind))
+}
+
+// IsDynamic reports whether values of the type are encoded out of place, behind an offset.
+func (t Type) IsDynamic() bool {
+	switch t.Kind {
+	case KindBytes, KindString, KindSlice:
+		return true
+	case KindArray:
+		return t.Elem.IsDynamic()
+	case KindTuple:
+		for _, c := range t.Components {
+			if c.IsDynamic() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize is the number of bytes the type occupies in the head of an enclosing tuple.
+func (t Type) headSize() int {
+	if t.IsDynamic() {
+		return wordSize
+	}
+	switch t.Kind {
+	case KindArray:
+		return t.Size * t.Elem.headSize()
+	case KindTuple:
+		size := 0
+		for _, c := range t.Components {
+			size += c.headSize()
+		}
+		return size
+	}
+	return wordSize
+}
+
+// elementary parses a type name without array suffixes.
+func elementary(name string) (Type, error) {
+	switch name {
+	case "address":
+		return Type{Kind: KindAddress}, nil
+	case "bool":
+		return Type{Kind: KindBool}, nil
+	case "bytes":
+		return Type{Kind: KindBytes}, nil
+	case "string":
+		return Type{Kind: KindString}, nil
+	case "uint", "int":
+		name += "256"
+	case "function":
+		return Type{Kind: KindFixedBytes, Size: 24}, nil
+	}
+
+	for _, k := range []struct {
+		prefix   string
+		kind     Kind
+		min, max int
+		step     int
+	}{
+		{"uint", KindUint, 8, 256, 8},
+		{"int", KindInt, 8, 256, 8},
+		{"bytes", KindFixedBytes, 1, 32, 1},
+	} {
+		digits, ok := strings.CutPrefix(name, k.prefix)
+		if !ok {
+			continue
+		}
+		size, err := strconv.Atoi(digits)
+		if err != nil || digits[0] == '0' || size < k.min || size > k.max || size%k.step != 0 {
+			return Type{}, fmt.Errorf("invalid size in %q", name)
+		}
+		return Type{Kind: k.kind, Size: size}, nil
+	}
+	return Type{}, fmt.Errorf("unknown type %q", name)
+}
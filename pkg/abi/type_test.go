@@ -0,0 +1,48 @@
+package abi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		dynamic bool
+	}{
+		{in: "uint", want: "uint256"},
+		{in: "int8", want: "int8"},
+		{in: "address", want: "address"},
+		{in: "bool", want: "bool"},
+		{in: "bytes32", want: "bytes32"},
+		{in: "bytes", want: "bytes", dynamic: true},
+		{in: "string", want: "string", dynamic: true},
+		{in: "uint[]", want: "uint256[]", dynamic: true},
+		{in: "address[2]", want: "address[2]"},
+		{in: "string[2]", want: "string[2]", dynamic: true},
+		{in: "uint8[][3]", want: "uint8[][3]", dynamic: true},
+		{in: "(address,bool)", want: "(address,bool)"},
+		{in: "tuple(address target, bool allowFailure, bytes callData)[]", want: "(address,bool,bytes)[]", dynamic: true},
+		{in: " ( uint256 , (string, int)[2] ) ", want: "(uint256,(string,int256)[2])", dynamic: true},
+	}
+	for _, tt := range tests {
+		typ, err := ParseType(tt.in)
+		if err != nil {
+			t.Errorf("ParseType(%q): %v", tt.in, err)
+			continue
+		}
+		if typ.String() != tt.want || typ.IsDynamic() != tt.dynamic {
+			t.Errorf("ParseType(%q) = %s (dynamic %v), want %s (dynamic %v)", tt.in, typ, typ.IsDynamic(), tt.want, tt.dynamic)
+		}
+	}
+}
+
+func TestParseType_Invalid(t *testing.T) {
+	for _, in := range []string{"", "uint7", "uint264", "uint08", "int0", "bytes0", "bytes33", "float", "uint[", "uint[0]", "uint[x]", "()", "(uint", "uint256 extra", "(uint,)", "uint[99999999999]", "uint[1048576][1048576]", strings.Repeat("(", 40) + "bool" + strings.Repeat(")", 40)} {
+		if typ, err := ParseType(in); !errors.Is(err, ErrInvalidType) {
+			t.Errorf("ParseType(%q) = %s, %v, want ErrInvalidType", in, typ, err)
+		}
+	}
+}
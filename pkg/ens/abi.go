@@ -0,0 +1,39 @@
+package ens
+
+import (
+	"fmt"
+
+	"go-ethereum-chains/pkg/abi"
+)
+
+// Contract functions used for resolution. OffchainLookup is the EIP-3668 custom error, whose
+// arguments are encoded like those of a function.
+var (
+	resolverFunc       = abi.MustParseFunction("resolver(bytes32 node) view returns (address)")
+	addrFunc           = abi.MustParseFunction("addr(bytes32 node) view returns (address)")
+	nameFunc           = abi.MustParseFunction("name(bytes32 node) view returns (string)")
+	textFunc           = abi.MustParseFunction("text(bytes32 node, string key) view returns (string)")
+	resolveFunc        = abi.MustParseFunction("resolve(bytes name, bytes data) view returns (bytes, address)")
+	reverseFunc        = abi.MustParseFunction("reverse(bytes reverseName) view returns (string, address, address, address)")
+	offchainLookupFunc = abi.MustParseFunction("OffchainLookup(address sender, string[] urls, bytes callData, bytes4 callbackFunction, bytes extraData)")
+
+	callbackArgs = []abi.Type{abi.MustParseType("bytes"), abi.MustParseType("bytes")}
+)
+
+// encodeCall encodes a call whose argument types are fixed by the package.
+func encodeCall(f abi.Function, args ...any) []byte {
+	data, err := f.EncodeCall(args...)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// decodeOutput decodes the return data of f.
+func decodeOutput(f abi.Function, data []byte) ([]any, error) {
+	values, err := f.DecodeOutput(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	return values, nil
+}
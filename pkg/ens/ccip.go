@@ -0,0 +1,160 @@
+package ens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go-ethereum-chains/pkg/abi"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+// ErrOffchainLookup is wrapped into errors of CCIP-Read (EIP-3668) offchain lookups.
+var ErrOffchainLookup = errors.New("offchain lookup failed")
+
+// offchainLookup is the decoded OffchainLookup revert of EIP-3668.
+type offchainLookup struct {
+	Sender    ethtypes.Address
+	URLs      []string
+	CallData  []byte
+	Callback  [4]byte
+	ExtraData []byte
+}
+
+// call performs an eth_call to contract and follows up to Options.MaxLookups CCIP-Read
+// offchain lookups it reverts with.
+func (r *Resolver) call(ctx context.Context, contract ethtypes.Address, data []byte, block ethtypes.BlockTag) ([]byte, error) {
+	for lookups := 0; ; lookups++ {
+		out, err := r.client.Call(ctx, ethclient.CallMsg{To: &contract, Data: data}, block)
+		if err == nil {
+			return out, nil
+		}
+		revert, ok := ethclient.AsRevert(err)
+		if !ok {
+			return nil, err
+		}
+		lookup, isLookup, lookupErr := decodeOffchainLookup(revert.Data)
+		if !isLookup {
+			return nil, err
+		}
+		if lookups >= r.opts.MaxLookups {
+			return nil, fmt.Errorf("%w: more than %d lookups", ErrOffchainLookup, r.opts.MaxLookups)
+		}
+
+		if lookupErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrOffchainLookup, lookupErr)
+		}
+		if lookup.Sender != contract {
+			return nil, fmt.Errorf("%w: sender %s is not the called contract %s", ErrOffchainLookup, lookup.Sender, contract)
+		}
+		response, err := r.queryGateways(ctx, lookup)
+		if err != nil {
+			return nil, err
+		}
+		args, err := abi.Encode(callbackArgs, response, lookup.ExtraData)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrOffchainLookup, err)
+		}
+		data = append(lookup.Callback[:], args...)
+	}
+}
+
+// decodeOffchainLookup decodes revert data if it is an OffchainLookup error.
+func decodeOffchainLookup(revert []byte) (offchainLookup, bool, error) {
+	sel := offchainLookupFunc.Selector()
+	if !bytes.HasPrefix(revert, sel[:]) {
+		return offchainLookup{}, false, nil
+	}
+	values, err := offchainLookupFunc.DecodeCall(revert)
+	if err != nil {
+		return offchainLookup{}, true, err
+	}
+	lookup := offchainLookup{
+		Sender:    values[0].(ethtypes.Address),
+		CallData:  values[2].([]byte),
+		Callback:  [4]byte(values[3].([]byte)),
+		ExtraData: values[4].([]byte),
+	}
+	for _, url := range values[1].([]any) {
+		lookup.URLs = append(lookup.URLs, url.(string))
+	}
+	return lookup, true, nil
+}
+
+// queryGateways tries the lookup's gateway URLs in order. As EIP-3668 requires, a 4xx
+// response ends the lookup while other failures move on to the next URL.
+func (r *Resolver) queryGateways(ctx context.Context, lookup offchainLookup) ([]byte, error) {
+	var errs []error
+	for _, template := range lookup.URLs {
+		response, status, err := r.queryGateway(ctx, template, lookup)
+		if err == nil {
+			return response, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", template, err))
+		if status >= 400 && status < 500 {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("%w: no gateway URLs", ErrOffchainLookup)
+	}
+	return nil, fmt.Errorf("%w: %w", ErrOffchainLookup, errors.Join(errs...))
+}
+
+// queryGateway sends a lookup to one gateway: a GET if the URL template contains {data},
+// otherwise a POST of {"data", "sender"}. It returns the response data and the HTTP status.
+func (r *Resolver) queryGateway(ctx context.Context, template string, lookup offchainLookup) ([]byte, int, error) {
+	sender := lookup.Sender.Hex()
+	callData := ethtypes.EncodeBytes(lookup.CallData)
+	url := strings.ReplaceAll(template, "{sender}", sender)
+
+	ctx, cancel := context.WithTimeout(ctx, r.opts.GatewayTimeout)
+	defer cancel()
+
+	var req *http.Request
+	var err error
+	if strings.Contains(url, "{data}") {
+		url = strings.ReplaceAll(url, "{data}", callData)
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	} else {
+		body, _ := json.Marshal(map[string]string{"data": callData, "sender": sender})
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := r.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGatewayResponse))
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	var result struct {
+		Data ethtypes.Bytes `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("invalid gateway response: %w", err)
+	}
+	return result.Data, resp.StatusCode, nil
+}
+
+// maxGatewayResponse caps the size of a gateway response body.
+const maxGatewayResponse = 4 << 20
@@ -0,0 +1,273 @@
+// Package ens resolves ENS names using the registry and Universal Resolver recorded in a
+// chain's EnsRegistry and EnsUniversalResolver, including CCIP-Read (EIP-3668) offchain
+// lookups.
+package ens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/rpc"
+)
+
+var (
+	// ErrUnsupportedChain is returned by NewResolver for chains without ENS contracts.
+	ErrUnsupportedChain = errors.New("chain has no ENS registry")
+	// ErrNotFound is returned when a name has no resolver or the requested record is not set.
+	ErrNotFound = errors.New("ENS record not found")
+	// ErrUnverified is returned by Name when the reverse record does not resolve back to the
+	// address.
+	ErrUnverified = errors.New("ENS reverse record not verified")
+)
+
+// Options defines parameters for a Resolver.
+type Options struct {
+	// HTTPClient is used for CCIP-Read gateway requests (defaults to rpc.DefaultHTTPClient()).
+	HTTPClient *http.Client
+	// GatewayTimeout bounds each gateway request.
+	GatewayTimeout time.Duration
+	// MaxLookups is the number of chained offchain lookups followed per call (0 disables CCIP-Read).
+	MaxLookups int
+	// Normalize normalizes names before they are hashed (defaults to Normalize, which only
+	// handles ASCII names). Set it to a full ENSIP-15 implementation to resolve other names.
+	Normalize func(name string) (string, error)
+}
+
+// DefaultOptions returns the options recommended by EIP-3668.
+func DefaultOptions() Options {
+	return Options{
+		HTTPClient:     rpc.DefaultHTTPClient(),
+		GatewayTimeout: 10 * time.Second,
+		MaxLookups:     4,
+		Normalize:      Normalize,
+	}
+}
+
+// Resolver resolves ENS names on the chain of an ethclient.Client.
+type Resolver struct {
+	client    *ethclient.Client
+	opts      Options
+	registry  *contract
+	universal *contract
+}
+
+// contract is a parsed types.Contract.
+type contract struct {
+	address      ethtypes.Address
+	blockCreated uint64
+}
+
+// NewResolver creates a Resolver for the chain of client.
+func NewResolver(client *ethclient.Client, opts Options) (*Resolver, error) {
+	chain := client.Chain()
+	registry, err := parseContract(chain.EnsRegistry)
+	if err != nil {
+		return nil, fmt.Errorf("ENS registry of %s: %w", chain.Name, err)
+	}
+	universal, err := parseContract(chain.EnsUniversalResolver)
+	if err != nil {
+		return nil, fmt.Errorf("ENS universal resolver of %s: %w", chain.Name, err)
+	}
+	if registry == nil && universal == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedChain, chain.Name)
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = rpc.DefaultHTTPClient()
+	}
+	if opts.GatewayTimeout <= 0 {
+		opts.GatewayTimeout = DefaultOptions().GatewayTimeout
+	}
+	if opts.Normalize == nil {
+		opts.Normalize = Normalize
+	}
+	return &Resolver{client: client, opts: opts, registry: registry, universal: universal}, nil
+}
+
+func parseContract(c *types.Contract) (*contract, error) {
+	if c == nil {
+		return nil, nil
+	}
+	address, err := ethtypes.ParseAddress(c.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &contract{address: address, blockCreated: c.BlockCreated}, nil
+}
+
+// deployedAt reports whether c exists at block. Named tags other than "earliest" are assumed
+// to be past the deployment.
+func (c *contract) deployedAt(block ethtypes.BlockTag) bool {
+	if c == nil {
+		return false
+	}
+	if block == ethtypes.Earliest {
+		return c.blockCreated == 0
+	}
+	if n, ok := block.Number(); ok {
+		return n >= c.blockCreated
+	}
+	return true
+}
+
+// Address resolves the ETH address of name at block.
+func (r *Resolver) Address(ctx context.Context, name string, block ethtypes.BlockTag) (ethtypes.Address, error) {
+	normalized, err := r.opts.Normalize(name)
+	if err != nil {
+		return ethtypes.Address{}, err
+	}
+	out, err := r.resolve(ctx, normalized, encodeCall(addrFunc, Namehash(normalized)), block)
+	if err != nil {
+		return ethtypes.Address{}, err
+	}
+	values, err := decodeOutput(addrFunc, out)
+	if err != nil {
+		return ethtypes.Address{}, fmt.Errorf("addr of %s: %w", normalized, err)
+	}
+	addr := values[0].(ethtypes.Address)
+	if addr.IsZero() {
+		return ethtypes.Address{}, fmt.Errorf("%w: addr of %s", ErrNotFound, normalized)
+	}
+	return addr, nil
+}
+
+// Text resolves the text record key of name at block.
+func (r *Resolver) Text(ctx context.Context, name, key string, block ethtypes.BlockTag) (string, error) {
+	normalized, err := r.opts.Normalize(name)
+	if err != nil {
+		return "", err
+	}
+	out, err := r.resolve(ctx, normalized, encodeCall(textFunc, Namehash(normalized), key), block)
+	if err != nil {
+		return "", err
+	}
+	values, err := decodeOutput(textFunc, out)
+	if err != nil {
+		return "", fmt.Errorf("text %q of %s: %w", key, normalized, err)
+	}
+	text := values[0].(string)
+	if text == "" {
+		return "", fmt.Errorf("%w: text %q of %s", ErrNotFound, key, normalized)
+	}
+	return text, nil
+}
+
+// Name returns the primary name of addr at block. The name is only returned if it is
+// normalized and its forward resolution is addr; otherwise the error wraps ErrUnverified.
+func (r *Resolver) Name(ctx context.Context, addr ethtypes.Address, block ethtypes.BlockTag) (string, error) {
+	name, resolved, err := r.reverse(ctx, addr, block)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("%w: name of %s", ErrNotFound, addr)
+	}
+	if normalized, err := r.opts.Normalize(name); err != nil || normalized != name {
+		return "", fmt.Errorf("%w: %s has non-normalized name %q", ErrUnverified, addr, name)
+	}
+
+	if resolved == nil {
+		forward, err := r.Address(ctx, name, block)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+		resolved = &forward
+	}
+	if *resolved != addr {
+		return "", fmt.Errorf("%w: %s resolves to %s, not %s", ErrUnverified, name, *resolved, addr)
+	}
+	return name, nil
+}
+
+// resolve runs a resolver call for name, through the Universal Resolver when it is deployed
+// at block and through the registry otherwise.
+func (r *Resolver) resolve(ctx context.Context, name string, data []byte, block ethtypes.BlockTag) ([]byte, error) {
+	if r.universal.deployedAt(block) {
+		out, err := r.call(ctx, r.universal.address, encodeCall(resolveFunc, dnsEncode(name), data), block)
+		if err != nil {
+			return nil, r.notFound(name, err)
+		}
+		values, err := decodeOutput(resolveFunc, out)
+		if err != nil {
+			return nil, err
+		}
+		return values[0].([]byte), nil
+	}
+
+	resolver, err := r.resolverOf(ctx, name, block)
+	if err != nil {
+		return nil, err
+	}
+	out, err := r.call(ctx, resolver, data, block)
+	if err != nil {
+		return nil, r.notFound(name, err)
+	}
+	return out, nil
+}
+
+// reverse looks up the reverse record of addr. Through the Universal Resolver it also returns
+// the address the name resolves to; through the registry that is left to the caller.
+func (r *Resolver) reverse(ctx context.Context, addr ethtypes.Address, block ethtypes.BlockTag) (string, *ethtypes.Address, error) {
+	reverseName := ReverseName(addr)
+	if r.universal.deployedAt(block) {
+		out, err := r.call(ctx, r.universal.address, encodeCall(reverseFunc, dnsEncode(reverseName)), block)
+		if err != nil {
+			return "", nil, r.notFound(reverseName, err)
+		}
+		values, err := decodeOutput(reverseFunc, out)
+		if err != nil {
+			return "", nil, fmt.Errorf("reverse of %s: %w", addr, err)
+		}
+		resolved := values[1].(ethtypes.Address)
+		return values[0].(string), &resolved, nil
+	}
+
+	resolver, err := r.resolverOf(ctx, reverseName, block)
+	if err != nil {
+		return "", nil, err
+	}
+	out, err := r.call(ctx, resolver, encodeCall(nameFunc, Namehash(reverseName)), block)
+	if err != nil {
+		return "", nil, r.notFound(reverseName, err)
+	}
+	values, err := decodeOutput(nameFunc, out)
+	if err != nil {
+		return "", nil, fmt.Errorf("reverse of %s: %w", addr, err)
+	}
+	return values[0].(string), nil, nil
+}
+
+// resolverOf returns the resolver the registry records for name. Wildcard resolution
+// (ENSIP-10) is only available through the Universal Resolver.
+func (r *Resolver) resolverOf(ctx context.Context, name string, block ethtypes.BlockTag) (ethtypes.Address, error) {
+	if !r.registry.deployedAt(block) {
+		return ethtypes.Address{}, fmt.Errorf("%w: no ENS registry at block %s", ErrNotFound, block)
+	}
+	out, err := r.call(ctx, r.registry.address, encodeCall(resolverFunc, Namehash(name)), block)
+	if err != nil {
+		return ethtypes.Address{}, err
+	}
+	values, err := decodeOutput(resolverFunc, out)
+	if err != nil {
+		return ethtypes.Address{}, fmt.Errorf("resolver of %s: %w", name, err)
+	}
+	resolver := values[0].(ethtypes.Address)
+	if resolver.IsZero() {
+		return ethtypes.Address{}, fmt.Errorf("%w: no resolver for %s", ErrNotFound, name)
+	}
+	return resolver, nil
+}
+
+// notFound maps a plain revert, as resolvers and the Universal Resolver raise for missing
+// names and records, to ErrNotFound. Failed offchain lookups and transport errors are kept.
+func (r *Resolver) notFound(name string, err error) error {
+	if _, ok := ethclient.AsRevert(err); ok && !errors.Is(err, ErrOffchainLookup) {
+		return fmt.Errorf("%w: %s: %w", ErrNotFound, name, err)
+	}
+	return err
+}
@@ -0,0 +1,364 @@
+package ens
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/abi"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testRegistry  = ethtypes.MustParseAddress("0x00000000000c2e074ec69a0dfb2997ba6c7d2e1e")
+	testUniversal = ethtypes.MustParseAddress("0xc0497e381f536be9ce14b0dd3817cbcae57d2f62")
+	testResolver  = ethtypes.MustParseAddress("0x00000000000000000000000000000000000000f1")
+	offchainRes   = ethtypes.MustParseAddress("0x00000000000000000000000000000000000000f2")
+	alice         = ethtypes.MustParseAddress("0x00000000000000000000000000000000000000a1")
+	bob           = ethtypes.MustParseAddress("0x00000000000000000000000000000000000000b0")
+	carol         = ethtypes.MustParseAddress("0x00000000000000000000000000000000000000c0")
+
+	resolveCallback = abi.MustParseFunction("resolveCallback(bytes response, bytes extraData) view returns (bytes, address)")
+)
+
+// ensNode is a stand-in node running the registry, a public resolver and a Universal
+// Resolver that defers names under offchain.eth to CCIP-Read gateways.
+type ensNode struct {
+	resolvers map[ethtypes.Hash]ethtypes.Address
+	addrs     map[ethtypes.Hash]ethtypes.Address
+	names     map[ethtypes.Hash]string
+	texts     map[string]string
+	gateways  []string
+
+	mu      sync.Mutex
+	targets []ethtypes.Address
+}
+
+func newENSNode() *ensNode {
+	n := &ensNode{
+		resolvers: make(map[ethtypes.Hash]ethtypes.Address),
+		addrs:     make(map[ethtypes.Hash]ethtypes.Address),
+		names:     make(map[ethtypes.Hash]string),
+		texts:     make(map[string]string),
+	}
+	// alice.eth <-> alice, with a text record.
+	n.resolvers[Namehash("alice.eth")] = testResolver
+	n.addrs[Namehash("alice.eth")] = alice
+	n.texts[Namehash("alice.eth").Hex()+"|url"] = "https://alice.example"
+	n.resolvers[Namehash(ReverseName(alice))] = testResolver
+	n.names[Namehash(ReverseName(alice))] = "alice.eth"
+	// bob claims alice.eth as primary name.
+	n.resolvers[Namehash(ReverseName(bob))] = testResolver
+	n.names[Namehash(ReverseName(bob))] = "alice.eth"
+	// carol has a non-normalized primary name.
+	n.resolvers[Namehash(ReverseName(carol))] = testResolver
+	n.names[Namehash(ReverseName(carol))] = "Carol.eth"
+	n.resolvers[Namehash("carol.eth")] = testResolver
+	n.addrs[Namehash("carol.eth")] = carol
+	return n
+}
+
+// revert is a JSON-RPC execution revert carrying data.
+type revert struct{ data []byte }
+
+func (n *ensNode) eth_call(to ethtypes.Address, data []byte) ([]byte, *revert) {
+	n.mu.Lock()
+	n.targets = append(n.targets, to)
+	n.mu.Unlock()
+
+	sel := [4]byte(data[:4])
+	switch {
+	case to == testRegistry && sel == resolverFunc.Selector():
+		args := decodeArgs(resolverFunc, data)
+		return returns(resolverFunc, n.resolvers[ethtypes.Hash(args[0].([]byte))]), nil
+
+	case to == testResolver && sel == addrFunc.Selector():
+		args := decodeArgs(addrFunc, data)
+		return returns(addrFunc, n.addrs[ethtypes.Hash(args[0].([]byte))]), nil
+
+	case to == testResolver && sel == nameFunc.Selector():
+		args := decodeArgs(nameFunc, data)
+		return returns(nameFunc, n.names[ethtypes.Hash(args[0].([]byte))]), nil
+
+	case to == testResolver && sel == textFunc.Selector():
+		args := decodeArgs(textFunc, data)
+		return returns(textFunc, n.texts[ethtypes.Hash(args[0].([]byte)).Hex()+"|"+args[1].(string)]), nil
+
+	case to == testUniversal && sel == resolveFunc.Selector():
+		args := decodeArgs(resolveFunc, data)
+		name, inner := decodeDNS(args[0].([]byte)), args[1].([]byte)
+		if strings.HasSuffix(name, "offchain.eth") {
+			sender := testUniversal
+			if name == "evil.offchain.eth" {
+				sender = offchainRes
+			}
+			urls := n.gateways
+			if name == "post.offchain.eth" {
+				urls = []string{strings.Split(n.gateways[len(n.gateways)-1], "/{sender}")[0] + "/post"}
+			}
+			lookup := encodeCall(offchainLookupFunc, sender, urls, inner, resolveCallback.Selector(), []byte(name))
+			return nil, &revert{lookup}
+		}
+		resolver := n.resolvers[Namehash(name)]
+		if resolver.IsZero() {
+			notFound := abi.Selector("ResolverNotFound()")
+			return nil, &revert{notFound[:]}
+		}
+		out, rev := n.eth_call(resolver, inner)
+		if rev != nil {
+			return nil, rev
+		}
+		return returns(resolveFunc, out, resolver), nil
+
+	case to == testUniversal && sel == resolveCallback.Selector():
+		args := decodeArgs(resolveCallback, data)
+		return returns(resolveFunc, args[0], offchainRes), nil
+
+	case to == testUniversal && sel == reverseFunc.Selector():
+		args := decodeArgs(reverseFunc, data)
+		reverseNode := Namehash(decodeDNS(args[0].([]byte)))
+		name := n.names[reverseNode]
+		resolved := n.addrs[Namehash(strings.ToLower(name))]
+		resolver := n.resolvers[reverseNode]
+		return returns(reverseFunc, name, resolved, resolver, resolver), nil
+	}
+	return nil, &revert{}
+}
+
+func decodeArgs(f abi.Function, data []byte) []any {
+	args, err := f.DecodeCall(data)
+	if err != nil {
+		panic(err)
+	}
+	return args
+}
+
+func returns(f abi.Function, values ...any) []byte {
+	out, err := abi.Encode(f.Outputs.Types(), values...)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// decodeDNS decodes a DNS-encoded name.
+func decodeDNS(b []byte) string {
+	var labels []string
+	for len(b) > 0 && b[0] != 0 {
+		labels = append(labels, string(b[1:1+b[0]]))
+		b = b[1+b[0]:]
+	}
+	return strings.Join(labels, ".")
+}
+
+// newTestResolver starts the stand-in node and returns a Resolver for a chain served by it,
+// whose Universal Resolver is created at block 100.
+func newTestResolver(t *testing.T, chainID int64) (*Resolver, *ensNode) {
+	t.Helper()
+	node := newENSNode()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     chainstypes.JsonRPCID `json:"id"`
+			Method string                `json:"method"`
+			Params []json.RawMessage     `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "eth_call", req.Method)
+		var msg struct {
+			To    ethtypes.Address `json:"to"`
+			Input ethtypes.Bytes   `json:"input"`
+		}
+		require.NoError(t, json.Unmarshal(req.Params[0], &msg))
+
+		resp := chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID}
+		out, rev := node.eth_call(msg.To, msg.Input)
+		if rev != nil {
+			data, _ := json.Marshal(ethtypes.Bytes(rev.data))
+			resp.Error = &chainstypes.JsonRPCError{Code: 3, Message: "execution reverted", Data: data}
+		} else {
+			resp.Result, _ = json.Marshal(ethtypes.Bytes(out))
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	registry.RegisterChain(chainstypes.Chain{
+		ID:                   big.NewInt(chainID),
+		Name:                 t.Name(),
+		RPCUrls:              map[string]chainstypes.RpcTarget{"default": {Http: []string{server.URL}}},
+		EnsRegistry:          &chainstypes.Contract{Address: testRegistry.Hex()},
+		EnsUniversalResolver: &chainstypes.Contract{Address: testUniversal.Hex(), BlockCreated: 100},
+	})
+	clientOpts := rpc.DefaultClientOptions()
+	clientOpts.Retry.MaxAttempts = 1
+	client, err := ethclient.NewClient(chainID, clientOpts)
+	require.NoError(t, err)
+	resolver, err := NewResolver(client, DefaultOptions())
+	require.NoError(t, err)
+	return resolver, node
+}
+
+// TestResolver_Onchain tests forward, text and reverse resolution through the Universal
+// Resolver and, before its deployment, through the registry.
+func TestResolver_Onchain(t *testing.T) {
+	r, node := newTestResolver(t, 7815)
+	ctx := context.Background()
+
+	for name, block := range map[string]ethtypes.BlockTag{"universal": ethtypes.Latest, "registry": ethtypes.BlockNumber(50)} {
+		t.Run(name, func(t *testing.T) {
+			node.targets = nil
+
+			addr, err := r.Address(ctx, "Alice.ETH", block)
+			require.NoError(t, err)
+			assert.Equal(t, alice, addr)
+
+			text, err := r.Text(ctx, "alice.eth", "url", block)
+			require.NoError(t, err)
+			assert.Equal(t, "https://alice.example", text)
+
+			_, err = r.Text(ctx, "alice.eth", "avatar", block)
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			_, err = r.Address(ctx, "nobody.eth", block)
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			_, err = r.Address(ctx, "bad name.eth", block)
+			assert.ErrorIs(t, err, ErrInvalidName)
+
+			name, err := r.Name(ctx, alice, block)
+			require.NoError(t, err)
+			assert.Equal(t, "alice.eth", name)
+
+			_, err = r.Name(ctx, bob, block)
+			assert.ErrorIs(t, err, ErrUnverified)
+
+			_, err = r.Name(ctx, carol, block)
+			assert.ErrorIs(t, err, ErrUnverified)
+
+			_, err = r.Name(ctx, ethtypes.Address{}, block)
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			universal := block == ethtypes.Latest
+			assert.Equal(t, universal, slices.Contains(node.targets, testUniversal))
+			assert.Equal(t, !universal, slices.Contains(node.targets, testRegistry))
+		})
+	}
+
+	// Names Normalize cannot handle are rejected unless a full normalizer is configured.
+	_, err := r.Address(ctx, "\u00e1lice.eth", ethtypes.Latest)
+	assert.ErrorIs(t, err, ErrUnsupportedName)
+	r.opts.Normalize = func(name string) (string, error) {
+		return strings.Replace(name, "\u00e1", "a", 1), nil
+	}
+	addr, err := r.Address(ctx, "\u00e1lice.eth", ethtypes.Latest)
+	require.NoError(t, err)
+	assert.Equal(t, alice, addr)
+}
+
+// TestResolver_CCIPRead tests offchain lookups against local stand-in gateways.
+func TestResolver_CCIPRead(t *testing.T) {
+	r, node := newTestResolver(t, 7816)
+	ctx := context.Background()
+
+	var failing, gets, posts atomic.Int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		failing.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(down.Close)
+
+	// answer serves addr and text calls for names under offchain.eth.
+	answer := func(callData []byte) []byte {
+		switch [4]byte(callData[:4]) {
+		case addrFunc.Selector():
+			return returns(addrFunc, carol)
+		case textFunc.Selector():
+			return returns(textFunc, "from the gateway")
+		}
+		return nil
+	}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var callData []byte
+		var sender string
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/post":
+			posts.Add(1)
+			var body struct {
+				Data   ethtypes.Bytes `json:"data"`
+				Sender string         `json:"sender"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			callData, sender = body.Data, body.Sender
+		case r.Method == http.MethodGet:
+			gets.Add(1)
+			parts := strings.Split(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".json"), "/")
+			require.Len(t, parts, 2)
+			sender = parts[0]
+			var err error
+			callData, err = ethtypes.DecodeBytes(parts[1])
+			require.NoError(t, err)
+		}
+		if sender != testUniversal.Hex() || len(callData) < 4 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]ethtypes.Bytes{"data": answer(callData)})
+	}))
+	t.Cleanup(gateway.Close)
+
+	node.gateways = []string{down.URL + "/{sender}/{data}.json", gateway.URL + "/{sender}/{data}.json"}
+
+	addr, err := r.Address(ctx, "dave.offchain.eth", ethtypes.Latest)
+	require.NoError(t, err)
+	assert.Equal(t, carol, addr)
+	assert.Equal(t, int32(1), failing.Load())
+	assert.Equal(t, int32(1), gets.Load())
+
+	text, err := r.Text(ctx, "post.offchain.eth", "url", ethtypes.Latest)
+	require.NoError(t, err)
+	assert.Equal(t, "from the gateway", text)
+	assert.Equal(t, int32(1), posts.Load())
+
+	// The sender of an offchain lookup must be the called contract.
+	_, err = r.Address(ctx, "evil.offchain.eth", ethtypes.Latest)
+	assert.ErrorIs(t, err, ErrOffchainLookup)
+	assert.NotErrorIs(t, err, ErrNotFound)
+
+	// A 4xx response ends the lookup without trying further gateways.
+	node.gateways = []string{gateway.URL + "/nobody/{data}.json", down.URL + "/{sender}/{data}.json"}
+	_, err = r.Address(ctx, "dave.offchain.eth", ethtypes.Latest)
+	assert.ErrorIs(t, err, ErrOffchainLookup)
+	assert.Equal(t, int32(1), failing.Load())
+
+	// Offchain lookups can be disabled.
+	r.opts.MaxLookups = 0
+	_, err = r.Address(ctx, "dave.offchain.eth", ethtypes.Latest)
+	assert.ErrorIs(t, err, ErrOffchainLookup)
+}
+
+// TestNewResolver_Unsupported tests that chains without ENS contracts are rejected.
+func TestNewResolver_Unsupported(t *testing.T) {
+	registry.RegisterChain(chainstypes.Chain{
+		ID:      big.NewInt(7817),
+		Name:    t.Name(),
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {Http: []string{"http://127.0.0.1:1"}}},
+	})
+	client, err := ethclient.NewClient(7817, rpc.DefaultClientOptions())
+	require.NoError(t, err)
+	_, err = NewResolver(client, DefaultOptions())
+	assert.ErrorIs(t, err, ErrUnsupportedChain)
+}
@@ -0,0 +1,122 @@
+package ens
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go-ethereum-chains/internal/keccak"
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+var (
+	// ErrInvalidName is wrapped into every normalization error for a name ENSIP-15 rejects.
+	ErrInvalidName = errors.New("invalid ENS name")
+	// ErrUnsupportedName is returned by Normalize for names outside the subset it handles.
+	ErrUnsupportedName = errors.New("ENS name not supported by Normalize")
+)
+
+// Normalize returns the ENSIP-15 normalized form of name, or an error wrapping ErrInvalidName.
+//
+// It is not a conforming ENSIP-15 implementation: without the Unicode tables the spec needs,
+// it only handles names whose labels are plain ASCII, where its result is the one ENSIP-15
+// gives:
+//   - labels are lowercased and must be non-empty;
+//   - labels may only contain a-z, 0-9, '-' and leading '_', and may not have "--" at the
+//     third and fourth position.
+//
+// Any other character, including letters with diacritics and emoji, is rejected with
+// ErrUnsupportedName rather than guessed at; use Options.Normalize to plug in a full
+// implementation for such names.
+func Normalize(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	for i := 0; i < len(name); i++ {
+		if name[i] >= utf8.RuneSelf {
+			return "", fmt.Errorf("%w: %q has non-ASCII characters", ErrUnsupportedName, name)
+		}
+	}
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		normalized, err := normalizeLabel(label)
+		if err != nil {
+			return "", fmt.Errorf("%w: label %q: %v", ErrInvalidName, label, err)
+		}
+		labels[i] = normalized
+	}
+	return strings.Join(labels, "."), nil
+}
+
+// normalizeLabel normalizes a single ASCII label.
+func normalizeLabel(label string) (string, error) {
+	label = strings.ToLower(label)
+	if label == "" {
+		return "", errors.New("empty label")
+	}
+
+	leading := true
+	for _, r := range label {
+		if r != '_' {
+			leading = false
+		}
+		switch {
+		case r == '_':
+			if !leading {
+				return "", errors.New("underscore allowed only at the start")
+			}
+		case r == '-', 'a' <= r && r <= 'z', '0' <= r && r <= '9':
+		default:
+			return "", fmt.Errorf("disallowed character %q", r)
+		}
+	}
+
+	if len(label) >= 4 && label[2:4] == "--" {
+		return "", errors.New(`"--" at the third and fourth position`)
+	}
+	return label, nil
+}
+
+// LabelHash returns the keccak256 hash of a single label.
+func LabelHash(label string) ethtypes.Hash {
+	return ethtypes.Hash(keccak.Sum256([]byte(label)))
+}
+
+// Namehash returns the ENSIP-1 namehash of a normalized name. The empty name hashes to zero.
+func Namehash(name string) ethtypes.Hash {
+	var node ethtypes.Hash
+	if name == "" {
+		return node
+	}
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := LabelHash(labels[i])
+		node = ethtypes.Hash(keccak.Sum256(node[:], label[:]))
+	}
+	return node
+}
+
+// ReverseName returns the name of the reverse record of addr, "<hex address>.addr.reverse".
+func ReverseName(addr ethtypes.Address) string {
+	return hex.EncodeToString(addr[:]) + ".addr.reverse"
+}
+
+// dnsEncode encodes a normalized name in DNS wire format as used by ENSIP-10. Labels longer
+// than 255 bytes are replaced by their bracketed label hash.
+func dnsEncode(name string) []byte {
+	var out []byte
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			if len(label) > 255 {
+				hash := LabelHash(label)
+				label = "[" + hex.EncodeToString(hash[:]) + "]"
+			}
+			out = append(out, byte(len(label)))
+			out = append(out, label...)
+		}
+	}
+	return append(out, 0)
+}
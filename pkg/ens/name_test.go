@@ -0,0 +1,113 @@
+package ens
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name        string
+		want        string
+		invalid     bool
+		unsupported bool
+	}{
+		{name: "", want: ""},
+		{name: "vitalik.eth", want: "vitalik.eth"},
+		{name: "Nick.ETH", want: "nick.eth"},
+		{name: "_dmarc.example.eth", want: "_dmarc.example.eth"},
+		{name: "a-b.eth", want: "a-b.eth"},
+		{name: "café.eth", unsupported: true},
+		{name: "💩.eth", unsupported: true},
+		{name: "❤\ufe0f.eth", unsupported: true},
+		{name: "vit\u0430lik.eth", unsupported: true},
+		{name: "a\u3002eth", unsupported: true},
+		{name: "a\u200bb.eth", unsupported: true},
+		{name: "\xff.eth", unsupported: true},
+		{name: "a..eth", invalid: true},
+		{name: "eth.", invalid: true},
+		{name: "a b.eth", invalid: true},
+		{name: "a_b.eth", invalid: true},
+		{name: "ab--c.eth", invalid: true},
+		{name: "xn--80ak6aa92e.eth", invalid: true},
+		{name: "a!.eth", invalid: true},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.name)
+		if tt.unsupported {
+			if !errors.Is(err, ErrUnsupportedName) {
+				t.Errorf("Normalize(%q) = %q, %v, want ErrUnsupportedName", tt.name, got, err)
+			}
+			continue
+		}
+		if tt.invalid {
+			if !errors.Is(err, ErrInvalidName) {
+				t.Errorf("Normalize(%q) = %q, %v, want ErrInvalidName", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestNamehash(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", "0x0000000000000000000000000000000000000000000000000000000000000000"},
+		{"eth", "0x93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae"},
+		{"foo.eth", "0xde9b09fd7c5f901e23a3f19fecc54828e9c848539801e86591bd9801b019f84f"},
+	}
+	for _, tt := range tests {
+		if got := Namehash(tt.name).Hex(); got != tt.want {
+			t.Errorf("Namehash(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	tests := []struct {
+		got  [4]byte
+		want string
+	}{
+		{resolverFunc.Selector(), "0178b8bf"},
+		{addrFunc.Selector(), "3b3b57de"},
+		{nameFunc.Selector(), "691f3431"},
+		{textFunc.Selector(), "59d1d43c"},
+		{resolveFunc.Selector(), "9061b923"},
+		{reverseFunc.Selector(), "ec11c823"},
+		{offchainLookupFunc.Selector(), "556f1830"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(tt.got[:]); got != tt.want {
+			t.Errorf("selector = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestDNSEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", "00"},
+		{"eth", "0365746800"},
+		{"foo.eth", "03666f6f0365746800"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(dnsEncode(tt.name)); got != tt.want {
+			t.Errorf("dnsEncode(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	addr := ethtypes.MustParseAddress("0x00000000000000000000000000000000000000aa")
+	if got, want := ReverseName(addr), "00000000000000000000000000000000000000aa.addr.reverse"; got != want {
+		t.Errorf("ReverseName = %s, want %s", got, want)
+	}
+}
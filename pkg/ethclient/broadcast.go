@@ -0,0 +1,132 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go-ethereum-chains/internal/keccak"
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+// ErrNotBroadcast is returned when no endpoint accepted a broadcast transaction.
+var ErrNotBroadcast = errors.New("transaction not accepted by any endpoint")
+
+// knownMessages are the lowercased error messages nodes answer a resubmitted transaction with
+// (geth and its forks, Besu, Nethermind, Erigon and several L2 sequencers).
+var knownMessages = []string{
+	"already known",
+	"known transaction",
+	"already imported",
+	"alreadyknown",
+	"already exists",
+	"already in mempool",
+}
+
+// nonceTooLowMessages are the lowercased error messages of a transaction whose nonce is already
+// used, which may be by this very transaction.
+var nonceTooLowMessages = []string{
+	"nonce too low",
+	"nonce is too low",
+	"oldnonce",
+}
+
+// BroadcastOptions defines parameters for BroadcastTransaction.
+type BroadcastOptions struct {
+	// Endpoints is the number of permitted endpoints the transaction is sent to (0 sends it to all).
+	Endpoints int
+}
+
+// SendOutcome is the answer of one endpoint to a broadcast transaction.
+type SendOutcome struct {
+	URL string
+	// Accepted reports whether the endpoint has the transaction: it accepted it, it already knew
+	// it, or it already mined it.
+	Accepted bool
+	// Known reports that the endpoint already had the transaction before this submission.
+	Known bool
+	// Err is the endpoint's error; it is kept for known transactions too.
+	Err error
+}
+
+// Broadcast is the result of BroadcastTransaction.
+type Broadcast struct {
+	// Hash is the transaction hash, computed from the raw transaction.
+	Hash ethtypes.Hash
+	// Outcomes holds the answer of every endpoint the transaction was sent to.
+	Outcomes []SendOutcome
+}
+
+// Accepted returns the number of endpoints that have the transaction.
+func (b *Broadcast) Accepted() int {
+	n := 0
+	for _, o := range b.Outcomes {
+		if o.Accepted {
+			n++
+		}
+	}
+	return n
+}
+
+// BroadcastTransaction submits a signed, RLP-encoded transaction to several endpoints in
+// parallel, so that a single node dropping it does not lose it. An endpoint that answers that it
+// already knows the transaction, or that its nonce is too low while it has the transaction
+// itself, counts as accepting it. The error wraps ErrNotBroadcast, and the returned Broadcast
+// still reports every outcome, if no endpoint accepted the transaction.
+func (c *Client) BroadcastTransaction(ctx context.Context, rawTx []byte, opts BroadcastOptions) (*Broadcast, error) {
+	b := &Broadcast{Hash: ethtypes.Hash(keccak.Sum256(rawTx))}
+	results, err := c.rpc.CallAll(ctx, opts.Endpoints, "eth_sendRawTransaction", ethtypes.Bytes(rawTx))
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, r := range results {
+		o := SendOutcome{URL: r.URL, Err: r.Err}
+		switch {
+		case r.Err == nil:
+			var hash ethtypes.Hash
+			if err := json.Unmarshal(r.Result, &hash); err != nil {
+				o.Err = fmt.Errorf("%s: failed to unmarshal transaction hash (%s): %w", r.URL, string(r.Result), err)
+			} else if hash != b.Hash {
+				o.Err = fmt.Errorf("%s: node returned hash %s, expected %s", r.URL, hash, b.Hash)
+			} else {
+				o.Accepted = true
+			}
+		case matchesAny(r.Err, knownMessages):
+			o.Accepted, o.Known = true, true
+		case matchesAny(r.Err, nonceTooLowMessages):
+			// The nonce is used; the transaction is known if it is the one that used it.
+			known := c.hasTransaction(ctx, r.URL, b.Hash)
+			o.Accepted, o.Known = known, known
+		}
+		if !o.Accepted {
+			errs = append(errs, o.Err)
+		}
+		b.Outcomes = append(b.Outcomes, o)
+	}
+
+	if b.Accepted() == 0 {
+		return b, fmt.Errorf("%w: %w", ErrNotBroadcast, errors.Join(errs...))
+	}
+	return b, nil
+}
+
+// hasTransaction reports whether the endpoint knows the transaction, pending or mined.
+func (c *Client) hasTransaction(ctx context.Context, url string, hash ethtypes.Hash) bool {
+	raw, err := c.rpc.CallEndpoint(ctx, url, "eth_getTransactionByHash", hash)
+	return err == nil && len(raw) > 0 && string(raw) != "null"
+}
+
+// matchesAny reports whether the lowercased error message contains one of the messages.
+func matchesAny(err error, messages []string) bool {
+	text := strings.ToLower(err.Error())
+	for _, m := range messages {
+		if strings.Contains(text, m) {
+			return true
+		}
+	}
+	return false
+}
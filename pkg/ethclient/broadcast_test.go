@@ -0,0 +1,105 @@
+package ethclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"go-ethereum-chains/internal/keccak"
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rpcHandler answers one JSON-RPC request with a result or an error.
+type rpcHandler func(method string) (result string, err *chainstypes.JsonRPCError)
+
+// ServeHTTP answers the request, or fails it with 503 Service Unavailable if the handler
+// returns neither a result nor an error.
+func (h rpcHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req chainstypes.JsonRPCRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	result, rpcErr := h(req.Method)
+	if rpcErr == nil && result == "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	resp := chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID, Error: rpcErr}
+	if rpcErr == nil {
+		resp.Result = json.RawMessage(result)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// TestClient_BroadcastTransaction tests which endpoint answers count as accepting a transaction.
+func TestClient_BroadcastTransaction(t *testing.T) {
+	rawTx := []byte{0x02, 0xf8, 0x6b, 0x01, 0x02, 0x03}
+	hash := ethtypes.Hash(keccak.Sum256(rawTx))
+	rejected := func(message string, hasTx bool) rpcHandler {
+		return func(method string) (string, *chainstypes.JsonRPCError) {
+			if method == "eth_getTransactionByHash" {
+				if hasTx {
+					return `{"hash":"` + hash.Hex() + `"}`, nil
+				}
+				return `null`, nil
+			}
+			return "", &chainstypes.JsonRPCError{Code: -32000, Message: message}
+		}
+	}
+	accepting := rpcHandler(func(method string) (string, *chainstypes.JsonRPCError) {
+		return `"` + hash.Hex() + `"`, nil
+	})
+	down := rpcHandler(func(string) (string, *chainstypes.JsonRPCError) { return "", nil })
+	ctx := context.Background()
+
+	t.Run("outcomes", func(t *testing.T) {
+		client, urls := newTestNodes(t, 7840,
+			accepting,
+			rejected("already known", false),
+			rejected("nonce too low: next nonce 5, tx nonce 4", true),
+			rejected("nonce too low: next nonce 5, tx nonce 4", false),
+			down,
+		)
+		b, err := client.BroadcastTransaction(ctx, rawTx, ethclient.BroadcastOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, hash, b.Hash)
+		assert.Equal(t, 3, b.Accepted())
+
+		require.Len(t, b.Outcomes, 5)
+		for i, want := range []struct{ accepted, known, failed bool }{
+			{accepted: true},
+			{accepted: true, known: true, failed: true},
+			{accepted: true, known: true, failed: true},
+			{failed: true},
+			{failed: true},
+		} {
+			o := b.Outcomes[i]
+			assert.Equal(t, urls[i], o.URL)
+			assert.Equal(t, want.accepted, o.Accepted, "endpoint %d", i)
+			assert.Equal(t, want.known, o.Known, "endpoint %d", i)
+			assert.Equal(t, want.failed, o.Err != nil, "endpoint %d", i)
+		}
+	})
+
+	t.Run("subset", func(t *testing.T) {
+		client, _ := newTestNodes(t, 7841, accepting, accepting, accepting)
+		b, err := client.BroadcastTransaction(ctx, rawTx, ethclient.BroadcastOptions{Endpoints: 2})
+		require.NoError(t, err)
+		assert.Len(t, b.Outcomes, 2)
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		client, _ := newTestNodes(t, 7842, rejected("insufficient funds for gas * price + value", false), down)
+		b, err := client.BroadcastTransaction(ctx, rawTx, ethclient.BroadcastOptions{})
+		require.ErrorIs(t, err, ethclient.ErrNotBroadcast)
+		assert.ErrorContains(t, err, "insufficient funds")
+		assert.Zero(t, b.Accepted())
+		assert.Len(t, b.Outcomes, 2)
+	})
+}
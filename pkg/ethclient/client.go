@@ -0,0 +1,171 @@
+// Package ethclient provides a typed Ethereum JSON-RPC client for chains in the registry. It is
+// built on the failover rpc.Client and decodes results into Go types without depending on
+// go-ethereum.
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/rpc"
+)
+
+// ErrNotFound is returned when a node answers null for a block, transaction or receipt.
+var ErrNotFound = errors.New("not found")
+
+// Client is a typed Ethereum JSON-RPC client. It is safe for concurrent use.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// NewClient creates a Client for a registered chain identified by ID or name.
+func NewClient(identifier any, opts rpc.ClientOptions) (*Client, error) {
+	c, err := rpc.NewClient(identifier, opts)
+	if err != nil {
+		return nil, err
+	}
+	return NewClientFromRPC(c), nil
+}
+
+// NewClientFromRPC creates a Client that sends its calls through an existing rpc.Client.
+func NewClientFromRPC(c *rpc.Client) *Client {
+	return &Client{rpc: c}
+}
+
+// RPC returns the underlying JSON-RPC client, e.g. for methods without a typed wrapper.
+func (c *Client) RPC() *rpc.Client {
+	return c.rpc
+}
+
+// Chain returns the chain the client was created for.
+func (c *Client) Chain() types.Chain {
+	return c.rpc.Chain()
+}
+
+// BlockNumber returns the number of the most recent block.
+func (c *Client) BlockNumber(ctx context.Context) (uint64, error) {
+	var n ethtypes.Uint64
+	err := c.rpc.Call(ctx, &n, "eth_blockNumber")
+	return uint64(n), err
+}
+
+// ChainID returns the chain ID reported by the node.
+func (c *Client) ChainID(ctx context.Context) (*big.Int, error) {
+	var id ethtypes.Big
+	if err := c.rpc.Call(ctx, &id, "eth_chainId"); err != nil {
+		return nil, err
+	}
+	return id.ToInt(), nil
+}
+
+// GetBalance returns the balance of an account in wei at the given block.
+func (c *Client) GetBalance(ctx context.Context, account ethtypes.Address, block ethtypes.BlockTag) (*big.Int, error) {
+	var balance ethtypes.Big
+	if err := c.rpc.Call(ctx, &balance, "eth_getBalance", account, block); err != nil {
+		return nil, err
+	}
+	return balance.ToInt(), nil
+}
+
+// GetTransactionCount returns the nonce of an account at the given block.
+func (c *Client) GetTransactionCount(ctx context.Context, account ethtypes.Address, block ethtypes.BlockTag) (uint64, error) {
+	var nonce ethtypes.Uint64
+	err := c.rpc.Call(ctx, &nonce, "eth_getTransactionCount", account, block)
+	return uint64(nonce), err
+}
+
+// Call executes a message call without creating a transaction and returns its return data.
+// Reverts are returned as *RevertError, which unwraps to the *types.JsonRPCError.
+func (c *Client) Call(ctx context.Context, msg CallMsg, block ethtypes.BlockTag) ([]byte, error) {
+	var data ethtypes.Bytes
+	if err := c.rpc.Call(ctx, &data, "eth_call", msg, block); err != nil {
+		return nil, revertOr(err)
+	}
+	return data, nil
+}
+
+// EstimateGas estimates the gas needed to execute msg against the pending state. Reverts are
+// returned as *RevertError.
+func (c *Client) EstimateGas(ctx context.Context, msg CallMsg) (uint64, error) {
+	var gas ethtypes.Uint64
+	if err := c.rpc.Call(ctx, &gas, "eth_estimateGas", msg); err != nil {
+		return 0, revertOr(err)
+	}
+	return uint64(gas), nil
+}
+
+// revertOr returns err as a *RevertError if it is an execution revert.
+func revertOr(err error) error {
+	if revert, ok := AsRevert(err); ok {
+		return revert
+	}
+	return err
+}
+
+// GasPrice returns the node's suggested legacy gas price in wei.
+func (c *Client) GasPrice(ctx context.Context) (*big.Int, error) {
+	var price ethtypes.Big
+	if err := c.rpc.Call(ctx, &price, "eth_gasPrice"); err != nil {
+		return nil, err
+	}
+	return price.ToInt(), nil
+}
+
+// FeeHistory returns base fees, gas usage ratios and the given reward percentiles for
+// blockCount blocks up to newest.
+func (c *Client) FeeHistory(ctx context.Context, blockCount uint64, newest ethtypes.BlockTag, rewardPercentiles []float64) (*FeeHistory, error) {
+	if rewardPercentiles == nil {
+		rewardPercentiles = []float64{}
+	}
+	var history FeeHistory
+	if err := c.rpc.Call(ctx, &history, "eth_feeHistory", ethtypes.EncodeUint64(blockCount), newest, rewardPercentiles); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// GetBlockByNumber returns a block, with full transactions if fullTx is set. It returns
+// ErrNotFound if the node does not have the block.
+func (c *Client) GetBlockByNumber(ctx context.Context, block ethtypes.BlockTag, fullTx bool) (*Block, error) {
+	var b *Block
+	if err := c.rpc.Call(ctx, &b, "eth_getBlockByNumber", block, fullTx); err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("block %s: %w", block, ErrNotFound)
+	}
+	return b, nil
+}
+
+// GetTransactionReceipt returns the receipt of a mined transaction. It returns ErrNotFound while
+// the transaction is pending or unknown.
+func (c *Client) GetTransactionReceipt(ctx context.Context, hash ethtypes.Hash) (*Receipt, error) {
+	var r *Receipt
+	if err := c.rpc.Call(ctx, &r, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, fmt.Errorf("receipt of %s: %w", hash, ErrNotFound)
+	}
+	return r, nil
+}
+
+// GetLogs returns the logs matching a filter.
+func (c *Client) GetLogs(ctx context.Context, query FilterQuery) ([]Log, error) {
+	var logs []Log
+	if err := c.rpc.Call(ctx, &logs, "eth_getLogs", query); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// SendRawTransaction submits a signed, RLP-encoded transaction and returns its hash.
+func (c *Client) SendRawTransaction(ctx context.Context, rawTx []byte) (ethtypes.Hash, error) {
+	var hash ethtypes.Hash
+	err := c.rpc.Call(ctx, &hash, "eth_sendRawTransaction", ethtypes.Bytes(rawTx))
+	return hash, err
+}
@@ -0,0 +1,237 @@
+package ethclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAccount = "0x00000000000000000000000000000000000000aa"
+	testTxHash  = "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"
+)
+
+// fakeNode answers each method with a canned result and records the params it received.
+type fakeNode struct {
+	results map[string]string
+	params  map[string]json.RawMessage
+}
+
+// ServeHTTP answers the request with the canned result for its method.
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     chainstypes.JsonRPCID `json:"id"`
+		Method string                `json:"method"`
+		Params json.RawMessage       `json:"params"`
+	}
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	n.params[req.Method] = req.Params
+
+	resp := chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID}
+	if result, ok := n.results[req.Method]; ok {
+		resp.Result = json.RawMessage(result)
+	} else {
+		resp.Error = &chainstypes.JsonRPCError{Code: -32601, Message: "method not found"}
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// decodeRequest decodes the JSON-RPC request into v, answering 400 Bad Request if it is malformed.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// newTestClient starts a fake node and returns a client for a chain served only by it.
+func newTestClient(t *testing.T, chainID int64, results map[string]string) (*ethclient.Client, *fakeNode) {
+	t.Helper()
+	node := &fakeNode{results: results, params: make(map[string]json.RawMessage)}
+	client, _ := newTestNodes(t, chainID, node)
+	return client, node
+}
+
+// newTestNodes starts one node per handler and returns a client for a chain served by them,
+// along with the nodes' URLs.
+func newTestNodes(t *testing.T, chainID int64, handlers ...http.Handler) (*ethclient.Client, []string) {
+	t.Helper()
+	urls := make([]string, len(handlers))
+	for i, handler := range handlers {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		urls[i] = server.URL
+	}
+
+	registry.RegisterChain(chainstypes.Chain{
+		ID:      big.NewInt(chainID),
+		Name:    t.Name(),
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {Http: urls}},
+	})
+	opts := rpc.DefaultClientOptions()
+	opts.Retry.MaxAttempts = 1
+	client, err := ethclient.NewClient(chainID, opts)
+	require.NoError(t, err)
+	return client, urls
+}
+
+// TestClient_Scalars tests methods returning quantities.
+func TestClient_Scalars(t *testing.T) {
+	client, node := newTestClient(t, 7795, map[string]string{
+		"eth_blockNumber":         `"0x1234"`,
+		"eth_chainId":             `"0x1e6b"`,
+		"eth_getBalance":          `"0xde0b6b3a7640000"`,
+		"eth_getTransactionCount": `"0x2a"`,
+		"eth_gasPrice":            `"0x3b9aca00"`,
+		"eth_estimateGas":         `"0x5208"`,
+	})
+	ctx := context.Background()
+	account := ethtypes.MustParseAddress(testAccount)
+
+	n, err := client.BlockNumber(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x1234), n)
+
+	id, err := client.ChainID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7787), id.Int64())
+
+	balance, err := client.GetBalance(ctx, account, ethtypes.Latest)
+	require.NoError(t, err)
+	assert.Equal(t, "1000000000000000000", balance.String())
+	assert.JSONEq(t, `["`+testAccount+`","latest"]`, string(node.params["eth_getBalance"]))
+
+	nonce, err := client.GetTransactionCount(ctx, account, ethtypes.BlockNumber(16))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), nonce)
+	assert.JSONEq(t, `["`+testAccount+`","0x10"]`, string(node.params["eth_getTransactionCount"]))
+
+	price, err := client.GasPrice(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_000_000_000), price.Int64())
+
+	gas, err := client.EstimateGas(ctx, ethclient.CallMsg{From: &account, To: &account, Value: big.NewInt(1)})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(21000), gas)
+	assert.JSONEq(t, `[{"from":"`+testAccount+`","to":"`+testAccount+`","value":"0x1"}]`, string(node.params["eth_estimateGas"]))
+}
+
+// TestClient_Call tests eth_call encoding and revert data.
+func TestClient_Call(t *testing.T) {
+	client, node := newTestClient(t, 7796, map[string]string{"eth_call": `"0x000000000000000000000000000000000000000000000000000000000000002a"`})
+	to := ethtypes.MustParseAddress(testAccount)
+
+	out, err := client.Call(context.Background(), ethclient.CallMsg{To: &to, Data: []byte{0x18, 0x16, 0x0d, 0xdd}}, ethtypes.Finalized)
+	require.NoError(t, err)
+	assert.Len(t, out, 32)
+	assert.Equal(t, byte(42), out[31])
+	assert.JSONEq(t, `[{"to":"`+testAccount+`","input":"0x18160ddd","data":"0x18160ddd"},"finalized"]`, string(node.params["eth_call"]))
+
+	_, err = client.SendRawTransaction(context.Background(), []byte{0x02})
+	var rpcErr *chainstypes.JsonRPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32601, rpcErr.Code)
+}
+
+// TestClient_Blocks tests block, transaction and receipt decoding.
+func TestClient_Blocks(t *testing.T) {
+	client, _ := newTestClient(t, 7797, map[string]string{
+		"eth_getBlockByNumber": `{
+			"number": "0x10", "hash": "` + testTxHash + `", "parentHash": "` + testTxHash + `",
+			"timestamp": "0x65000000", "miner": "` + testAccount + `", "gasLimit": "0x1c9c380",
+			"gasUsed": "0x5208", "baseFeePerGas": "0x7", "extraData": "0x",
+			"transactions": [{
+				"hash": "` + testTxHash + `", "type": "0x2", "from": "` + testAccount + `", "to": null,
+				"nonce": "0x1", "value": "0x0", "gas": "0x5208", "maxFeePerGas": "0x10",
+				"maxPriorityFeePerGas": "0x1", "input": "0x6000", "blockHash": "` + testTxHash + `",
+				"blockNumber": "0x10", "transactionIndex": "0x0"
+			}]
+		}`,
+		"eth_getTransactionReceipt": `null`,
+		"eth_sendRawTransaction":    `"` + testTxHash + `"`,
+	})
+	ctx := context.Background()
+
+	block, err := client.GetBlockByNumber(ctx, ethtypes.Latest, true)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(16), block.Number)
+	assert.Equal(t, uint64(0x65000000), block.Timestamp)
+	assert.Equal(t, int64(7), block.BaseFeePerGas.Int64())
+	require.Len(t, block.Transactions, 1)
+	tx := block.Transactions[0]
+	assert.Nil(t, tx.To)
+	assert.Equal(t, uint64(2), tx.Type)
+	assert.Nil(t, tx.GasPrice)
+	assert.Equal(t, int64(16), tx.BlockNumber.Int64())
+	assert.Equal(t, []ethtypes.Hash{tx.Hash}, block.TransactionHashes)
+
+	hash, err := client.SendRawTransaction(ctx, []byte{0x02, 0xf8})
+	require.NoError(t, err)
+	assert.Equal(t, testTxHash, hash.Hex())
+
+	_, err = client.GetTransactionReceipt(ctx, hash)
+	assert.True(t, errors.Is(err, ethclient.ErrNotFound))
+}
+
+// TestClient_LogsAndFees tests eth_getLogs filters, receipts with logs and fee history.
+func TestClient_LogsAndFees(t *testing.T) {
+	logJSON := `{
+		"address": "` + testAccount + `", "topics": ["` + testTxHash + `"], "data": "0x01",
+		"blockNumber": "0x10", "blockHash": "` + testTxHash + `", "transactionHash": "` + testTxHash + `",
+		"transactionIndex": "0x0", "logIndex": "0x3", "removed": false
+	}`
+	client, node := newTestClient(t, 7798, map[string]string{
+		"eth_getLogs": `[` + logJSON + `]`,
+		"eth_getTransactionReceipt": `{
+			"transactionHash": "` + testTxHash + `", "transactionIndex": "0x0", "blockHash": "` + testTxHash + `",
+			"blockNumber": "0x10", "from": "` + testAccount + `", "to": "` + testAccount + `",
+			"contractAddress": null, "status": "0x1", "gasUsed": "0x5208", "cumulativeGasUsed": "0x5208",
+			"effectiveGasPrice": "0x8", "type": "0x2", "logs": [` + logJSON + `]
+		}`,
+		"eth_feeHistory": `{"oldestBlock": "0xf", "baseFeePerGas": ["0x1", "0x2", "0x3"], "gasUsedRatio": [0.5, 0.25], "reward": [["0xa"], ["0xb"]]}`,
+	})
+	ctx := context.Background()
+	account := ethtypes.MustParseAddress(testAccount)
+	topic := ethtypes.MustParseHash(testTxHash)
+
+	logs, err := client.GetLogs(ctx, ethclient.FilterQuery{
+		FromBlock: ethtypes.BlockNumber(1),
+		ToBlock:   ethtypes.Latest,
+		Addresses: []ethtypes.Address{account},
+		Topics:    [][]ethtypes.Hash{{topic}, nil},
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, uint64(3), logs[0].LogIndex)
+	assert.Equal(t, []byte{1}, logs[0].Data)
+	assert.JSONEq(t, `[{"fromBlock":"0x1","toBlock":"latest","address":"`+testAccount+`","topics":["`+testTxHash+`",null]}]`, string(node.params["eth_getLogs"]))
+
+	receipt, err := client.GetTransactionReceipt(ctx, topic)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), receipt.Status)
+	assert.Nil(t, receipt.ContractAddress)
+	assert.Len(t, receipt.Logs, 1)
+
+	history, err := client.FeeHistory(ctx, 2, ethtypes.Latest, []float64{50})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(15), history.OldestBlock)
+	assert.Len(t, history.BaseFeePerGas, 3)
+	assert.Equal(t, int64(11), history.Reward[1][0].Int64())
+	assert.JSONEq(t, `["0x2","latest",[50]]`, string(node.params["eth_feeHistory"]))
+}
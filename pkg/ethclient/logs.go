@@ -0,0 +1,334 @@
+package ethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/rpc"
+)
+
+// defaultLogChunkSize is the block range first requested from an endpoint whose limit is unknown.
+const defaultLogChunkSize = 2000
+
+// logLimitMessages are the lowercased error messages nodes and providers answer an eth_getLogs
+// request with when its block range, result count or response size exceeds their limit.
+var logLimitMessages = []string{
+	"block range",
+	"blocks range",
+	"range too large",
+	"range is too large",
+	"range too wide",
+	"range is too wide",
+	"too many blocks",
+	"returned more than",
+	"too many results",
+	"too many logs",
+	"response size",
+	"response too large",
+	"query timeout",
+}
+
+// resultLimitMessages tell a limit on the results or the response of eth_getLogs apart from a
+// limit on its block range.
+var resultLimitMessages = []string{"result", "logs", "response", "timeout"}
+
+// rateLimitMessages are limit errors that are about the request rate or the account's quota,
+// not about eth_getLogs.
+var rateLimitMessages = []string{"rate limit", "too many requests", "per second", "quota", "compute units", "credits"}
+
+var (
+	// suggestedRange matches the block range some providers suggest instead, e.g. "[0x1, 0x7d0]".
+	suggestedRange = regexp.MustCompile(`\[\s*(0x[0-9a-f]+)\s*,\s*(0x[0-9a-f]+)\s*\]`)
+	// rangeCap matches a stated block range cap, e.g. "maximum block range: 5000" or
+	// "limited to a 10,000 range".
+	rangeCap = regexp.MustCompile(`(?:max(?:imum)?|limit(?:ed)?(?: to)?|up to|at most)\D{0,24}?(\d[\d,]*)(k?)\b`)
+)
+
+// LogChunk is the logs of a consecutive block range, in the order the node returned them.
+type LogChunk struct {
+	From uint64
+	To   uint64
+	Logs []Log
+}
+
+// LogCheckpoint persists the progress of a log fetch, so that a job that stopped can resume.
+type LogCheckpoint interface {
+	// Load returns the next block to fetch, or false if nothing was saved yet.
+	Load() (next uint64, ok bool, err error)
+	// Save records that every block before next was delivered.
+	Save(next uint64) error
+}
+
+// FileCheckpoint is a LogCheckpoint that keeps the next block in the file at its path.
+type FileCheckpoint string
+
+// Load reads the next block from the file; a missing file means nothing was saved.
+func (p FileCheckpoint) Load() (uint64, bool, error) {
+	data, err := os.ReadFile(string(p))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	next, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid checkpoint %s: %w", p, err)
+	}
+	return next, true, nil
+}
+
+// Save writes the next block to a temporary file and renames it over the file, so that a crash
+// never leaves a partial checkpoint.
+func (p FileCheckpoint) Save(next uint64) error {
+	tmp := string(p) + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(next, 10)+"\n"), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, string(p))
+}
+
+// LogFetcherOptions defines parameters for a LogFetcher.
+type LogFetcherOptions struct {
+	// ChunkSize is the block range requested from an endpoint whose limit is unknown. It is also
+	// the largest range requested after successes, unless the endpoint stated a larger cap.
+	ChunkSize uint64
+	// Checkpoint, if set, is loaded when a fetch starts and saved after every delivered chunk.
+	Checkpoint LogCheckpoint
+}
+
+// DefaultLogFetcherOptions returns options that start with 2000-block requests and keep no checkpoint.
+func DefaultLogFetcherOptions() LogFetcherOptions {
+	return LogFetcherOptions{ChunkSize: defaultLogChunkSize}
+}
+
+// LogFetcher fetches the logs of large block ranges in chunks that fit each endpoint's
+// eth_getLogs limits. It is safe for concurrent use, and the limits it learns are shared by all
+// its fetches.
+type LogFetcher struct {
+	client *Client
+	opts   LogFetcherOptions
+
+	mu     sync.Mutex
+	limits map[string]*logLimit
+}
+
+// logLimit is what a LogFetcher learned about one endpoint.
+type logLimit struct {
+	// max is the block range cap the endpoint stated (0 if none is known).
+	max uint64
+	// span is the block range requested next. It halves when the endpoint rejects a request for
+	// too many results and doubles after a full chunk succeeds.
+	span uint64
+}
+
+// NewLogFetcher creates a LogFetcher that sends its requests through client.
+func NewLogFetcher(client *Client, opts LogFetcherOptions) *LogFetcher {
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = defaultLogChunkSize
+	}
+	return &LogFetcher{client: client, opts: opts, limits: make(map[string]*logLimit)}
+}
+
+// ChunkSize returns the block range the fetcher currently requests from the endpoint.
+func (f *LogFetcher) ChunkSize(url string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.limit(url).span
+}
+
+// Logs returns an iterator over the logs matching query between blocks from and to, inclusive,
+// in consecutive chunks in block order. The block range and block hash of query are ignored.
+//
+// A request that exceeds an endpoint's limit is retried with the range the error states or
+// suggests, or else with half the range. Other failures move on to the next endpoint; once
+// every endpoint failed for a chunk, the iterator yields the error and stops. With a checkpoint,
+// the fetch starts after the last saved chunk, and a chunk is saved once the loop body that
+// received it returns, so every chunk is delivered at least once.
+func (f *LogFetcher) Logs(ctx context.Context, query FilterQuery, from, to uint64) iter.Seq2[LogChunk, error] {
+	query.BlockHash = nil
+	return func(yield func(LogChunk, error) bool) {
+		if f.opts.Checkpoint != nil {
+			next, ok, err := f.opts.Checkpoint.Load()
+			if err != nil {
+				yield(LogChunk{}, fmt.Errorf("failed to load log checkpoint: %w", err))
+				return
+			}
+			if ok {
+				if next > to {
+					return
+				}
+				from = max(from, next)
+			}
+		}
+
+		endpoint := 0
+		for from <= to {
+			chunk, err := f.fetch(ctx, query, from, to, &endpoint)
+			if err != nil {
+				yield(LogChunk{}, err)
+				return
+			}
+			if !yield(chunk, nil) {
+				return
+			}
+			if f.opts.Checkpoint != nil {
+				if err := f.opts.Checkpoint.Save(chunk.To + 1); err != nil {
+					yield(LogChunk{}, fmt.Errorf("failed to save log checkpoint: %w", err))
+					return
+				}
+			}
+			if chunk.To == to {
+				return
+			}
+			from = chunk.To + 1
+		}
+	}
+}
+
+// fetch returns the chunk starting at from that the endpoint at *endpoint, or the next one that
+// answers, serves.
+func (f *LogFetcher) fetch(ctx context.Context, query FilterQuery, from, to uint64, endpoint *int) (LogChunk, error) {
+	// Limit errors are answers the fetcher handles, not endpoint failures.
+	ctx = rpc.WithExpectedErrors(ctx, func(err error) bool {
+		_, ok := parseLogLimit(err)
+		return ok
+	})
+	urls := f.client.rpc.Endpoints()
+	var errs []error
+	for tried := 0; tried < len(urls); {
+		url := urls[*endpoint%len(urls)]
+		end := to
+		if span := f.ChunkSize(url); to-from >= span {
+			end = from + span - 1
+		}
+		query.FromBlock, query.ToBlock = ethtypes.BlockNumber(from), ethtypes.BlockNumber(end)
+
+		raw, err := f.client.rpc.CallEndpoint(ctx, url, "eth_getLogs", query)
+		if err == nil {
+			var logs []Log
+			if err := json.Unmarshal(raw, &logs); err != nil {
+				return LogChunk{}, fmt.Errorf("%s: failed to unmarshal logs of blocks %d-%d: %w", url, from, end, err)
+			}
+			f.succeeded(url, end-from+1)
+			return LogChunk{From: from, To: end, Logs: logs}, nil
+		}
+		if ctx.Err() != nil {
+			return LogChunk{}, err
+		}
+		if limit, ok := parseLogLimit(err); ok && end > from {
+			f.exceeded(url, end-from+1, limit)
+			continue
+		}
+		errs = append(errs, err)
+		*endpoint++
+		tried++
+	}
+	return LogChunk{}, fmt.Errorf("eth_getLogs of blocks %d-%d failed on every endpoint: %w", from, to, errors.Join(errs...))
+}
+
+// limit returns the endpoint's entry, creating it with the configured chunk size. f.mu must be held.
+func (f *LogFetcher) limit(url string) *logLimit {
+	l, ok := f.limits[url]
+	if !ok {
+		l = &logLimit{span: f.opts.ChunkSize}
+		f.limits[url] = l
+	}
+	return l
+}
+
+// succeeded grows the endpoint's span after it served a full chunk of the given size.
+func (f *LogFetcher) succeeded(url string, size uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l := f.limit(url)
+	if size < l.span {
+		return
+	}
+	ceiling := f.opts.ChunkSize
+	if l.max > 0 {
+		ceiling = l.max
+	}
+	l.span = min(l.span*2, max(ceiling, l.span))
+}
+
+// exceeded shrinks the endpoint's span after it rejected a request for size blocks.
+func (f *LogFetcher) exceeded(url string, size uint64, e logLimitError) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l := f.limit(url)
+	switch {
+	case e.maxBlocks > 0 && e.maxBlocks < size:
+		l.max, l.span = e.maxBlocks, e.maxBlocks
+	case e.suggested > 0 && e.suggested < size:
+		l.span = e.suggested
+	default:
+		l.span = max(size/2, 1)
+		if e.rangeCap {
+			// The range is too large whatever the results: never grow back past what works.
+			l.max = l.span
+		}
+	}
+}
+
+// logLimitError is what an endpoint's eth_getLogs limit error tells about its limits.
+type logLimitError struct {
+	// rangeCap reports that the limit is on the block range rather than on the results.
+	rangeCap bool
+	// maxBlocks is the block range cap the error states (0 if none).
+	maxBlocks uint64
+	// suggested is the size of the block range the error suggests instead (0 if none).
+	suggested uint64
+}
+
+// parseLogLimit reports whether err is an endpoint's answer to an eth_getLogs request that
+// exceeds one of its limits, and what it tells about the limit.
+func parseLogLimit(err error) (logLimitError, bool) {
+	var rpcErr *types.JsonRPCError
+	if !errors.As(err, &rpcErr) || !matchesAny(rpcErr, logLimitMessages) || matchesAny(rpcErr, rateLimitMessages) {
+		return logLimitError{}, false
+	}
+	msg := strings.ToLower(rpcErr.Message)
+	e := logLimitError{rangeCap: !matchesAny(rpcErr, resultLimitMessages)}
+
+	// Infura states the range that would have fit in the error data.
+	var data struct {
+		From ethtypes.Uint64 `json:"from"`
+		To   ethtypes.Uint64 `json:"to"`
+	}
+	if len(rpcErr.Data) > 0 && json.Unmarshal(rpcErr.Data, &data) == nil && data.To >= data.From && data.To > 0 {
+		e.suggested = uint64(data.To-data.From) + 1
+		return e, true
+	}
+	if m := suggestedRange.FindStringSubmatch(msg); m != nil {
+		from, err1 := ethtypes.DecodeUint64(m[1])
+		to, err2 := ethtypes.DecodeUint64(m[2])
+		if err1 == nil && err2 == nil && to >= from {
+			e.suggested = to - from + 1
+			return e, true
+		}
+	}
+	if e.rangeCap {
+		if m := rangeCap.FindStringSubmatch(msg); m != nil {
+			n, err := strconv.ParseUint(strings.ReplaceAll(m[1], ",", ""), 10, 64)
+			if err == nil && m[2] == "k" {
+				n *= 1000
+			}
+			if err == nil {
+				e.maxBlocks = n
+			}
+		}
+	}
+	return e, true
+}
@@ -0,0 +1,276 @@
+package ethclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logNode serves eth_getLogs with one log per block, unless limit rejects the range.
+type logNode struct {
+	limit func(from, to uint64) *chainstypes.JsonRPCError
+
+	mu     sync.Mutex
+	ranges [][2]uint64
+}
+
+// requested returns the block ranges the node was asked for, rejected ones included.
+func (n *logNode) requested() [][2]uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ranges
+}
+
+// ServeHTTP answers eth_getLogs for the requested range.
+func (n *logNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     chainstypes.JsonRPCID `json:"id"`
+		Params []struct {
+			FromBlock ethtypes.Uint64 `json:"fromBlock"`
+			ToBlock   ethtypes.Uint64 `json:"toBlock"`
+		} `json:"params"`
+	}
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if len(req.Params) != 1 {
+		http.Error(w, "expected one filter", http.StatusBadRequest)
+		return
+	}
+	from, to := uint64(req.Params[0].FromBlock), uint64(req.Params[0].ToBlock)
+	n.mu.Lock()
+	n.ranges = append(n.ranges, [2]uint64{from, to})
+	n.mu.Unlock()
+
+	resp := chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID}
+	if n.limit != nil {
+		resp.Error = n.limit(from, to)
+	}
+	if resp.Error == nil {
+		logs := make([]json.RawMessage, 0, to-from+1)
+		for b := from; b <= to; b++ {
+			logs = append(logs, json.RawMessage(fmt.Sprintf(`{"blockNumber":"%s","logIndex":"0x0"}`, ethtypes.EncodeUint64(b))))
+		}
+		resp.Result, _ = json.Marshal(logs)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// collectLogs drains the iterator and returns the block numbers of the logs and the chunks.
+func collectLogs(t *testing.T, logs iter.Seq2[ethclient.LogChunk, error]) ([]uint64, [][2]uint64) {
+	t.Helper()
+	var blocks []uint64
+	var chunks [][2]uint64
+	for chunk, err := range logs {
+		require.NoError(t, err)
+		chunks = append(chunks, [2]uint64{chunk.From, chunk.To})
+		for _, l := range chunk.Logs {
+			blocks = append(blocks, l.BlockNumber)
+		}
+	}
+	return blocks, chunks
+}
+
+// blockRange returns the numbers from..to.
+func blockRange(from, to uint64) []uint64 {
+	var blocks []uint64
+	for b := from; b <= to; b++ {
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// TestLogFetcher tests chunking, learning endpoint limits and resuming from a checkpoint.
+func TestLogFetcher(t *testing.T) {
+	ctx := context.Background()
+	query := ethclient.FilterQuery{Addresses: []ethtypes.Address{ethtypes.MustParseAddress(testAccount)}}
+
+	t.Run("stated range cap", func(t *testing.T) {
+		node := &logNode{limit: func(from, to uint64) *chainstypes.JsonRPCError {
+			if to-from+1 > 30 {
+				return &chainstypes.JsonRPCError{Code: -32600, Message: "exceed maximum block range: 30"}
+			}
+			return nil
+		}}
+		client, urls := newTestNodes(t, 7849, node)
+		fetcher := ethclient.NewLogFetcher(client, ethclient.LogFetcherOptions{ChunkSize: 100})
+
+		blocks, chunks := collectLogs(t, fetcher.Logs(ctx, query, 10, 99))
+		assert.Equal(t, blockRange(10, 99), blocks)
+		assert.Equal(t, [][2]uint64{{10, 39}, {40, 69}, {70, 99}}, chunks)
+		assert.Equal(t, uint64(30), fetcher.ChunkSize(urls[0]), "the cap is never exceeded again")
+		assert.Len(t, node.requested(), 4)
+	})
+
+	t.Run("result cap", func(t *testing.T) {
+		node := &logNode{limit: func(from, to uint64) *chainstypes.JsonRPCError {
+			if to-from+1 > 20 {
+				return &chainstypes.JsonRPCError{Code: -32005, Message: "query returned more than 20 results"}
+			}
+			return nil
+		}}
+		client, _ := newTestNodes(t, 7850, node)
+		fetcher := ethclient.NewLogFetcher(client, ethclient.LogFetcherOptions{ChunkSize: 64})
+
+		blocks, chunks := collectLogs(t, fetcher.Logs(ctx, query, 0, 63))
+		assert.Equal(t, blockRange(0, 63), blocks)
+		// Bisected to 16 blocks, then grown back to 32 after each success and halved again.
+		assert.Equal(t, [][2]uint64{{0, 15}, {16, 31}, {32, 47}, {48, 63}}, chunks)
+	})
+
+	t.Run("suggested range", func(t *testing.T) {
+		node := &logNode{limit: func(from, to uint64) *chainstypes.JsonRPCError {
+			if to-from+1 > 25 {
+				return &chainstypes.JsonRPCError{Code: -32602, Message: fmt.Sprintf(
+					"Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range and no limit on the response size, "+
+						"or you can request any block range with a cap of 10K logs in the response. Based on your parameters, this block range should work: [%s, %s]",
+					ethtypes.EncodeUint64(from), ethtypes.EncodeUint64(from+24))}
+			}
+			return nil
+		}}
+		client, _ := newTestNodes(t, 7851, node)
+		fetcher := ethclient.NewLogFetcher(client, ethclient.LogFetcherOptions{ChunkSize: 100})
+
+		_, chunks := collectLogs(t, fetcher.Logs(ctx, query, 0, 49))
+		assert.Equal(t, [2]uint64{0, 24}, chunks[0])
+		assert.Equal(t, [][2]uint64{{0, 49}, {0, 24}}, node.requested()[:2])
+	})
+
+	t.Run("fails over", func(t *testing.T) {
+		broken := &logNode{limit: func(from, to uint64) *chainstypes.JsonRPCError {
+			return &chainstypes.JsonRPCError{Code: -32601, Message: "the method eth_getLogs does not exist"}
+		}}
+		capped := &logNode{limit: func(from, to uint64) *chainstypes.JsonRPCError {
+			if to-from+1 > 10 {
+				return &chainstypes.JsonRPCError{Code: -32000, Message: "block range is too wide"}
+			}
+			return nil
+		}}
+		client, urls := newTestNodes(t, 7852, broken, capped)
+		fetcher := ethclient.NewLogFetcher(client, ethclient.LogFetcherOptions{ChunkSize: 40})
+
+		blocks, _ := collectLogs(t, fetcher.Logs(ctx, query, 0, 39))
+		assert.Equal(t, blockRange(0, 39), blocks)
+		assert.Len(t, broken.requested(), 1, "later chunks stay on the working endpoint")
+		assert.Equal(t, uint64(10), fetcher.ChunkSize(urls[1]))
+		assert.Equal(t, uint64(40), fetcher.ChunkSize(urls[0]))
+
+		// A block that is too large on its own cannot be split.
+		single := &logNode{limit: func(from, to uint64) *chainstypes.JsonRPCError {
+			return &chainstypes.JsonRPCError{Code: -32005, Message: "query returned more than 10000 results"}
+		}}
+		client, _ = newTestNodes(t, 7853, single)
+		fetcher = ethclient.NewLogFetcher(client, ethclient.LogFetcherOptions{ChunkSize: 4})
+		var errs []error
+		for _, err := range fetcher.Logs(ctx, query, 0, 3) {
+			errs = append(errs, err)
+		}
+		require.Len(t, errs, 1)
+		assert.ErrorContains(t, errs[0], "blocks 0-3 failed on every endpoint")
+		assert.Len(t, single.requested(), 3, "0-3, 0-1, 0-0")
+	})
+
+	t.Run("quota errors", func(t *testing.T) {
+		for i, message := range []string{
+			"Monthly capacity limit exceeded",
+			"compute units per second limit exceeded",
+			"daily request quota exceeded for logs",
+		} {
+			node := &logNode{limit: func(from, to uint64) *chainstypes.JsonRPCError {
+				return &chainstypes.JsonRPCError{Code: -32005, Message: message}
+			}}
+			client, urls := newTestNodes(t, 7866+int64(i), node)
+			fetcher := ethclient.NewLogFetcher(client, ethclient.LogFetcherOptions{ChunkSize: 64})
+			var errs []error
+			for _, err := range fetcher.Logs(ctx, query, 0, 63) {
+				errs = append(errs, err)
+			}
+			require.Len(t, errs, 1, message)
+			assert.ErrorContains(t, errs[0], message)
+			assert.Len(t, node.requested(), 1, "%s is not a range limit", message)
+			assert.Equal(t, uint64(64), fetcher.ChunkSize(urls[0]), message)
+		}
+	})
+
+	t.Run("limits are answers", func(t *testing.T) {
+		opts := rpc.DefaultClientOptions()
+		opts.Retry.MaxAttempts = 1
+		opts.RateLimiter = rpc.NewRateLimiter(rpc.DefaultRateLimiterOptions())
+		opts.Breakers = rpc.NewBreakers(rpc.BreakerOptions{FailureThreshold: 2})
+		// fetch reads the logs from node with a client sharing opts and returns the node's URL.
+		fetch := func(chainID int64, node *logNode) string {
+			t.Helper()
+			_, urls := newTestNodes(t, chainID, node)
+			client, err := ethclient.NewClient(chainID, opts)
+			require.NoError(t, err)
+			start := time.Now()
+			blocks, _ := collectLogs(t, ethclient.NewLogFetcher(client, ethclient.LogFetcherOptions{ChunkSize: 64}).Logs(ctx, query, 0, 63))
+			assert.Equal(t, blockRange(0, 63), blocks)
+			assert.Less(t, time.Since(start), time.Second)
+			return urls[0]
+		}
+
+		// A result cap phrased like a rate limit does not throttle the host.
+		capacity := &logNode{limit: func(from, to uint64) *chainstypes.JsonRPCError {
+			if to-from+1 > 10 {
+				return &chainstypes.JsonRPCError{Code: -32005, Message: "too many logs: query exceeded the result capacity"}
+			}
+			return nil
+		}}
+		url := fetch(7862, capacity)
+		assert.True(t, opts.RateLimiter.ThrottledUntil(url).IsZero())
+
+		// A query timeout with a retryable code does not trip the breaker.
+		timeout := &logNode{limit: func(from, to uint64) *chainstypes.JsonRPCError {
+			if to-from+1 > 10 {
+				return &chainstypes.JsonRPCError{Code: -32603, Message: "query timeout exceeded"}
+			}
+			return nil
+		}}
+		url = fetch(7863, timeout)
+		assert.Equal(t, rpc.BreakerClosed, opts.Breakers.State(url))
+	})
+
+	t.Run("checkpoint", func(t *testing.T) {
+		node := &logNode{}
+		client, _ := newTestNodes(t, 7854, node)
+		checkpoint := ethclient.FileCheckpoint(filepath.Join(t.TempDir(), "logs.checkpoint"))
+		opts := ethclient.LogFetcherOptions{ChunkSize: 10, Checkpoint: checkpoint}
+
+		// The job stops while handling the third chunk, before it is saved.
+		var first []uint64
+		for chunk, err := range ethclient.NewLogFetcher(client, opts).Logs(ctx, query, 0, 49) {
+			require.NoError(t, err)
+			if chunk.From == 20 {
+				break
+			}
+			first = append(first, chunk.From)
+		}
+		assert.Equal(t, []uint64{0, 10}, first)
+		next, ok, err := checkpoint.Load()
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, uint64(20), next)
+
+		blocks, _ := collectLogs(t, ethclient.NewLogFetcher(client, opts).Logs(ctx, query, 0, 49))
+		assert.Equal(t, blockRange(20, 49), blocks)
+
+		// A finished job has nothing left.
+		blocks, _ = collectLogs(t, ethclient.NewLogFetcher(client, opts).Logs(ctx, query, 0, 49))
+		assert.Empty(t, blocks)
+	})
+}
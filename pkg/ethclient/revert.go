@@ -0,0 +1,234 @@
+package ethclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/abi"
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+var (
+	reasonError = abi.MustParseError("Error(string)")
+	panicError  = abi.MustParseError("Panic(uint256)")
+)
+
+// panicReasons describes the Panic(uint256) codes emitted by the Solidity compiler.
+var panicReasons = map[uint64]string{
+	0x00: "generic compiler panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "conversion into non-existent enum value",
+	0x22: "access to incorrectly encoded storage byte array",
+	0x31: "pop() on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to zero-initialized internal function",
+}
+
+// PanicReason describes a Panic(uint256) code, or returns "" for unknown codes.
+func PanicReason(code *big.Int) string {
+	if code == nil || !code.IsUint64() {
+		return ""
+	}
+	return panicReasons[code.Uint64()]
+}
+
+// RevertError is returned by Call and EstimateGas when execution reverted.
+type RevertError struct {
+	// Data is the raw revert data, or nil if the node did not return it.
+	Data []byte
+	// Reason is the message of an Error(string) revert, or the reason a node only reported in
+	// its error message.
+	Reason string
+	// PanicCode is the code of a Panic(uint256) revert.
+	PanicCode *big.Int
+	// Custom is the custom error matched by DecodeCustom, with its decoded arguments in Args.
+	Custom *abi.Error
+	Args   []any
+
+	err error
+}
+
+// Error describes the revert like Solidity tooling does.
+func (e *RevertError) Error() string {
+	switch {
+	case e.Custom != nil:
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = fmt.Sprint(formatArg(arg))
+		}
+		return fmt.Sprintf("execution reverted: %s(%s)", e.Custom.Name, strings.Join(args, ", "))
+	case e.PanicCode != nil:
+		if reason := PanicReason(e.PanicCode); reason != "" {
+			return fmt.Sprintf("execution reverted: panic 0x%x (%s)", e.PanicCode, reason)
+		}
+		return fmt.Sprintf("execution reverted: panic 0x%x", e.PanicCode)
+	case e.Reason != "":
+		return "execution reverted: " + e.Reason
+	case len(e.Data) > 0:
+		return "execution reverted: " + ethtypes.EncodeBytes(e.Data)
+	}
+	return "execution reverted"
+}
+
+// Unwrap returns the JSON-RPC error the revert was decoded from.
+func (e *RevertError) Unwrap() error {
+	return e.err
+}
+
+// DecodeCustom matches the revert data against custom error ABIs and, on success, sets Custom
+// and Args.
+func (e *RevertError) DecodeCustom(errs ...abi.Error) bool {
+	for i := range errs {
+		if args, err := errs[i].Decode(e.Data); err == nil {
+			e.Custom, e.Args = &errs[i], args
+			return true
+		}
+	}
+	return false
+}
+
+// formatArg prints byte values as hex rather than as lists of numbers.
+func formatArg(arg any) any {
+	if b, ok := arg.([]byte); ok {
+		return ethtypes.EncodeBytes(b)
+	}
+	return arg
+}
+
+// AsRevert returns the revert in err's chain, decoding a JSON-RPC execution revert if needed.
+// Errors that are not reverts, such as transport failures, return false.
+//
+// Nodes report reverts differently; all of these are recognized:
+//   - geth, Erigon and Arbitrum Nitro answer code 3 "execution reverted: <reason>" (older
+//     releases and some providers -32000) with the revert data as a hex string in data;
+//   - Nethermind answers code -32015 "VM execution error." or "Reverted ..." with data such as
+//     "Reverted 0x08c379a0...", the same form OpenEthereum used;
+//   - zkSync Era may leave data empty and put the revert data into the message as
+//     "Error function_selector = 0x..., data = 0x...";
+//   - development nodes and some gateways nest data as {"data": "0x..."}, or key it by
+//     transaction hash with the revert data under "return".
+func AsRevert(err error) (*RevertError, bool) {
+	var revert *RevertError
+	if errors.As(err, &revert) {
+		return revert, true
+	}
+	var rpcErr *types.JsonRPCError
+	if !errors.As(err, &rpcErr) {
+		return nil, false
+	}
+
+	data, hasData := findRevertData(rpcErr.Data)
+	message := strings.ToLower(rpcErr.Message)
+	isRevert := rpcErr.Code == 3 || rpcErr.Code == -32015 ||
+		strings.Contains(message, "revert") || strings.Contains(message, "vm execution error")
+	if !isRevert {
+		return nil, false
+	}
+	if !hasData {
+		data, hasData = messageRevertData(rpcErr.Message)
+	}
+
+	revert = &RevertError{}
+	if hasData {
+		revert = DecodeRevert(data)
+	}
+	if len(revert.Data) == 0 {
+		revert.Reason = messageReason(rpcErr.Message)
+	}
+	revert.err = err
+	return revert, true
+}
+
+// DecodeRevert decodes revert data, such as the return data of a failed Multicall3 call, and
+// recognizes Error(string) and Panic(uint256).
+func DecodeRevert(data []byte) *RevertError {
+	revert := &RevertError{Data: data}
+	if args, err := reasonError.Decode(data); err == nil {
+		revert.Reason = args[0].(string)
+	} else if args, err := panicError.Decode(data); err == nil {
+		revert.PanicCode = args[0].(*big.Int)
+	}
+	return revert
+}
+
+// findRevertData locates revert data in the data member of a JSON-RPC error.
+func findRevertData(raw json.RawMessage) ([]byte, bool) {
+	if len(raw) == 0 {
+		return nil, false
+	}
+
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		s = strings.TrimSpace(s)
+		// Nethermind and OpenEthereum prefix the data with "Reverted".
+		s = strings.TrimSpace(strings.TrimPrefix(s, "Reverted"))
+		if s == "" || s == "0x" {
+			return []byte{}, true
+		}
+		if !strings.HasPrefix(s, "0x") {
+			s = "0x" + s
+		}
+		data, err := ethtypes.DecodeBytes(s)
+		return data, err == nil
+	}
+
+	var object map[string]json.RawMessage
+	if json.Unmarshal(raw, &object) != nil {
+		return nil, false
+	}
+	for _, key := range []string{"data", "return", "result"} {
+		if nested, ok := object[key]; ok {
+			if data, ok := findRevertData(nested); ok {
+				return data, true
+			}
+		}
+	}
+	// Ganache keys the details by transaction hash.
+	for key, nested := range object {
+		if strings.HasPrefix(key, "0x") {
+			if data, ok := findRevertData(nested); ok {
+				return data, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// zkSyncRevert matches the revert data zkSync Era puts into error messages.
+var zkSyncRevert = regexp.MustCompile(`function_selector = (0x[0-9a-fA-F]*), data = (0x[0-9a-fA-F]*)`)
+
+// messageRevertData extracts revert data from an error message.
+func messageRevertData(message string) ([]byte, bool) {
+	m := zkSyncRevert.FindStringSubmatch(message)
+	if m == nil {
+		return nil, false
+	}
+	selector, err1 := ethtypes.DecodeBytes(m[1])
+	data, err2 := ethtypes.DecodeBytes(m[2])
+	if err1 != nil || err2 != nil {
+		return nil, false
+	}
+	// Some versions repeat the selector at the start of data.
+	if len(data) >= len(selector) && string(data[:len(selector)]) == string(selector) {
+		return data, true
+	}
+	return append(selector, data...), true
+}
+
+// messageReason returns the reason of an "execution reverted: <reason>" message.
+func messageReason(message string) string {
+	for _, prefix := range []string{"execution reverted:", "Reverted", "reverted:"} {
+		if i := strings.Index(message, prefix); i >= 0 {
+			return strings.TrimSpace(message[i+len(prefix):])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package ethclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/abi"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAsRevert tests revert detection and decoding for the error shapes of different nodes.
+func TestAsRevert(t *testing.T) {
+	reason, err := abi.MustParseError("Error(string)").Encode("Insufficient balance")
+	require.NoError(t, err)
+	reasonHex := ethtypes.EncodeBytes(reason)
+	overflow, err := abi.MustParseError("Panic(uint256)").Encode(0x11)
+	require.NoError(t, err)
+	custom := abi.MustParseError("error InsufficientBalance(uint256 available, uint256 required)")
+	customData, err := custom.Encode(10, 20)
+	require.NoError(t, err)
+
+	quoted := func(s string) json.RawMessage {
+		b, _ := json.Marshal(s)
+		return b
+	}
+
+	tests := []struct {
+		name   string
+		err    *chainstypes.JsonRPCError
+		reason string
+		panic  int64
+		data   []byte
+	}{
+		{
+			name:   "geth",
+			err:    &chainstypes.JsonRPCError{Code: 3, Message: "execution reverted: Insufficient balance", Data: quoted(reasonHex)},
+			reason: "Insufficient balance", data: reason,
+		},
+		{
+			name:  "erigon panic",
+			err:   &chainstypes.JsonRPCError{Code: 3, Message: "execution reverted", Data: quoted(ethtypes.EncodeBytes(overflow))},
+			panic: 0x11, data: overflow,
+		},
+		{
+			name:   "arbitrum",
+			err:    &chainstypes.JsonRPCError{Code: -32000, Message: "execution reverted", Data: quoted(reasonHex)},
+			reason: "Insufficient balance", data: reason,
+		},
+		{
+			name:   "nethermind",
+			err:    &chainstypes.JsonRPCError{Code: -32015, Message: "VM execution error.", Data: quoted("Reverted " + reasonHex)},
+			reason: "Insufficient balance", data: reason,
+		},
+		{
+			name:   "nethermind without prefix",
+			err:    &chainstypes.JsonRPCError{Code: -32015, Message: "Reverted", Data: quoted(reasonHex[2:])},
+			reason: "Insufficient balance", data: reason,
+		},
+		{
+			name: "zksync",
+			err: &chainstypes.JsonRPCError{Code: 3, Message: fmt.Sprintf(
+				"execution reverted: Error function_selector = 0x%x, data = 0x%x", reason[:4], reason[4:])},
+			reason: "Insufficient balance", data: reason,
+		},
+		{
+			name:   "nested object",
+			err:    &chainstypes.JsonRPCError{Code: -32603, Message: "Error: VM Exception while processing transaction: reverted", Data: json.RawMessage(`{"message":"revert","data":"` + reasonHex + `"}`)},
+			reason: "Insufficient balance", data: reason,
+		},
+		{
+			name:   "keyed by transaction",
+			err:    &chainstypes.JsonRPCError{Code: -32000, Message: "VM Exception while processing transaction: revert", Data: json.RawMessage(`{"0x1234":{"error":"revert","return":"` + reasonHex + `"}}`)},
+			reason: "Insufficient balance", data: reason,
+		},
+		{
+			name:   "reason only in message",
+			err:    &chainstypes.JsonRPCError{Code: 3, Message: "execution reverted: Ownable: caller is not the owner"},
+			reason: "Ownable: caller is not the owner",
+		},
+		{
+			name: "custom error",
+			err:  &chainstypes.JsonRPCError{Code: 3, Message: "execution reverted", Data: quoted(ethtypes.EncodeBytes(customData))},
+			data: customData,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapped := fmt.Errorf("http://node: %w", tt.err)
+			revert, ok := ethclient.AsRevert(wrapped)
+			require.True(t, ok)
+			assert.Equal(t, tt.reason, revert.Reason)
+			assert.Equal(t, tt.data, revert.Data)
+			if tt.panic != 0 {
+				require.NotNil(t, revert.PanicCode)
+				assert.Equal(t, tt.panic, revert.PanicCode.Int64())
+			} else {
+				assert.Nil(t, revert.PanicCode)
+			}
+			assert.ErrorIs(t, revert, tt.err)
+		})
+	}
+
+	revert, _ := ethclient.AsRevert(tests[1].err)
+	assert.Equal(t, "execution reverted: panic 0x11 (arithmetic underflow or overflow)", revert.Error())
+
+	revert, _ = ethclient.AsRevert(tests[len(tests)-1].err)
+	assert.Equal(t, "execution reverted: "+ethtypes.EncodeBytes(customData), revert.Error())
+	require.True(t, revert.DecodeCustom(abi.MustParseError("Unauthorized()"), custom))
+	assert.Equal(t, "InsufficientBalance", revert.Custom.Name)
+	assert.Equal(t, []any{big.NewInt(10), big.NewInt(20)}, revert.Args)
+	assert.Equal(t, "execution reverted: InsufficientBalance(10, 20)", revert.Error())
+
+	for _, notRevert := range []error{
+		errors.New("connection refused"),
+		&chainstypes.JsonRPCError{Code: -32000, Message: "header not found"},
+		&chainstypes.JsonRPCError{Code: -32601, Message: "method not found"},
+	} {
+		_, ok := ethclient.AsRevert(notRevert)
+		assert.False(t, ok, notRevert.Error())
+	}
+}
+
+// TestClient_CallRevert tests that Call and EstimateGas return *RevertError.
+func TestClient_CallRevert(t *testing.T) {
+	reason, err := abi.MustParseError("Error(string)").Encode("nope")
+	require.NoError(t, err)
+	client, _ := newTestNodes(t, 7818, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chainstypes.JsonRPCRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		data, _ := json.Marshal(ethtypes.Bytes(reason))
+		_ = json.NewEncoder(w).Encode(chainstypes.JsonRPCResponse{
+			Version: "2.0",
+			ID:      req.ID,
+			Error:   &chainstypes.JsonRPCError{Code: 3, Message: "execution reverted: nope", Data: data},
+		})
+	}))
+
+	to := ethtypes.MustParseAddress(testAccount)
+	_, err = client.Call(context.Background(), ethclient.CallMsg{To: &to}, ethtypes.Latest)
+	var revert *ethclient.RevertError
+	require.ErrorAs(t, err, &revert)
+	assert.Equal(t, "nope", revert.Reason)
+	var rpcErr *chainstypes.JsonRPCError
+	assert.ErrorAs(t, err, &rpcErr)
+
+	_, err = client.EstimateGas(context.Background(), ethclient.CallMsg{To: &to})
+	require.ErrorAs(t, err, &revert)
+	assert.Equal(t, "execution reverted: nope", err.Error())
+}
@@ -0,0 +1,367 @@
+package ethclient
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+// CallMsg holds the parameters of eth_call and eth_estimateGas. Nil and zero fields are omitted.
+type CallMsg struct {
+	From                 *ethtypes.Address
+	To                   *ethtypes.Address
+	Gas                  uint64
+	GasPrice             *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	Value                *big.Int
+	Data                 []byte
+}
+
+// MarshalJSON encodes the message as a JSON-RPC transaction call object.
+func (m CallMsg) MarshalJSON() ([]byte, error) {
+	obj := map[string]any{}
+	if m.From != nil {
+		obj["from"] = m.From
+	}
+	if m.To != nil {
+		obj["to"] = m.To
+	}
+	if m.Gas != 0 {
+		obj["gas"] = ethtypes.EncodeUint64(m.Gas)
+	}
+	for key, v := range map[string]*big.Int{
+		"gasPrice":             m.GasPrice,
+		"maxFeePerGas":         m.MaxFeePerGas,
+		"maxPriorityFeePerGas": m.MaxPriorityFeePerGas,
+		"value":                m.Value,
+	} {
+		if v != nil {
+			obj[key] = ethtypes.EncodeBig(v)
+		}
+	}
+	if len(m.Data) > 0 {
+		// "input" is the current name; "data" is still required by some older nodes.
+		obj["input"] = ethtypes.Bytes(m.Data)
+		obj["data"] = ethtypes.Bytes(m.Data)
+	}
+	return json.Marshal(obj)
+}
+
+// Block is a block as returned by eth_getBlockByNumber. Transactions is only filled when full
+// transactions were requested; TransactionHashes always is.
+type Block struct {
+	Number            uint64
+	Hash              ethtypes.Hash
+	ParentHash        ethtypes.Hash
+	Timestamp         uint64
+	Miner             ethtypes.Address
+	GasLimit          uint64
+	GasUsed           uint64
+	BaseFeePerGas     *big.Int
+	ExtraData         []byte
+	Transactions      []Transaction
+	TransactionHashes []ethtypes.Hash
+}
+
+type rpcBlock struct {
+	Number        ethtypes.Uint64   `json:"number"`
+	Hash          ethtypes.Hash     `json:"hash"`
+	ParentHash    ethtypes.Hash     `json:"parentHash"`
+	Timestamp     ethtypes.Uint64   `json:"timestamp"`
+	Miner         ethtypes.Address  `json:"miner"`
+	GasLimit      ethtypes.Uint64   `json:"gasLimit"`
+	GasUsed       ethtypes.Uint64   `json:"gasUsed"`
+	BaseFeePerGas *ethtypes.Big     `json:"baseFeePerGas"`
+	ExtraData     ethtypes.Bytes    `json:"extraData"`
+	Transactions  []json.RawMessage `json:"transactions"`
+}
+
+// UnmarshalJSON decodes a JSON-RPC block with either transaction hashes or full transactions.
+func (b *Block) UnmarshalJSON(data []byte) error {
+	var raw rpcBlock
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*b = Block{
+		Number:        uint64(raw.Number),
+		Hash:          raw.Hash,
+		ParentHash:    raw.ParentHash,
+		Timestamp:     uint64(raw.Timestamp),
+		Miner:         raw.Miner,
+		GasLimit:      uint64(raw.GasLimit),
+		GasUsed:       uint64(raw.GasUsed),
+		BaseFeePerGas: raw.BaseFeePerGas.ToInt(),
+		ExtraData:     raw.ExtraData,
+	}
+	for _, txData := range raw.Transactions {
+		if len(txData) > 0 && txData[0] == '"' {
+			var hash ethtypes.Hash
+			if err := json.Unmarshal(txData, &hash); err != nil {
+				return err
+			}
+			b.TransactionHashes = append(b.TransactionHashes, hash)
+			continue
+		}
+		var tx Transaction
+		if err := json.Unmarshal(txData, &tx); err != nil {
+			return err
+		}
+		b.Transactions = append(b.Transactions, tx)
+		b.TransactionHashes = append(b.TransactionHashes, tx.Hash)
+	}
+	return nil
+}
+
+// Transaction is a transaction as included in a block. BlockNumber is nil for pending transactions.
+type Transaction struct {
+	Hash                 ethtypes.Hash
+	Type                 uint64
+	From                 ethtypes.Address
+	To                   *ethtypes.Address
+	Nonce                uint64
+	Value                *big.Int
+	Gas                  uint64
+	GasPrice             *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	Input                []byte
+	BlockHash            *ethtypes.Hash
+	BlockNumber          *big.Int
+	TransactionIndex     uint64
+}
+
+type rpcTransaction struct {
+	Hash                 ethtypes.Hash     `json:"hash"`
+	Type                 ethtypes.Uint64   `json:"type"`
+	From                 ethtypes.Address  `json:"from"`
+	To                   *ethtypes.Address `json:"to"`
+	Nonce                ethtypes.Uint64   `json:"nonce"`
+	Value                *ethtypes.Big     `json:"value"`
+	Gas                  ethtypes.Uint64   `json:"gas"`
+	GasPrice             *ethtypes.Big     `json:"gasPrice"`
+	MaxFeePerGas         *ethtypes.Big     `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *ethtypes.Big     `json:"maxPriorityFeePerGas"`
+	Input                ethtypes.Bytes    `json:"input"`
+	BlockHash            *ethtypes.Hash    `json:"blockHash"`
+	BlockNumber          *ethtypes.Big     `json:"blockNumber"`
+	TransactionIndex     ethtypes.Uint64   `json:"transactionIndex"`
+}
+
+// UnmarshalJSON decodes a JSON-RPC transaction object.
+func (tx *Transaction) UnmarshalJSON(data []byte) error {
+	var raw rpcTransaction
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*tx = Transaction{
+		Hash:                 raw.Hash,
+		Type:                 uint64(raw.Type),
+		From:                 raw.From,
+		To:                   raw.To,
+		Nonce:                uint64(raw.Nonce),
+		Value:                raw.Value.ToInt(),
+		Gas:                  uint64(raw.Gas),
+		GasPrice:             raw.GasPrice.ToInt(),
+		MaxFeePerGas:         raw.MaxFeePerGas.ToInt(),
+		MaxPriorityFeePerGas: raw.MaxPriorityFeePerGas.ToInt(),
+		Input:                raw.Input,
+		BlockHash:            raw.BlockHash,
+		BlockNumber:          raw.BlockNumber.ToInt(),
+		TransactionIndex:     uint64(raw.TransactionIndex),
+	}
+	return nil
+}
+
+// Receipt is a transaction receipt. Status is 1 for success and 0 for failure.
+type Receipt struct {
+	TransactionHash   ethtypes.Hash
+	TransactionIndex  uint64
+	BlockHash         ethtypes.Hash
+	BlockNumber       uint64
+	From              ethtypes.Address
+	To                *ethtypes.Address
+	ContractAddress   *ethtypes.Address
+	Status            uint64
+	GasUsed           uint64
+	CumulativeGasUsed uint64
+	EffectiveGasPrice *big.Int
+	Type              uint64
+	Logs              []Log
+}
+
+type rpcReceipt struct {
+	TransactionHash   ethtypes.Hash     `json:"transactionHash"`
+	TransactionIndex  ethtypes.Uint64   `json:"transactionIndex"`
+	BlockHash         ethtypes.Hash     `json:"blockHash"`
+	BlockNumber       ethtypes.Uint64   `json:"blockNumber"`
+	From              ethtypes.Address  `json:"from"`
+	To                *ethtypes.Address `json:"to"`
+	ContractAddress   *ethtypes.Address `json:"contractAddress"`
+	Status            ethtypes.Uint64   `json:"status"`
+	GasUsed           ethtypes.Uint64   `json:"gasUsed"`
+	CumulativeGasUsed ethtypes.Uint64   `json:"cumulativeGasUsed"`
+	EffectiveGasPrice *ethtypes.Big     `json:"effectiveGasPrice"`
+	Type              ethtypes.Uint64   `json:"type"`
+	Logs              []Log             `json:"logs"`
+}
+
+// UnmarshalJSON decodes a JSON-RPC receipt object.
+func (r *Receipt) UnmarshalJSON(data []byte) error {
+	var raw rpcReceipt
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*r = Receipt{
+		TransactionHash:   raw.TransactionHash,
+		TransactionIndex:  uint64(raw.TransactionIndex),
+		BlockHash:         raw.BlockHash,
+		BlockNumber:       uint64(raw.BlockNumber),
+		From:              raw.From,
+		To:                raw.To,
+		ContractAddress:   raw.ContractAddress,
+		Status:            uint64(raw.Status),
+		GasUsed:           uint64(raw.GasUsed),
+		CumulativeGasUsed: uint64(raw.CumulativeGasUsed),
+		EffectiveGasPrice: raw.EffectiveGasPrice.ToInt(),
+		Type:              uint64(raw.Type),
+		Logs:              raw.Logs,
+	}
+	return nil
+}
+
+// Log is an event log.
+type Log struct {
+	Address          ethtypes.Address
+	Topics           []ethtypes.Hash
+	Data             []byte
+	BlockNumber      uint64
+	BlockHash        ethtypes.Hash
+	TransactionHash  ethtypes.Hash
+	TransactionIndex uint64
+	LogIndex         uint64
+	Removed          bool
+}
+
+type rpcLog struct {
+	Address          ethtypes.Address `json:"address"`
+	Topics           []ethtypes.Hash  `json:"topics"`
+	Data             ethtypes.Bytes   `json:"data"`
+	BlockNumber      ethtypes.Uint64  `json:"blockNumber"`
+	BlockHash        ethtypes.Hash    `json:"blockHash"`
+	TransactionHash  ethtypes.Hash    `json:"transactionHash"`
+	TransactionIndex ethtypes.Uint64  `json:"transactionIndex"`
+	LogIndex         ethtypes.Uint64  `json:"logIndex"`
+	Removed          bool             `json:"removed"`
+}
+
+// UnmarshalJSON decodes a JSON-RPC log object.
+func (l *Log) UnmarshalJSON(data []byte) error {
+	var raw rpcLog
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*l = Log{
+		Address:          raw.Address,
+		Topics:           raw.Topics,
+		Data:             raw.Data,
+		BlockNumber:      uint64(raw.BlockNumber),
+		BlockHash:        raw.BlockHash,
+		TransactionHash:  raw.TransactionHash,
+		TransactionIndex: uint64(raw.TransactionIndex),
+		LogIndex:         uint64(raw.LogIndex),
+		Removed:          raw.Removed,
+	}
+	return nil
+}
+
+// FilterQuery selects logs for eth_getLogs. Either BlockHash or the FromBlock/ToBlock range is
+// used. Topics match by position; a nil or empty position matches anything, and several hashes
+// in one position match any of them.
+type FilterQuery struct {
+	BlockHash *ethtypes.Hash
+	FromBlock ethtypes.BlockTag
+	ToBlock   ethtypes.BlockTag
+	Addresses []ethtypes.Address
+	Topics    [][]ethtypes.Hash
+}
+
+// MarshalJSON encodes the query as a JSON-RPC filter object.
+func (q FilterQuery) MarshalJSON() ([]byte, error) {
+	obj := map[string]any{}
+	if q.BlockHash != nil {
+		obj["blockHash"] = q.BlockHash
+	} else {
+		if q.FromBlock != "" {
+			obj["fromBlock"] = q.FromBlock
+		}
+		if q.ToBlock != "" {
+			obj["toBlock"] = q.ToBlock
+		}
+	}
+	switch len(q.Addresses) {
+	case 0:
+	case 1:
+		obj["address"] = q.Addresses[0]
+	default:
+		obj["address"] = q.Addresses
+	}
+	if len(q.Topics) > 0 {
+		topics := make([]any, len(q.Topics))
+		for i, position := range q.Topics {
+			switch len(position) {
+			case 0:
+				topics[i] = nil
+			case 1:
+				topics[i] = position[0]
+			default:
+				topics[i] = position
+			}
+		}
+		obj["topics"] = topics
+	}
+	return json.Marshal(obj)
+}
+
+// FeeHistory is the result of eth_feeHistory. Reward has one entry per block with one value per
+// requested percentile; BaseFeePerGas has one more entry than blocks, for the next block.
+type FeeHistory struct {
+	OldestBlock   uint64
+	BaseFeePerGas []*big.Int
+	GasUsedRatio  []float64
+	Reward        [][]*big.Int
+}
+
+type rpcFeeHistory struct {
+	OldestBlock   ethtypes.Uint64   `json:"oldestBlock"`
+	BaseFeePerGas []*ethtypes.Big   `json:"baseFeePerGas"`
+	GasUsedRatio  []float64         `json:"gasUsedRatio"`
+	Reward        [][]*ethtypes.Big `json:"reward"`
+}
+
+// UnmarshalJSON decodes a JSON-RPC fee history object.
+func (h *FeeHistory) UnmarshalJSON(data []byte) error {
+	var raw rpcFeeHistory
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*h = FeeHistory{
+		OldestBlock:   uint64(raw.OldestBlock),
+		BaseFeePerGas: toInts(raw.BaseFeePerGas),
+		GasUsedRatio:  raw.GasUsedRatio,
+	}
+	for _, rewards := range raw.Reward {
+		h.Reward = append(h.Reward, toInts(rewards))
+	}
+	return nil
+}
+
+// toInts converts decoded quantities to *big.Int values.
+func toInts(values []*ethtypes.Big) []*big.Int {
+	ints := make([]*big.Int, len(values))
+	for i, v := range values {
+		ints[i] = v.ToInt()
+	}
+	return ints
+}
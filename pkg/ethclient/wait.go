@@ -0,0 +1,188 @@
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/rpc"
+)
+
+// ErrReorged is wrapped into the error of WaitForTransaction when a reorg moves the transaction
+// out of the block it was seen in.
+var ErrReorged = errors.New("transaction reorged out of its block")
+
+// unsupportedTagMessages are the lowercased errors of nodes that do not support a block tag.
+var unsupportedTagMessages = []string{"invalid block tag", "unknown block"}
+
+// defaultPollInterval paces WaitForTransaction on chains without a known block time.
+const defaultPollInterval = 2 * time.Second
+
+// WaitOptions defines how WaitForTransaction waits for a transaction.
+type WaitOptions struct {
+	// Confirmations is the number of blocks, counting the transaction's own block, that must be
+	// on top of the chain (1 returns as soon as the transaction is mined).
+	Confirmations uint64
+	// Finality, if set to ethtypes.Safe or ethtypes.Finalized, also waits until the transaction's
+	// block is at or below the block of that tag. Chains whose nodes do not support the tag
+	// rely on Confirmations alone.
+	Finality ethtypes.BlockTag
+	// PollInterval is the delay between two checks. If 0, it is half the chain's block time,
+	// at least 100ms, or 2s if the block time is unknown.
+	PollInterval time.Duration
+	// Heads, if set, triggers a check on every new head, e.g. from a newHeads subscription.
+	// Polling continues at PollInterval in case the subscription stalls.
+	Heads <-chan Block
+	// OnReorg, if set, is called with the stale receipt when a reorg moves the transaction out
+	// of its block, and waiting continues. If nil, WaitForTransaction returns an error wrapping
+	// ErrReorged instead.
+	OnReorg func(dropped *Receipt)
+}
+
+// DefaultWaitOptions returns options that wait for one confirmation, polling at the chain's pace.
+func DefaultWaitOptions() WaitOptions {
+	return WaitOptions{Confirmations: 1}
+}
+
+// pollInterval returns opts.PollInterval or the interval derived from the chain's block time.
+func (o WaitOptions) pollInterval(chain types.Chain) time.Duration {
+	switch {
+	case o.PollInterval > 0:
+		return o.PollInterval
+	case chain.BlockTime > 0:
+		return max(chain.BlockTime/2, 100*time.Millisecond)
+	default:
+		return defaultPollInterval
+	}
+}
+
+// WaitForTransaction waits for a transaction on a registered chain identified by ID or name,
+// through a client with default options. See Client.WaitForTransaction.
+func WaitForTransaction(ctx context.Context, chain any, hash ethtypes.Hash, opts WaitOptions) (*Receipt, error) {
+	client, err := NewClient(chain, rpc.DefaultClientOptions())
+	if err != nil {
+		return nil, err
+	}
+	return client.WaitForTransaction(ctx, hash, opts)
+}
+
+// WaitForTransaction waits until the transaction is mined with opts.Confirmations confirmations
+// and, if opts.Finality is set, its block is safe or finalized, and returns its receipt. The
+// receipt is re-read on every check, so a reorg that drops the transaction or moves it to
+// another block is detected. A missing or moved receipt only counts as a reorg once the block it
+// was seen in is no longer canonical, as it may come from an endpoint that is behind.
+func (c *Client) WaitForTransaction(ctx context.Context, hash ethtypes.Hash, opts WaitOptions) (*Receipt, error) {
+	w := &waiter{client: c, hash: hash, opts: opts, finality: opts.Finality}
+	interval := opts.pollInterval(c.Chain())
+	heads := opts.Heads
+
+	var head *Block
+	for {
+		receipt, err := w.check(ctx, head)
+		if receipt != nil || err != nil {
+			return receipt, err
+		}
+
+		head = nil
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for %s: %w", hash, ctx.Err())
+		case b, ok := <-heads:
+			if !ok {
+				heads = nil
+				continue
+			}
+			head = &b
+		case <-time.After(interval):
+		}
+	}
+}
+
+// waiter is the state of one WaitForTransaction call.
+type waiter struct {
+	client *Client
+	hash   ethtypes.Hash
+	opts   WaitOptions
+	// finality is cleared when the chain's nodes turn out not to support the tag.
+	finality ethtypes.BlockTag
+	// seen is the receipt of the last check that found one.
+	seen *Receipt
+}
+
+// check returns the receipt once the transaction is final enough, or nil to keep waiting.
+// head is the newest block if a subscription just delivered it.
+func (w *waiter) check(ctx context.Context, head *Block) (*Receipt, error) {
+	receipt, err := w.client.GetTransactionReceipt(ctx, w.hash)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if w.seen != nil && (receipt == nil || receipt.BlockHash != w.seen.BlockHash) {
+		reorged, err := w.reorged(ctx)
+		if err != nil || !reorged {
+			// An endpoint that is behind may not have the receipt yet: keep waiting.
+			return nil, err
+		}
+		dropped := w.seen
+		w.seen = nil
+		if w.opts.OnReorg == nil {
+			return nil, fmt.Errorf("%w: %s was in block %d (%s)", ErrReorged, w.hash, dropped.BlockNumber, dropped.BlockHash)
+		}
+		w.opts.OnReorg(dropped)
+	}
+	if receipt == nil {
+		return nil, nil
+	}
+	w.seen = receipt
+
+	if w.opts.Confirmations > 1 {
+		var number uint64
+		if head != nil {
+			number = head.Number
+		} else if number, err = w.client.BlockNumber(ctx); err != nil {
+			return nil, err
+		}
+		if number < receipt.BlockNumber || number-receipt.BlockNumber+1 < w.opts.Confirmations {
+			return nil, nil
+		}
+	}
+
+	if w.finality != "" {
+		final, err := w.client.GetBlockByNumber(ctx, w.finality, false)
+		switch {
+		case unsupportedTag(err):
+			// The tag is not supported: rely on confirmations from now on.
+			w.finality = ""
+		case errors.Is(err, ErrNotFound):
+			return nil, nil
+		case err != nil:
+			return nil, err
+		case final.Number < receipt.BlockNumber:
+			return nil, nil
+		}
+	}
+	return receipt, nil
+}
+
+// reorged reports whether the block the receipt was last seen in is no longer canonical.
+func (w *waiter) reorged(ctx context.Context) (bool, error) {
+	block, err := w.client.GetBlockByNumber(ctx, ethtypes.BlockNumber(w.seen.BlockNumber), false)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return block.Hash != w.seen.BlockHash, nil
+}
+
+// unsupportedTag reports whether err is a node's answer to a block tag it does not support.
+func unsupportedTag(err error) bool {
+	var rpcErr *types.JsonRPCError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return rpcErr.Code == -32601 || rpcErr.Code == -32602 || matchesAny(rpcErr, unsupportedTagMessages)
+}
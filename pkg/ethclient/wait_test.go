@@ -0,0 +1,223 @@
+package ethclient_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/registry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiptIn returns a receipt of the test transaction in a block.
+func receiptIn(number, hash string) string {
+	return `{"transactionHash":"` + testTxHash + `","blockNumber":"` + number + `","blockHash":"` + hash + `","status":"0x1"}`
+}
+
+const (
+	blockHashA = "0x00000000000000000000000000000000000000000000000000000000000000aa"
+	blockHashB = "0x00000000000000000000000000000000000000000000000000000000000000bb"
+	blockHashC = "0x00000000000000000000000000000000000000000000000000000000000000cc"
+)
+
+// TestClient_WaitForTransaction tests waiting for a receipt, confirmations and finality.
+func TestClient_WaitForTransaction(t *testing.T) {
+	ctx := context.Background()
+	hash := ethtypes.MustParseHash(testTxHash)
+
+	t.Run("confirmations", func(t *testing.T) {
+		var receipts, heads atomic.Int64
+		client, _ := newTestNodes(t, 7843, rpcHandler(func(method string) (string, *chainstypes.JsonRPCError) {
+			switch method {
+			case "eth_getTransactionReceipt":
+				// Pending for two checks, then mined in block 0x10.
+				if receipts.Add(1) <= 2 {
+					return `null`, nil
+				}
+				return receiptIn("0x10", blockHashA), nil
+			case "eth_blockNumber":
+				return `"` + ethtypes.EncodeUint64(0x10+uint64(heads.Add(1))-1) + `"`, nil
+			}
+			return "", &chainstypes.JsonRPCError{Code: -32601, Message: "method not found"}
+		}))
+		opts := ethclient.WaitOptions{Confirmations: 3, PollInterval: time.Millisecond}
+
+		receipt, err := client.WaitForTransaction(ctx, hash, opts)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0x10), receipt.BlockNumber)
+		assert.Equal(t, int64(5), receipts.Load())
+		assert.Equal(t, int64(3), heads.Load(), "heads 0x10, 0x11 and 0x12")
+
+		timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		opts.Confirmations = 100
+		_, err = client.WaitForTransaction(timeout, hash, opts)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("heads", func(t *testing.T) {
+		var blockNumbers atomic.Int64
+		client, _ := newTestNodes(t, 7844, rpcHandler(func(method string) (string, *chainstypes.JsonRPCError) {
+			switch method {
+			case "eth_getTransactionReceipt":
+				return receiptIn("0x10", blockHashA), nil
+			case "eth_blockNumber":
+				blockNumbers.Add(1)
+				return `"0x10"`, nil
+			}
+			return "", &chainstypes.JsonRPCError{Code: -32601, Message: "method not found"}
+		}))
+		heads := make(chan ethclient.Block, 2)
+		heads <- ethclient.Block{Number: 0x11}
+		heads <- ethclient.Block{Number: 0x12}
+		opts := ethclient.WaitOptions{Confirmations: 3, PollInterval: time.Hour, Heads: heads}
+
+		receipt, err := client.WaitForTransaction(ctx, hash, opts)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0x10), receipt.BlockNumber)
+		assert.Equal(t, int64(1), blockNumbers.Load(), "only the first check asks for the head")
+	})
+
+	t.Run("finalized", func(t *testing.T) {
+		var finalized atomic.Int64
+		client, _ := newTestNodes(t, 7845, rpcHandler(func(method string) (string, *chainstypes.JsonRPCError) {
+			switch method {
+			case "eth_getTransactionReceipt":
+				return receiptIn("0x10", blockHashA), nil
+			case "eth_getBlockByNumber":
+				return `{"number":"` + ethtypes.EncodeUint64(0x0e+uint64(finalized.Add(1))) + `"}`, nil
+			}
+			return "", &chainstypes.JsonRPCError{Code: -32601, Message: "method not found"}
+		}))
+		opts := ethclient.WaitOptions{Finality: ethtypes.Finalized, PollInterval: time.Millisecond}
+
+		_, err := client.WaitForTransaction(ctx, hash, opts)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), finalized.Load(), "finalized 0x0f, then 0x10")
+	})
+
+	t.Run("finality tag unsupported", func(t *testing.T) {
+		client, _ := newTestNodes(t, 7846, rpcHandler(func(method string) (string, *chainstypes.JsonRPCError) {
+			switch method {
+			case "eth_getTransactionReceipt":
+				return receiptIn("0x10", blockHashA), nil
+			case "eth_blockNumber":
+				return `"0x12"`, nil
+			}
+			return "", &chainstypes.JsonRPCError{Code: -32602, Message: "invalid block tag"}
+		}))
+		opts := ethclient.WaitOptions{Confirmations: 3, Finality: ethtypes.Safe, PollInterval: time.Millisecond}
+
+		_, err := client.WaitForTransaction(ctx, hash, opts)
+		require.NoError(t, err)
+	})
+
+	t.Run("reorg", func(t *testing.T) {
+		var receipts atomic.Int64
+		client, _ := newTestNodes(t, 7847, rpcHandler(func(method string) (string, *chainstypes.JsonRPCError) {
+			switch method {
+			case "eth_getTransactionReceipt":
+				// Mined in block A, dropped by a reorg, then mined again in block B.
+				switch receipts.Add(1) {
+				case 1:
+					return receiptIn("0x10", blockHashA), nil
+				case 2:
+					return `null`, nil
+				default:
+					return receiptIn("0x11", blockHashB), nil
+				}
+			case "eth_blockNumber":
+				if receipts.Load() < 3 {
+					return `"0x10"`, nil
+				}
+				return `"0x12"`, nil
+			case "eth_getBlockByNumber":
+				// Block 0x10 is no longer block A.
+				return `{"number":"0x10","hash":"` + blockHashC + `"}`, nil
+			}
+			return "", &chainstypes.JsonRPCError{Code: -32601, Message: "method not found"}
+		}))
+		opts := ethclient.WaitOptions{Confirmations: 3, PollInterval: time.Millisecond}
+
+		_, err := client.WaitForTransaction(ctx, hash, opts)
+		require.ErrorIs(t, err, ethclient.ErrReorged)
+
+		var dropped []*ethclient.Receipt
+		opts.Confirmations = 2
+		opts.OnReorg = func(r *ethclient.Receipt) { dropped = append(dropped, r) }
+		receipts.Store(0)
+		receipt, err := client.WaitForTransaction(ctx, hash, opts)
+		require.NoError(t, err)
+		assert.Equal(t, ethtypes.MustParseHash(blockHashB), receipt.BlockHash)
+		require.Len(t, dropped, 1)
+		assert.Equal(t, ethtypes.MustParseHash(blockHashA), dropped[0].BlockHash)
+	})
+
+	t.Run("lagging endpoint", func(t *testing.T) {
+		var receipts atomic.Int64
+		client, _ := newTestNodes(t, 7860, rpcHandler(func(method string) (string, *chainstypes.JsonRPCError) {
+			switch method {
+			case "eth_getTransactionReceipt":
+				// An endpoint that is behind answers the second check.
+				if receipts.Add(1) == 2 {
+					return `null`, nil
+				}
+				return receiptIn("0x10", blockHashA), nil
+			case "eth_blockNumber":
+				if receipts.Load() < 3 {
+					return `"0x10"`, nil
+				}
+				return `"0x12"`, nil
+			case "eth_getBlockByNumber":
+				return `{"number":"0x10","hash":"` + blockHashA + `"}`, nil
+			}
+			return "", &chainstypes.JsonRPCError{Code: -32601, Message: "method not found"}
+		}))
+		opts := ethclient.WaitOptions{Confirmations: 3, PollInterval: time.Millisecond}
+
+		receipt, err := client.WaitForTransaction(ctx, hash, opts)
+		require.NoError(t, err, "block A is still canonical")
+		assert.Equal(t, ethtypes.MustParseHash(blockHashA), receipt.BlockHash)
+	})
+
+	t.Run("finality check fails", func(t *testing.T) {
+		client, _ := newTestNodes(t, 7861, rpcHandler(func(method string) (string, *chainstypes.JsonRPCError) {
+			if method == "eth_getTransactionReceipt" {
+				return receiptIn("0x10", blockHashA), nil
+			}
+			return "", &chainstypes.JsonRPCError{Code: -32603, Message: "internal error"}
+		}))
+		opts := ethclient.WaitOptions{Finality: ethtypes.Finalized, PollInterval: time.Millisecond}
+
+		_, err := client.WaitForTransaction(ctx, hash, opts)
+		var rpcErr *chainstypes.JsonRPCError
+		require.ErrorAs(t, err, &rpcErr, "a failure is not taken for an unsupported tag")
+		assert.Equal(t, -32603, rpcErr.Code)
+	})
+
+	t.Run("block time", func(t *testing.T) {
+		var receipts atomic.Int64
+		client, _ := newTestNodes(t, 7848, rpcHandler(func(method string) (string, *chainstypes.JsonRPCError) {
+			if receipts.Add(1) <= 2 {
+				return `null`, nil
+			}
+			return receiptIn("0x10", blockHashA), nil
+		}))
+		chain := client.Chain()
+		chain.BlockTime = 200 * time.Millisecond
+		registry.RegisterChain(chain)
+
+		// Half the block time between checks, so the third check is about 200ms in.
+		start := time.Now()
+		_, err := ethclient.WaitForTransaction(ctx, chain.ID, hash, ethclient.DefaultWaitOptions())
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
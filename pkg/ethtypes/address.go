@@ -0,0 +1,133 @@
+package ethtypes
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// AddressLength is the length of an address in bytes.
+const AddressLength = 20
+
+// HashLength is the length of a hash in bytes.
+const HashLength = 32
+
+// Address is a 20-byte account address.
+type Address [AddressLength]byte
+
+// ParseAddress parses a 0x-prefixed, 40-digit hex address (checksums are not verified).
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	err := parseFixed(a[:], s, "address")
+	return a, err
+}
+
+// MustParseAddress is like ParseAddress but panics on invalid input. It is meant for constants.
+func MustParseAddress(s string) Address {
+	a, err := ParseAddress(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// BytesToAddress returns the last 20 bytes of b as an address, left-padding shorter input.
+func BytesToAddress(b []byte) Address {
+	var a Address
+	if len(b) > len(a) {
+		b = b[len(b)-len(a):]
+	}
+	copy(a[len(a)-len(b):], b)
+	return a
+}
+
+// Hex returns the lower-case 0x-prefixed hex encoding.
+func (a Address) Hex() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// String implements fmt.Stringer.
+func (a Address) String() string {
+	return a.Hex()
+}
+
+// IsZero reports whether a is the zero address.
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.Hex()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Address) UnmarshalText(text []byte) error {
+	return parseFixed(a[:], string(text), "address")
+}
+
+// Hash is a 32-byte hash, e.g. of a block or transaction.
+type Hash [HashLength]byte
+
+// ParseHash parses a 0x-prefixed, 64-digit hex hash.
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	err := parseFixed(h[:], s, "hash")
+	return h, err
+}
+
+// MustParseHash is like ParseHash but panics on invalid input. It is meant for constants.
+func MustParseHash(s string) Hash {
+	h, err := ParseHash(s)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// BytesToHash returns the last 32 bytes of b as a hash, left-padding shorter input.
+func BytesToHash(b []byte) Hash {
+	var h Hash
+	if len(b) > len(h) {
+		b = b[len(b)-len(h):]
+	}
+	copy(h[len(h)-len(b):], b)
+	return h
+}
+
+// Hex returns the 0x-prefixed hex encoding.
+func (h Hash) Hex() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// String implements fmt.Stringer.
+func (h Hash) String() string {
+	return h.Hex()
+}
+
+// IsZero reports whether h is all zeros.
+func (h Hash) IsZero() bool {
+	return h == Hash{}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(h.Hex()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *Hash) UnmarshalText(text []byte) error {
+	return parseFixed(h[:], string(text), "hash")
+}
+
+// parseFixed decodes hex into dst, requiring exactly len(dst) bytes.
+func parseFixed(dst []byte, s, kind string) error {
+	b, err := DecodeBytes(s)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", kind, err)
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("invalid %s %q: want %d bytes, got %d", kind, s, len(dst), len(b))
+	}
+	copy(dst, b)
+	return nil
+}
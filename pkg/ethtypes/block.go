@@ -0,0 +1,53 @@
+package ethtypes
+
+import (
+	"math/big"
+	"strings"
+)
+
+// BlockTag selects a block in JSON-RPC calls: a named tag or a hex block number.
+type BlockTag string
+
+const (
+	// Latest is the most recent block the node knows about.
+	Latest BlockTag = "latest"
+	// Pending is the block being built from the node's mempool.
+	Pending BlockTag = "pending"
+	// Safe is the most recent block that is unlikely to be reorged.
+	Safe BlockTag = "safe"
+	// Finalized is the most recent finalized block.
+	Finalized BlockTag = "finalized"
+	// Earliest is the genesis block.
+	Earliest BlockTag = "earliest"
+)
+
+// BlockNumber returns the tag of a specific block number.
+func BlockNumber(n uint64) BlockTag {
+	return BlockTag(EncodeUint64(n))
+}
+
+// BigBlockNumber returns the tag of a specific block number, or Latest if n is nil.
+func BigBlockNumber(n *big.Int) BlockTag {
+	if n == nil {
+		return Latest
+	}
+	return BlockTag(EncodeBig(n))
+}
+
+// Number returns the block number of a numeric tag.
+func (t BlockTag) Number() (uint64, bool) {
+	if !strings.HasPrefix(string(t), "0x") {
+		return 0, false
+	}
+	n, err := DecodeUint64(string(t))
+	return n, err == nil
+}
+
+// IsNamed reports whether t is one of the named tags rather than a block number.
+func (t BlockTag) IsNamed() bool {
+	switch t {
+	case Latest, Pending, Safe, Finalized, Earliest:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+// Package ethtypes provides the Ethereum primitives used by the typed clients of this module:
+// addresses, hashes, hex-encoded byte strings and quantities, and block tags. It has no
+// dependency outside the standard library.
+package ethtypes
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidHex is wrapped into every hex decoding error.
+var ErrInvalidHex = errors.New("invalid hex")
+
+// EncodeBig encodes a non-negative integer as a JSON-RPC quantity ("0x0", "0x1a", ...).
+func EncodeBig(n *big.Int) string {
+	if n == nil || n.Sign() == 0 {
+		return "0x0"
+	}
+	return "0x" + n.Text(16)
+}
+
+// DecodeBig decodes a JSON-RPC quantity. Leading zeros are accepted, as some nodes send them.
+func DecodeBig(s string) (*big.Int, error) {
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok {
+		digits, ok = strings.CutPrefix(s, "0X")
+	}
+	if !ok || digits == "" {
+		return nil, fmt.Errorf("%w quantity %q", ErrInvalidHex, s)
+	}
+	n, ok := new(big.Int).SetString(digits, 16)
+	if !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("%w quantity %q", ErrInvalidHex, s)
+	}
+	return n, nil
+}
+
+// EncodeUint64 encodes n as a JSON-RPC quantity.
+func EncodeUint64(n uint64) string {
+	return "0x" + strconv.FormatUint(n, 16)
+}
+
+// DecodeUint64 decodes a JSON-RPC quantity that fits into 64 bits.
+func DecodeUint64(s string) (uint64, error) {
+	n, err := DecodeBig(s)
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsUint64() {
+		return 0, fmt.Errorf("%w quantity %q: exceeds 64 bits", ErrInvalidHex, s)
+	}
+	return n.Uint64(), nil
+}
+
+// EncodeBytes encodes b as 0x-prefixed hex.
+func EncodeBytes(b []byte) string {
+	return "0x" + hex.EncodeToString(b)
+}
+
+// DecodeBytes decodes 0x-prefixed hex data. An odd number of digits is rejected.
+func DecodeBytes(s string) ([]byte, error) {
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok {
+		digits, ok = strings.CutPrefix(s, "0X")
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w data %q: missing 0x prefix", ErrInvalidHex, s)
+	}
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("%w data %q: %w", ErrInvalidHex, s, err)
+	}
+	return b, nil
+}
+
+// Big is a *big.Int that encodes as a JSON-RPC quantity.
+type Big big.Int
+
+// NewBig returns n as a *Big.
+func NewBig(n *big.Int) *Big {
+	return (*Big)(n)
+}
+
+// ToInt returns the value as a *big.Int.
+func (b *Big) ToInt() *big.Int {
+	return (*big.Int)(b)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (b *Big) MarshalText() ([]byte, error) {
+	return []byte(EncodeBig((*big.Int)(b))), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *Big) UnmarshalText(text []byte) error {
+	n, err := DecodeBig(string(text))
+	if err != nil {
+		return err
+	}
+	(*big.Int)(b).Set(n)
+	return nil
+}
+
+// Uint64 is a uint64 that encodes as a JSON-RPC quantity.
+type Uint64 uint64
+
+// MarshalText implements encoding.TextMarshaler.
+func (n Uint64) MarshalText() ([]byte, error) {
+	return []byte(EncodeUint64(uint64(n))), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (n *Uint64) UnmarshalText(text []byte) error {
+	v, err := DecodeUint64(string(text))
+	if err != nil {
+		return err
+	}
+	*n = Uint64(v)
+	return nil
+}
+
+// Bytes is a byte slice that encodes as 0x-prefixed hex.
+type Bytes []byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (b Bytes) MarshalText() ([]byte, error) {
+	return []byte(EncodeBytes(b)), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *Bytes) UnmarshalText(text []byte) error {
+	decoded, err := DecodeBytes(string(text))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// String returns the 0x-prefixed hex encoding.
+func (b Bytes) String() string {
+	return EncodeBytes(b)
+}
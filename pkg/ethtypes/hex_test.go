@@ -0,0 +1,133 @@
+package ethtypes
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestDecodeBig(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "0x0", want: "0"},
+		{input: "0x1a", want: "26"},
+		{input: "0x00ff", want: "255"},
+		{input: "0X10", want: "16"},
+		{input: "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", want: "115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+		{input: "0x", wantErr: true},
+		{input: "1a", wantErr: true},
+		{input: "0xzz", wantErr: true},
+		{input: "0x-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := DecodeBig(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DecodeBig(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidHex) {
+				t.Errorf("DecodeBig(%q) error = %v, want ErrInvalidHex", tt.input, err)
+			}
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("DecodeBig(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityRoundTrip(t *testing.T) {
+	for _, n := range []uint64{0, 1, 15, 16, 1 << 32, 1<<64 - 1} {
+		encoded := EncodeUint64(n)
+		decoded, err := DecodeUint64(encoded)
+		if err != nil || decoded != n {
+			t.Errorf("DecodeUint64(EncodeUint64(%d)) = %d, %v", n, decoded, err)
+		}
+		if viaBig := EncodeBig(new(big.Int).SetUint64(n)); viaBig != encoded {
+			t.Errorf("EncodeBig(%d) = %s, want %s", n, viaBig, encoded)
+		}
+	}
+	if _, err := DecodeUint64("0x10000000000000000"); err == nil {
+		t.Error("DecodeUint64 should reject values above 64 bits")
+	}
+}
+
+func TestJSONTypes(t *testing.T) {
+	var decoded struct {
+		Value   *Big     `json:"value"`
+		Nonce   Uint64   `json:"nonce"`
+		Input   Bytes    `json:"input"`
+		To      *Address `json:"to"`
+		Hash    Hash     `json:"hash"`
+		Missing *Big     `json:"missing"`
+	}
+	input := `{
+		"value": "0xde0b6b3a7640000",
+		"nonce": "0x2a",
+		"input": "0xa9059cbb",
+		"to": "0xcA11bde05977b3631167028862bE2a173976CA11",
+		"hash": "0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b"
+	}`
+	if err := json.Unmarshal([]byte(input), &decoded); err != nil {
+		t.Fatalf("Unmarshal error = %v", err)
+	}
+	if decoded.Value.ToInt().String() != "1000000000000000000" || decoded.Nonce != 42 || decoded.Missing != nil {
+		t.Errorf("decoded quantities = %v, %d, %v", decoded.Value.ToInt(), decoded.Nonce, decoded.Missing)
+	}
+	if decoded.Input.String() != "0xa9059cbb" {
+		t.Errorf("Input = %s", decoded.Input)
+	}
+	if decoded.To.Hex() != "0xca11bde05977b3631167028862be2a173976ca11" {
+		t.Errorf("To = %s", decoded.To)
+	}
+
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+	want := `{"value":"0xde0b6b3a7640000","nonce":"0x2a","input":"0xa9059cbb","to":"0xca11bde05977b3631167028862be2a173976ca11","hash":"0x88df016429689c079f3b2f6ad39fa052532c56795b733da78a91ebe6a713944b","missing":null}`
+	if string(encoded) != want {
+		t.Errorf("Marshal = %s, want %s", encoded, want)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{input: "0x0000000000000000000000000000000000000001"},
+		{input: "0x00000000000000000000000000000000000001", wantErr: true},
+		{input: "0000000000000000000000000000000000000001", wantErr: true},
+		{input: "0x000000000000000000000000000000000000000g", wantErr: true},
+	}
+	for _, tt := range tests {
+		if _, err := ParseAddress(tt.input); (err != nil) != tt.wantErr {
+			t.Errorf("ParseAddress(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+	}
+	if a := BytesToAddress([]byte{1, 2}); a.Hex() != "0x0000000000000000000000000000000000000102" {
+		t.Errorf("BytesToAddress = %s", a)
+	}
+}
+
+func TestBlockTag(t *testing.T) {
+	if tag := BlockNumber(255); tag != "0xff" {
+		t.Errorf("BlockNumber(255) = %s", tag)
+	}
+	if n, ok := BlockNumber(255).Number(); !ok || n != 255 {
+		t.Errorf("Number() = %d, %v", n, ok)
+	}
+	if _, ok := Finalized.Number(); ok || !Finalized.IsNamed() {
+		t.Error("finalized is a named tag")
+	}
+	if BigBlockNumber(nil) != Latest {
+		t.Error("BigBlockNumber(nil) should be latest")
+	}
+}
@@ -0,0 +1,50 @@
+package multicall
+
+import (
+	"fmt"
+
+	"go-ethereum-chains/pkg/abi"
+)
+
+// aggregate3 is the Multicall3 entry point used for batching.
+var aggregate3 = abi.MustParseFunction("function aggregate3((address target, bool allowFailure, bytes callData)[] calls) payable returns ((bool success, bytes returnData)[] returnData)")
+
+// encodeAggregate3 encodes an aggregate3 call of calls.
+func encodeAggregate3(calls []Call) []byte {
+	tuples := make([]any, len(calls))
+	for i, call := range calls {
+		tuples[i] = []any{call.Target, call.AllowFailure, call.CallData}
+	}
+	data, err := aggregate3.EncodeCall(tuples)
+	if err != nil {
+		// The values are typed by Call and always encode.
+		panic(err)
+	}
+	return data
+}
+
+// decodeAggregate3 decodes the (bool success, bytes returnData)[] returned by aggregate3.
+func decodeAggregate3(data []byte, n int) ([]Result, error) {
+	values, err := aggregate3.DecodeOutput(data)
+	if err != nil {
+		return nil, err
+	}
+	tuples := values[0].([]any)
+	if len(tuples) != n {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(tuples), n)
+	}
+
+	results := make([]Result, n)
+	for i, tuple := range tuples {
+		fields := tuple.([]any)
+		results[i] = Result{Success: fields[0].(bool), ReturnData: fields[1].([]byte)}
+	}
+	return results, nil
+}
+
+// callSize is the number of calldata bytes a call adds to an aggregate3 batch: its offset,
+// the three tuple words, the bytes length and the padded data.
+func callSize(call Call) int {
+	const wordSize = 32
+	return 5*wordSize + (len(call.CallData)+wordSize-1)/wordSize*wordSize
+}
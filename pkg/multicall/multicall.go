@@ -0,0 +1,224 @@
+// Package multicall batches eth_calls through the Multicall3 contract recorded in a chain's
+// Contracts.Multicall3, falling back to individual calls where the contract is not available.
+package multicall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/rpc"
+)
+
+// ErrCallFailed is wrapped into the error of Aggregate when a call that does not allow failure
+// reverted.
+var ErrCallFailed = errors.New("multicall: required call failed")
+
+// Call is a single eth_call to be aggregated.
+type Call struct {
+	Target   ethtypes.Address
+	CallData []byte
+	// AllowFailure lets the other calls succeed if this one reverts.
+	AllowFailure bool
+	// Decode, if set, is called with the return data of a successful call. Its error is
+	// reported in Result.Err.
+	Decode func(returnData []byte) error
+}
+
+// Result is the outcome of one Call.
+type Result struct {
+	Success    bool
+	ReturnData []byte
+	// Err is set when the call reverted or Decode failed.
+	Err error
+}
+
+// Options defines parameters for an Aggregator.
+type Options struct {
+	// MaxCalls is the largest number of calls packed into one aggregate3 call (0 means unlimited).
+	MaxCalls int
+	// MaxCalldataBytes caps the calldata of one aggregate3 call (0 means unlimited).
+	MaxCalldataBytes int
+	// Concurrency is the number of aggregate3 calls sent in parallel (at least 1).
+	Concurrency int
+}
+
+// DefaultOptions returns options that keep aggregate3 calls below common provider limits.
+func DefaultOptions() Options {
+	return Options{
+		MaxCalls:         500,
+		MaxCalldataBytes: 128 * 1024,
+		Concurrency:      4,
+	}
+}
+
+// Aggregator packs eth_calls into Multicall3 aggregate3 calls.
+type Aggregator struct {
+	client       *ethclient.Client
+	opts         Options
+	address      ethtypes.Address
+	blockCreated uint64
+	available    bool
+}
+
+// New creates an Aggregator for the chain of client. If the chain has no valid Multicall3
+// address, every call is sent individually.
+func New(client *ethclient.Client, opts Options) *Aggregator {
+	a := &Aggregator{client: client, opts: opts}
+	if contracts := client.Chain().Contracts; contracts != nil && contracts.Multicall3 != nil {
+		if address, err := ethtypes.ParseAddress(contracts.Multicall3.Address); err == nil {
+			a.address = address
+			a.blockCreated = contracts.Multicall3.BlockCreated
+			a.available = true
+		}
+	}
+	if a.opts.Concurrency < 1 {
+		a.opts.Concurrency = 1
+	}
+	return a
+}
+
+// Available reports whether Multicall3 can be used at block. Named tags other than "earliest"
+// are assumed to be past the deployment.
+func (a *Aggregator) Available(block ethtypes.BlockTag) bool {
+	if !a.available {
+		return false
+	}
+	if block == ethtypes.Earliest {
+		return a.blockCreated == 0
+	}
+	if n, ok := block.Number(); ok {
+		return n >= a.blockCreated
+	}
+	return true
+}
+
+// Aggregate executes calls at block and returns one Result per call, in order. It fails if a
+// call that does not allow failure reverted, or on transport errors.
+func (a *Aggregator) Aggregate(ctx context.Context, calls []Call, block ethtypes.BlockTag) ([]Result, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	var results []Result
+	var err error
+	if a.Available(block) {
+		results, err = a.aggregate(ctx, calls, block)
+	} else {
+		results, err = a.individually(ctx, calls, block)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, call := range calls {
+		r := &results[i]
+		switch {
+		case !r.Success && r.Err == nil:
+			r.Err = fmt.Errorf("call %d to %s: %w", i, call.Target, ethclient.DecodeRevert(r.ReturnData))
+		case r.Success && call.Decode != nil:
+			r.Err = call.Decode(r.ReturnData)
+		}
+	}
+	return results, nil
+}
+
+// aggregate sends the calls in aggregate3 chunks, in parallel up to Concurrency.
+func (a *Aggregator) aggregate(ctx context.Context, calls []Call, block ethtypes.BlockTag) ([]Result, error) {
+	results := make([]Result, len(calls))
+	chunks := a.split(calls)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, a.opts.Concurrency)
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			chunkResults, err := a.aggregateChunk(ctx, calls[chunk[0]:chunk[1]], block)
+			if err != nil {
+				errs[i] = fmt.Errorf("calls %d-%d: %w", chunk[0], chunk[1]-1, err)
+				return
+			}
+			copy(results[chunk[0]:], chunkResults)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// aggregateChunk sends one aggregate3 call.
+func (a *Aggregator) aggregateChunk(ctx context.Context, calls []Call, block ethtypes.BlockTag) ([]Result, error) {
+	msg := ethclient.CallMsg{To: &a.address, Data: encodeAggregate3(calls)}
+	out, err := a.client.Call(ctx, msg, block)
+	if err != nil {
+		// aggregate3 only reverts as a whole when a required call failed.
+		if _, ok := ethclient.AsRevert(err); ok {
+			return nil, fmt.Errorf("%w: %w", ErrCallFailed, err)
+		}
+		return nil, err
+	}
+	return decodeAggregate3(out, len(calls))
+}
+
+// split returns [start, end) index ranges of calls that respect MaxCalls and MaxCalldataBytes.
+func (a *Aggregator) split(calls []Call) [][2]int {
+	var chunks [][2]int
+	start, size := 0, 0
+	for i, call := range calls {
+		n := callSize(call)
+		full := a.opts.MaxCalls > 0 && i-start >= a.opts.MaxCalls
+		tooBig := a.opts.MaxCalldataBytes > 0 && size+n > a.opts.MaxCalldataBytes
+		if i > start && (full || tooBig) {
+			chunks = append(chunks, [2]int{start, i})
+			start, size = i, 0
+		}
+		size += n
+	}
+	return append(chunks, [2]int{start, len(calls)})
+}
+
+// individually sends each call as its own eth_call, grouped into JSON-RPC batches.
+func (a *Aggregator) individually(ctx context.Context, calls []Call, block ethtypes.BlockTag) ([]Result, error) {
+	batch := make([]rpc.BatchElem, len(calls))
+	returnData := make([]ethtypes.Bytes, len(calls))
+	for i, call := range calls {
+		target := call.Target
+		batch[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Params: []any{ethclient.CallMsg{To: &target, Data: call.CallData}, block},
+			Result: &returnData[i],
+		}
+	}
+	if err := a.client.RPC().BatchCall(ctx, batch); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(calls))
+	for i, elem := range batch {
+		if elem.Error == nil {
+			results[i] = Result{Success: true, ReturnData: returnData[i]}
+			continue
+		}
+		revert, ok := ethclient.AsRevert(elem.Error)
+		switch {
+		case ok && calls[i].AllowFailure:
+			results[i] = Result{ReturnData: revert.Data, Err: revert}
+		case ok:
+			return nil, fmt.Errorf("%w: call %d to %s: %w", ErrCallFailed, i, calls[i].Target, revert)
+		default:
+			return nil, fmt.Errorf("call %d to %s: %w", i, calls[i].Target, elem.Error)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,268 @@
+package multicall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/abi"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testMulticall = ethtypes.MustParseAddress("0xca11bde05977b3631167028862be2a173976ca11")
+	echoTarget    = ethtypes.MustParseAddress("0x00000000000000000000000000000000000000e1")
+	revertTarget  = ethtypes.MustParseAddress("0x00000000000000000000000000000000000000e2")
+)
+
+// fakeNode executes eth_call against two stand-in contracts: echoTarget returns its calldata
+// and revertTarget always reverts. Calls to testMulticall run aggregate3 over them.
+type fakeNode struct {
+	mu         sync.Mutex
+	aggregates int
+	direct     int
+	blocks     []string
+}
+
+func (n *fakeNode) handle(req json.RawMessage) chainstypes.JsonRPCResponse {
+	var call struct {
+		ID     chainstypes.JsonRPCID `json:"id"`
+		Method string                `json:"method"`
+		Params []json.RawMessage     `json:"params"`
+	}
+	if err := json.Unmarshal(req, &call); err != nil || call.Method != "eth_call" || len(call.Params) != 2 {
+		return chainstypes.JsonRPCResponse{Version: "2.0", ID: call.ID, Error: &chainstypes.JsonRPCError{Code: -32601, Message: "method not found"}}
+	}
+	var msg struct {
+		To    ethtypes.Address `json:"to"`
+		Input ethtypes.Bytes   `json:"input"`
+	}
+	_ = json.Unmarshal(call.Params[0], &msg)
+	var block string
+	_ = json.Unmarshal(call.Params[1], &block)
+
+	n.mu.Lock()
+	n.blocks = append(n.blocks, block)
+	if msg.To == testMulticall {
+		n.aggregates++
+	} else {
+		n.direct++
+	}
+	n.mu.Unlock()
+
+	resp := chainstypes.JsonRPCResponse{Version: "2.0", ID: call.ID}
+	var out []byte
+	var err error
+	if msg.To == testMulticall {
+		out, err = runAggregate3(msg.Input)
+	} else {
+		out, err = execute(msg.To, msg.Input)
+	}
+	if err != nil {
+		resp.Error = &chainstypes.JsonRPCError{Code: 3, Message: "execution reverted: " + err.Error()}
+		return resp
+	}
+	resp.Result, _ = json.Marshal(ethtypes.Bytes(out))
+	return resp
+}
+
+// execute runs a call against a stand-in contract.
+func execute(target ethtypes.Address, data []byte) ([]byte, error) {
+	if target == echoTarget {
+		return data, nil
+	}
+	return nil, errors.New("nope")
+}
+
+// runAggregate3 decodes aggregate3 calldata, executes the calls and encodes the results.
+func runAggregate3(input []byte) ([]byte, error) {
+	args, err := aggregate3.DecodeCall(input)
+	if err != nil {
+		return nil, err
+	}
+	var results []any
+	for _, tuple := range args[0].([]any) {
+		fields := tuple.([]any)
+		out, err := execute(fields[0].(ethtypes.Address), fields[2].([]byte))
+		if err != nil && !fields[1].(bool) {
+			return nil, errors.New("Multicall3: call failed")
+		}
+		results = append(results, []any{err == nil, out})
+	}
+	return abi.Encode(aggregate3.Outputs.Types(), results)
+}
+
+// newTestAggregator starts a fake node and returns an Aggregator for a chain served by it.
+func newTestAggregator(t *testing.T, chainID int64, contracts *chainstypes.Contracts, opts Options) (*Aggregator, *fakeNode) {
+	t.Helper()
+	node := &fakeNode{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body[0] != '[' {
+			_ = json.NewEncoder(w).Encode(node.handle(body))
+			return
+		}
+		var reqs []json.RawMessage
+		require.NoError(t, json.Unmarshal(body, &reqs))
+		resps := make([]chainstypes.JsonRPCResponse, len(reqs))
+		for i, req := range reqs {
+			resps[i] = node.handle(req)
+		}
+		_ = json.NewEncoder(w).Encode(resps)
+	}))
+	t.Cleanup(server.Close)
+
+	registry.RegisterChain(chainstypes.Chain{
+		ID:        big.NewInt(chainID),
+		Name:      t.Name(),
+		RPCUrls:   map[string]chainstypes.RpcTarget{"default": {Http: []string{server.URL}}},
+		Contracts: contracts,
+	})
+	clientOpts := rpc.DefaultClientOptions()
+	clientOpts.Retry.MaxAttempts = 1
+	client, err := ethclient.NewClient(chainID, clientOpts)
+	require.NoError(t, err)
+	return New(client, opts), node
+}
+
+func multicall3At(block uint64) *chainstypes.Contracts {
+	return &chainstypes.Contracts{Multicall3: &chainstypes.Contract{Address: testMulticall.Hex(), BlockCreated: block}}
+}
+
+func echo(i int) Call {
+	return Call{Target: echoTarget, CallData: []byte(fmt.Sprintf("call-%d", i))}
+}
+
+// TestAggregate_Split tests that calls are split into aggregate3 calls and reassembled in order.
+func TestAggregate_Split(t *testing.T) {
+	opts := DefaultOptions()
+	opts.MaxCalls = 3
+	agg, node := newTestAggregator(t, 7810, multicall3At(100), opts)
+
+	calls := make([]Call, 10)
+	for i := range calls {
+		calls[i] = echo(i)
+	}
+	results, err := agg.Aggregate(context.Background(), calls, ethtypes.Latest)
+	require.NoError(t, err)
+	require.Len(t, results, len(calls))
+	for i, r := range results {
+		assert.True(t, r.Success)
+		assert.NoError(t, r.Err)
+		assert.Equal(t, calls[i].CallData, r.ReturnData)
+	}
+	assert.Equal(t, 4, node.aggregates)
+	assert.Zero(t, node.direct)
+
+	// A calldata cap splits by size as well.
+	opts = DefaultOptions()
+	opts.MaxCalldataBytes = 2 * callSize(echo(0))
+	agg.opts = opts
+	node.aggregates = 0
+	_, err = agg.Aggregate(context.Background(), calls, ethtypes.Latest)
+	require.NoError(t, err)
+	assert.Equal(t, 5, node.aggregates)
+}
+
+// TestAggregate_Failures tests allowFailure, required failures and decode callbacks.
+func TestAggregate_Failures(t *testing.T) {
+	agg, _ := newTestAggregator(t, 7811, multicall3At(100), DefaultOptions())
+	ctx := context.Background()
+
+	var decoded string
+	calls := []Call{
+		{Target: echoTarget, CallData: []byte("hello"), Decode: func(b []byte) error {
+			decoded = string(b)
+			return nil
+		}},
+		{Target: revertTarget, AllowFailure: true},
+		{Target: echoTarget, Decode: func([]byte) error { return errors.New("bad result") }},
+	}
+	results, err := agg.Aggregate(ctx, calls, ethtypes.BlockNumber(200))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", decoded)
+	assert.True(t, results[0].Success)
+	assert.False(t, results[1].Success)
+	assert.Error(t, results[1].Err)
+	assert.True(t, results[2].Success)
+	assert.EqualError(t, results[2].Err, "bad result")
+
+	calls[1].AllowFailure = false
+	_, err = agg.Aggregate(ctx, calls, ethtypes.Latest)
+	assert.ErrorIs(t, err, ErrCallFailed)
+}
+
+// TestAggregate_Fallback tests individual calls before the deployment block and on chains
+// without Multicall3, with the same failure semantics.
+func TestAggregate_Fallback(t *testing.T) {
+	ctx := context.Background()
+	calls := []Call{echo(0), {Target: revertTarget, AllowFailure: true}, echo(2)}
+
+	for name, tc := range map[string]struct {
+		chainID   int64
+		contracts *chainstypes.Contracts
+		block     ethtypes.BlockTag
+	}{
+		"before deployment": {7812, multicall3At(100), ethtypes.BlockNumber(99)},
+		"earliest":          {7813, multicall3At(100), ethtypes.Earliest},
+		"no multicall3":     {7814, nil, ethtypes.Latest},
+	} {
+		t.Run(name, func(t *testing.T) {
+			agg, node := newTestAggregator(t, tc.chainID, tc.contracts, DefaultOptions())
+			assert.False(t, agg.Available(tc.block))
+
+			results, err := agg.Aggregate(ctx, calls, tc.block)
+			require.NoError(t, err)
+			assert.Zero(t, node.aggregates)
+			assert.Equal(t, len(calls), node.direct)
+			for _, block := range node.blocks {
+				assert.Equal(t, string(tc.block), block)
+			}
+
+			assert.Equal(t, calls[0].CallData, results[0].ReturnData)
+			assert.False(t, results[1].Success)
+			assert.Error(t, results[1].Err)
+			assert.Equal(t, calls[2].CallData, results[2].ReturnData)
+
+			required := append([]Call{}, calls...)
+			required[1].AllowFailure = false
+			_, err = agg.Aggregate(ctx, required, tc.block)
+			assert.ErrorIs(t, err, ErrCallFailed)
+		})
+	}
+}
+
+// TestAggregate3_Encoding tests the calldata layout against a known encoding.
+func TestAggregate3_Encoding(t *testing.T) {
+	calls := []Call{{Target: echoTarget, AllowFailure: true, CallData: []byte{0xde, 0xad}}}
+	got := encodeAggregate3(calls)
+
+	want := "82ad56cb" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"00000000000000000000000000000000000000000000000000000000000000e1" +
+		"0000000000000000000000000000000000000000000000000000000000000001" +
+		"0000000000000000000000000000000000000000000000000000000000000060" +
+		"0000000000000000000000000000000000000000000000000000000000000002" +
+		"dead000000000000000000000000000000000000000000000000000000000000"
+	assert.Equal(t, want, fmt.Sprintf("%x", got))
+	assert.Equal(t, len(got)-4-2*32, callSize(calls[0]))
+
+	_, err := decodeAggregate3(got[:40], 1)
+	assert.Error(t, err)
+}
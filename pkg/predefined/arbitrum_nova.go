@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // ArbitrumNova is the Arbitrum Nova mainnet configuration.
@@ -41,4 +42,5 @@ var ArbitrumNova = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 250 * time.Millisecond,
 }
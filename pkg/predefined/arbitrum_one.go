@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // ArbitrumOne is the Arbitrum One mainnet configuration.
@@ -49,4 +50,5 @@ var ArbitrumOne = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 250 * time.Millisecond,
 }
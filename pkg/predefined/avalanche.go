@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Avalanche is the Avalanche C-Chain mainnet configuration.
@@ -55,4 +56,5 @@ var Avalanche = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 2 * time.Second,
 }
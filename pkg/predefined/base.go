@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Base is the Base mainnet configuration.
@@ -40,4 +41,5 @@ var Base = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 2 * time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // BerachainArtio is the Berachain Artio testnet configuration.
@@ -41,4 +42,5 @@ var BerachainArtio = types.Chain{
 	},
 	// Contracts: Specific contracts for Berachain might differ.
 	IsTestnet: true,
+	BlockTime: 2 * time.Second,
 }
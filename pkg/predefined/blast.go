@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Blast is the Blast mainnet configuration.
@@ -50,4 +51,5 @@ var Blast = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 2 * time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Bnb is the BNB Smart Chain mainnet configuration.
@@ -53,4 +54,5 @@ var Bnb = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 750 * time.Millisecond,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Celo is the Celo mainnet configuration.
@@ -51,4 +52,5 @@ var Celo = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Core is the Core DAO mainnet configuration.
@@ -59,4 +60,5 @@ var Core = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 3 * time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Fantom is the Fantom Opera mainnet configuration.
@@ -51,4 +52,5 @@ var Fantom = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: time.Second,
 }
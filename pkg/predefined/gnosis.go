@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Gnosis is the Gnosis Chain mainnet configuration.
@@ -61,4 +62,5 @@ var Gnosis = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 5 * time.Second,
 }
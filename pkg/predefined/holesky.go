@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Holesky is the Holesky testnet configuration.
@@ -41,4 +42,5 @@ var Holesky = types.Chain{
 		},
 	},
 	IsTestnet: true,
+	BlockTime: 12 * time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Linea is the Linea mainnet configuration.
@@ -48,4 +49,5 @@ var Linea = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 2 * time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Mainnet is the official Ethereum Mainnet chain configuration.
@@ -54,4 +55,5 @@ var Mainnet = types.Chain{
 		BlockCreated: 16966585,
 	},
 	IsTestnet: false,
+	BlockTime: 12 * time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Optimism (now OP Mainnet) is the Optimism mainnet configuration.
@@ -49,4 +50,5 @@ var Optimism = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 2 * time.Second,
 }
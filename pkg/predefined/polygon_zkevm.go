@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // PolygonZkEvm is the Polygon zkEVM mainnet configuration.
@@ -40,4 +41,5 @@ var PolygonZkEvm = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 2 * time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Scroll is the Scroll mainnet configuration.
@@ -43,4 +44,5 @@ var Scroll = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: 3 * time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // Sepolia is the official Sepolia testnet configuration.
@@ -49,4 +50,5 @@ var Sepolia = types.Chain{
 		},
 	},
 	IsTestnet: true,
+	BlockTime: 12 * time.Second,
 }
@@ -3,6 +3,7 @@ package predefined
 import (
 	"go-ethereum-chains/internal/types"
 	"math/big"
+	"time"
 )
 
 // ZkSync is the zkSync Era mainnet configuration.
@@ -31,4 +32,5 @@ var ZkSync = types.Chain{
 		},
 	},
 	IsTestnet: false,
+	BlockTime: time.Second,
 }
@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+)
+
+// AuthHeaders returns the headers that authenticate a request according to auth. Secrets are
+// resolved on every call, so rotated files and variables are picked up. Errors never contain
+// secret values.
+func AuthHeaders(auth *types.AuthConfig) (http.Header, error) {
+	header := make(http.Header)
+	if auth == nil {
+		return header, nil
+	}
+
+	for name, ref := range auth.Headers {
+		value, err := ref.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("auth header %s: %w", name, err)
+		}
+		header.Set(name, value)
+	}
+	if auth.Basic != nil {
+		password, err := auth.Basic.Password.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("basic auth password: %w", err)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(auth.Basic.Username + ":" + password))
+		header.Set("Authorization", "Basic "+credentials)
+	}
+	if auth.Bearer != nil {
+		token, err := auth.Bearer.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("bearer token: %w", err)
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+	if auth.JWT != nil {
+		token, err := engineJWT(auth.JWT, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return header, nil
+}
+
+// engineJWT creates an Engine API token: HS256 with the current time as "iat".
+func engineJWT(cfg *types.JWTAuth, now time.Time) (string, error) {
+	secretHex, err := cfg.Secret.Resolve()
+	if err != nil {
+		return "", fmt.Errorf("jwt secret: %w", err)
+	}
+	secret, err := hex.DecodeString(strings.TrimPrefix(secretHex, "0x"))
+	if err != nil || len(secret) != 32 {
+		return "", fmt.Errorf("jwt secret %s must be 32 hex-encoded bytes", cfg.Secret)
+	}
+
+	claims := map[string]any{"iat": now.Unix()}
+	if cfg.ID != "" {
+		claims["id"] = cfg.ID
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal jwt claims: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claimsJSON)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+// authTransport adds authentication headers to every request.
+type authTransport struct {
+	next http.RoundTripper
+	auth *types.AuthConfig
+}
+
+func (t authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	header, err := AuthHeaders(t.auth)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	return t.next.RoundTrip(req)
+}
+
+// withAuth returns a client that authenticates its requests according to auth. It shares the
+// underlying transport of client, and thus its connections.
+func withAuth(client *http.Client, auth *types.AuthConfig) *http.Client {
+	if auth == nil {
+		return client
+	}
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	authed := *client
+	authed.Transport = authTransport{next: next, auth: auth}
+	return &authed
+}
+
+// handshakeHeader merges base with the authentication headers of auth for a WebSocket handshake.
+func handshakeHeader(base http.Header, auth *types.AuthConfig) (http.Header, error) {
+	header, err := AuthHeaders(auth)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range base {
+		if _, set := header[name]; !set {
+			header[name] = values
+		}
+	}
+	return header, nil
+}
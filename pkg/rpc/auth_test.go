@@ -0,0 +1,149 @@
+package rpc_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// verifyJWT checks an Engine API token signed with secret.
+func verifyJWT(t *testing.T, token string, secret []byte) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if base64.RawURLEncoding.EncodeToString(mac.Sum(nil)) != parts[2] {
+		return false
+	}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims struct {
+		IAT int64  `json:"iat"`
+		ID  string `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(claimsJSON, &claims))
+	return time.Since(time.Unix(claims.IAT, 0)).Abs() < time.Minute && claims.ID == "checker"
+}
+
+// TestAuth tests Bearer, Basic, API-key and JWT auth on HTTP checks, WebSocket handshakes and clients.
+func TestAuth(t *testing.T) {
+	const token, password, apiKey = "bearer-token", "basic-password", "api-key"
+	jwtSecret := make([]byte, 32)
+	jwtSecret[0] = 0xab
+
+	dir := t.TempDir()
+	jwtFile := filepath.Join(dir, "jwtsecret")
+	require.NoError(t, os.WriteFile(jwtFile, []byte("0x"+hex.EncodeToString(jwtSecret)+"\n"), 0o600))
+	keyFile := filepath.Join(dir, "api-key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(apiKey), 0o600))
+	t.Setenv("RPC_TEST_BEARER", token)
+
+	authorized := func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/bearer":
+			return r.Header.Get("Authorization") == "Bearer "+token
+		case "/basic":
+			user, pass, ok := r.BasicAuth()
+			return ok && user == "erigon" && pass == password
+		case "/key":
+			return r.Header.Get("X-Api-Key") == apiKey
+		case "/engine":
+			return verifyJWT(t, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), jwtSecret)
+		}
+		return false
+	}
+	gateway := setupHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		blockNumberHandler(w, r)
+	})
+	upgrader := websocket.Upgrader{}
+	wsGateway := setupHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		var req chainstypes.JsonRPCRequest
+		if conn.ReadJSON(&req) == nil {
+			_ = conn.WriteJSON(chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID, Result: json.RawMessage(`"0x10"`)})
+		}
+	})
+	wsURL := "ws" + strings.TrimPrefix(wsGateway.URL, "http")
+
+	targets := map[string]chainstypes.RpcTarget{
+		"bearer": {
+			Http: []string{gateway.URL + "/bearer"}, WebSocket: []string{wsURL + "/bearer"},
+			Auth: &chainstypes.AuthConfig{Bearer: &chainstypes.SecretRef{Env: "RPC_TEST_BEARER"}},
+		},
+		"basic": {
+			Http: []string{gateway.URL + "/basic"}, WebSocket: []string{wsURL + "/basic"},
+			Auth: &chainstypes.AuthConfig{Basic: &chainstypes.BasicAuth{Username: "erigon", Password: chainstypes.SecretRef{Value: password}}},
+		},
+		"key": {
+			Http: []string{gateway.URL + "/key"}, WebSocket: []string{wsURL + "/key"},
+			Auth: &chainstypes.AuthConfig{Headers: map[string]chainstypes.SecretRef{"x-api-key": {File: keyFile}}},
+		},
+		"engine": {
+			Http: []string{gateway.URL + "/engine"}, WebSocket: []string{wsURL + "/engine"},
+			Auth: &chainstypes.AuthConfig{JWT: &chainstypes.JWTAuth{Secret: chainstypes.SecretRef{File: jwtFile}, ID: "checker"}},
+		},
+		"anonymous": {Http: []string{gateway.URL + "/bearer"}},
+	}
+	registry.RegisterChain(chainstypes.Chain{ID: big.NewInt(7808), Name: t.Name(), RPCUrls: targets})
+
+	opts := rpc.DefaultCheckOptions()
+	opts.TimeoutPerCheck = 2 * time.Second
+	opts.Providers = nil
+	statuses, err := rpc.CheckRPCs(context.Background(), 7808, opts)
+	require.NoError(t, err)
+	require.Len(t, statuses, 9)
+	var rejected int
+	for _, s := range statuses {
+		if !s.IsAvailable {
+			// The anonymous target lists the bearer URL without credentials.
+			rejected++
+			assert.Equal(t, gateway.URL+"/bearer", s.URL)
+			assert.ErrorContains(t, s.Error, "401")
+		}
+	}
+	assert.Equal(t, 1, rejected)
+
+	clientOpts := fastClientOptions()
+	clientOpts.Criteria.Providers = []chainstypes.ProviderName{"engine", "basic"}
+	client, err := rpc.NewClient(7808, clientOpts)
+	require.NoError(t, err)
+	require.NoError(t, client.Call(context.Background(), nil, "eth_blockNumber"))
+
+	// Secrets that cannot be resolved fail the request without revealing other secrets.
+	t.Setenv("RPC_TEST_BEARER", "")
+	_, err = rpc.AuthHeaders(targets["bearer"].Auth)
+	assert.ErrorContains(t, err, "RPC_TEST_BEARER")
+	_, err = rpc.AuthHeaders(&chainstypes.AuthConfig{JWT: &chainstypes.JWTAuth{Secret: chainstypes.SecretRef{Value: "not-hex"}}})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "not-hex")
+}
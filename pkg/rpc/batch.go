@@ -0,0 +1,238 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-ethereum-chains/internal/types"
+)
+
+// ErrBatchUnsupported is returned when an endpoint rejects JSON-RPC batch requests altogether.
+var ErrBatchUnsupported = errors.New("endpoint does not support batch requests")
+
+// errBatchTooLarge is returned when an endpoint rejects a batch because of its size.
+var errBatchTooLarge = errors.New("batch too large")
+
+// BatchElem is a single call of a batch request.
+type BatchElem struct {
+	Method string
+	Params []any
+	// Result, if non-nil, receives the decoded result.
+	Result any
+	// Error is set when this call failed, independently of the other calls of the batch.
+	Error error
+}
+
+// BatchCall sends all elements as JSON-RPC batches and matches responses to elements by ID.
+// Batches are split to ClientOptions.MaxBatchSize and further to any smaller limit an endpoint
+// enforces; endpoints without batch support are sent the calls one by one. The returned error
+// only reports transport failures; per-call errors are stored in BatchElem.Error.
+func (c *Client) BatchCall(ctx context.Context, batch []BatchElem) error {
+	size := c.opts.MaxBatchSize
+	if size <= 0 {
+		size = len(batch)
+	}
+
+	for start := 0; start < len(batch); start += size {
+		chunk := batch[start:min(start+size, len(batch))]
+		idempotent := true
+		for _, elem := range chunk {
+			idempotent = idempotent && c.opts.Retry.isIdempotent(elem.Method)
+		}
+
+		pending := make([]*BatchElem, len(chunk))
+		for i := range chunk {
+			pending[i] = &chunk[i]
+		}
+		label := fmt.Sprintf("batch of %d calls", len(chunk))
+		err := c.withFailover(ctx, label, idempotent, false, func(attemptCtx context.Context, url string) error {
+			var err error
+			pending, err = c.sendBatch(ctx, attemptCtx, url, pending)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendBatch sends elements to one endpoint, honouring its learned batch limit. It returns the
+// elements that did not get a response so a failover only resends those. Batches are sent with
+// attemptCtx, which carries the attempt's deadline; individual calls to an endpoint without batch
+// support are sent with the caller's ctx, since each is an attempt of its own.
+func (c *Client) sendBatch(ctx, attemptCtx context.Context, url string, elems []*BatchElem) ([]*BatchElem, error) {
+	for len(elems) > 0 {
+		limit := c.batchLimit(url, len(elems))
+		if limit == 0 {
+			return c.sendEach(ctx, url, elems)
+		}
+
+		chunk := elems[:min(limit, len(elems))]
+		reqs := make([]types.JsonRPCRequest, len(chunk))
+		for i, elem := range chunk {
+			reqs[i] = c.newRequest(elem.Method, elem.Params)
+		}
+
+		resps, err := callHTTPBatch(attemptCtx, c.clients[url], url, reqs)
+		switch {
+		case errors.Is(err, errBatchTooLarge) && len(chunk) > 1:
+			c.batchLimits.Store(url, len(chunk)/2)
+			continue
+		case errors.Is(err, errBatchTooLarge), errors.Is(err, ErrBatchUnsupported):
+			c.batchLimits.Store(url, 0)
+			continue
+		case err != nil:
+			return elems, err
+		}
+
+		matchBatch(reqs, resps, chunk)
+		elems = elems[len(chunk):]
+	}
+	return nil, nil
+}
+
+// sendEach falls back to individual calls for an endpoint without batch support. Every call is
+// an attempt of its own, with a rate-limit token and the per-call deadline, and its outcome is
+// observed, except for a failure, which the failover reports.
+func (c *Client) sendEach(ctx context.Context, url string, elems []*BatchElem) ([]*BatchElem, error) {
+	for i, elem := range elems {
+		req := c.newRequest(elem.Method, elem.Params)
+		var result json.RawMessage
+		err := c.attempt(ctx, url, func(ctx context.Context, url string) error {
+			var err error
+			result, err = callHTTP(ctx, c.clients[url], url, req)
+			return err
+		})
+		var rpcErr *types.JsonRPCError
+		if errors.As(err, &rpcErr) && !isRetryable(err, c.opts.Retry.RetryableCodes) {
+			c.observe(ctx, url, err)
+			elem.Error = rpcErr
+			continue
+		}
+		if err != nil {
+			return elems[i:], err
+		}
+		c.observe(ctx, url, nil)
+		elem.Error = decodeResult(elem, result)
+	}
+	return nil, nil
+}
+
+// batchLimit returns how many calls may be sent to url in one batch (0 means no batching).
+func (c *Client) batchLimit(url string, want int) int {
+	if v, ok := c.batchLimits.Load(url); ok {
+		return min(v.(int), want)
+	}
+	return want
+}
+
+// matchBatch stores each response in the element whose request has the same ID.
+func matchBatch(reqs []types.JsonRPCRequest, resps []types.JsonRPCResponse, elems []*BatchElem) {
+	byID := make(map[types.JsonRPCID]types.JsonRPCResponse, len(resps))
+	for _, resp := range resps {
+		byID[resp.ID] = resp
+	}
+	for i, req := range reqs {
+		resp, ok := byID[req.ID]
+		if n, numeric := req.ID.Int64(); !ok && numeric {
+			resp, ok = byID[types.StringID(strconv.FormatInt(n, 10))]
+		}
+		switch {
+		case !ok:
+			elems[i].Error = fmt.Errorf("missing response for batch element %s (id %s)", req.Method, req.ID)
+		case resp.Error != nil:
+			elems[i].Error = resp.Error
+		default:
+			elems[i].Error = decodeResult(elems[i], resp.Result)
+		}
+	}
+}
+
+// decodeResult unmarshals a result into the element's Result, if any.
+func decodeResult(elem *BatchElem, result json.RawMessage) error {
+	if elem.Result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(result, elem.Result); err != nil {
+		return fmt.Errorf("failed to unmarshal %s result (%s): %w", elem.Method, string(result), err)
+	}
+	return nil
+}
+
+// callHTTPBatch posts a JSON-RPC batch and returns the responses in the order received.
+func callHTTPBatch(ctx context.Context, client *http.Client, url string, reqs []types.JsonRPCRequest) ([]types.JsonRPCResponse, error) {
+	bodyBytes, err := postJSON(ctx, client, url, reqs)
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.StatusCode == http.StatusRequestEntityTooLarge {
+			return nil, fmt.Errorf("%w: %w", errBatchTooLarge, err)
+		}
+		// Some gateways reject batches with a 4xx status and a JSON-RPC error body.
+		var rpcResp types.JsonRPCResponse
+		if httpErr.StatusCode >= 400 && httpErr.StatusCode < 500 && httpErr.StatusCode != http.StatusTooManyRequests &&
+			json.Unmarshal([]byte(httpErr.Body), &rpcResp) == nil && rpcResp.Error != nil {
+			return nil, classifyBatchRejection(rpcResp.Error)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(bodyBytes)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		// A single object in reply to a batch is a batch-level rejection.
+		var rpcResp types.JsonRPCResponse
+		if err := json.Unmarshal(trimmed, &rpcResp); err != nil {
+			return nil, fmt.Errorf("%w: failed to unmarshal json-rpc batch response (body: %s): %w", errBadResponse, string(bodyBytes), err)
+		}
+		return nil, classifyBatchRejection(rpcResp.Error)
+	}
+
+	var rpcResps []types.JsonRPCResponse
+	if err := json.Unmarshal(trimmed, &rpcResps); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal json-rpc batch response (body: %s): %w", errBadResponse, string(bodyBytes), err)
+	}
+	return rpcResps, nil
+}
+
+// classifyBatchRejection maps a batch-level error object to a size limit, rate limit or
+// missing batch support.
+func classifyBatchRejection(rpcErr *types.JsonRPCError) error {
+	if rpcErr == nil {
+		return ErrBatchUnsupported
+	}
+	if limited, _ := rateLimited(rpcErr); limited {
+		return rpcErr
+	}
+	msg := strings.ToLower(rpcErr.Message)
+	if strings.Contains(msg, "batch") {
+		for _, hint := range []string{"too large", "too many", "size", "exceed", "limit", "maximum"} {
+			if strings.Contains(msg, hint) {
+				return fmt.Errorf("%w: %w", errBatchTooLarge, rpcErr)
+			}
+		}
+	}
+	return fmt.Errorf("%w: %w", ErrBatchUnsupported, rpcErr)
+}
+
+// checkBatchSupport sends a two-call batch and reports whether the endpoint answered it.
+func checkBatchSupport(ctx context.Context, client *http.Client, url string) bool {
+	reqs := []types.JsonRPCRequest{
+		newProbeRequest(10, "eth_blockNumber"),
+		newProbeRequest(11, "eth_chainId"),
+	}
+	resps, err := callHTTPBatch(ctx, client, url, reqs)
+	if err != nil || len(resps) != len(reqs) {
+		return false
+	}
+	elems := []*BatchElem{{}, {}}
+	matchBatch(reqs, resps, elems)
+	return elems[0].Error == nil && elems[1].Error == nil
+}
@@ -0,0 +1,201 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchNode is a mock endpoint that answers batches through a per-request handler.
+type batchNode struct {
+	URL string
+
+	mu         sync.Mutex
+	batchSizes []int
+	singles    int
+}
+
+// newBatchNode starts a node. maxBatch limits the batch size (0 means unlimited, -1 rejects
+// batches). Responses to batches are returned in reverse order.
+func newBatchNode(t *testing.T, maxBatch int, respond func(req chainstypes.JsonRPCRequest) chainstypes.JsonRPCResponse) *batchNode {
+	t.Helper()
+	node := &batchNode{}
+	server := setupHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		if body[0] != '[' {
+			var req chainstypes.JsonRPCRequest
+			require.NoError(t, json.Unmarshal(body, &req))
+			node.mu.Lock()
+			node.singles++
+			node.mu.Unlock()
+			_ = json.NewEncoder(w).Encode(respond(req))
+			return
+		}
+
+		var reqs []chainstypes.JsonRPCRequest
+		require.NoError(t, json.Unmarshal(body, &reqs))
+		node.mu.Lock()
+		node.batchSizes = append(node.batchSizes, len(reqs))
+		node.mu.Unlock()
+
+		switch {
+		case maxBatch < 0:
+			_ = json.NewEncoder(w).Encode(chainstypes.JsonRPCResponse{Version: "2.0", Error: &chainstypes.JsonRPCError{Code: -32600, Message: "batch requests are not supported"}})
+			return
+		case maxBatch > 0 && len(reqs) > maxBatch:
+			_ = json.NewEncoder(w).Encode(chainstypes.JsonRPCResponse{Version: "2.0", Error: &chainstypes.JsonRPCError{Code: -32600, Message: "batch size too large, max " + strconv.Itoa(maxBatch)}})
+			return
+		}
+
+		resps := make([]chainstypes.JsonRPCResponse, 0, len(reqs))
+		for i := len(reqs) - 1; i >= 0; i-- {
+			resps = append(resps, respond(reqs[i]))
+		}
+		_ = json.NewEncoder(w).Encode(resps)
+	})
+	node.URL = server.URL
+	return node
+}
+
+// echoBlock answers eth_getBlockByNumber with the requested number and fails eth_call.
+func echoBlock(req chainstypes.JsonRPCRequest) chainstypes.JsonRPCResponse {
+	resp := chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID}
+	if req.Method == "eth_call" {
+		resp.Error = &chainstypes.JsonRPCError{Code: 3, Message: "execution reverted"}
+		return resp
+	}
+	resp.Result, _ = json.Marshal(map[string]any{"number": req.Params[0]})
+	return resp
+}
+
+// blockBatch builds n eth_getBlockByNumber elements.
+func blockBatch(n int) ([]rpc.BatchElem, []map[string]string) {
+	results := make([]map[string]string, n)
+	batch := make([]rpc.BatchElem, n)
+	for i := range batch {
+		batch[i] = rpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Params: []any{"0x" + strconv.FormatInt(int64(i), 16), false},
+			Result: &results[i],
+		}
+	}
+	return batch, results
+}
+
+// TestClient_BatchCall tests ID matching of out-of-order responses and partial errors.
+func TestClient_BatchCall(t *testing.T) {
+	node := newBatchNode(t, 0, echoBlock)
+	client, err := rpc.NewClient(registerMockChain(t, 7790, node.URL), fastClientOptions())
+	require.NoError(t, err)
+
+	batch, results := blockBatch(5)
+	batch = append(batch, rpc.BatchElem{Method: "eth_call", Params: []any{map[string]string{"to": "0x0"}, "latest"}})
+
+	require.NoError(t, client.BatchCall(context.Background(), batch))
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, batch[i].Error)
+		assert.Equal(t, "0x"+strconv.FormatInt(int64(i), 16), results[i]["number"])
+	}
+	var rpcErr *chainstypes.JsonRPCError
+	require.ErrorAs(t, batch[5].Error, &rpcErr)
+	assert.Equal(t, 3, rpcErr.Code)
+	assert.Equal(t, []int{6}, node.batchSizes)
+}
+
+// TestClient_BatchSplitting tests MaxBatchSize and learning an endpoint's smaller limit.
+func TestClient_BatchSplitting(t *testing.T) {
+	node := newBatchNode(t, 3, echoBlock)
+	opts := fastClientOptions()
+	opts.MaxBatchSize = 8
+	client, err := rpc.NewClient(registerMockChain(t, 7791, node.URL), opts)
+	require.NoError(t, err)
+
+	batch, results := blockBatch(10)
+	require.NoError(t, client.BatchCall(context.Background(), batch))
+	for i := range batch {
+		require.NoError(t, batch[i].Error)
+		assert.Equal(t, "0x"+strconv.FormatInt(int64(i), 16), results[i]["number"])
+	}
+	// 8 rejected, 4 rejected, then chunks of at most 3 for the rest.
+	assert.Equal(t, []int{8, 4, 2, 2, 2, 2, 2}, node.batchSizes)
+
+	node.batchSizes = nil
+	batch, _ = blockBatch(3)
+	require.NoError(t, client.BatchCall(context.Background(), batch))
+	assert.Equal(t, []int{2, 1}, node.batchSizes, "the learned limit is reused")
+}
+
+// TestClient_BatchUnsupported tests the fallback to individual calls.
+func TestClient_BatchUnsupported(t *testing.T) {
+	node := newBatchNode(t, -1, echoBlock)
+	client, err := rpc.NewClient(registerMockChain(t, 7792, node.URL), fastClientOptions())
+	require.NoError(t, err)
+
+	batch, results := blockBatch(4)
+	require.NoError(t, client.BatchCall(context.Background(), batch))
+	for i := range batch {
+		require.NoError(t, batch[i].Error)
+		assert.Equal(t, "0x"+strconv.FormatInt(int64(i), 16), results[i]["number"])
+	}
+	assert.Equal(t, []int{4}, node.batchSizes)
+	assert.Equal(t, 4, node.singles)
+}
+
+// TestClient_BatchFallbackLimits tests that individual calls of the fallback each take a
+// rate-limit token and get their own per-call deadline.
+func TestClient_BatchFallbackLimits(t *testing.T) {
+	node := newBatchNode(t, -1, func(req chainstypes.JsonRPCRequest) chainstypes.JsonRPCResponse {
+		time.Sleep(30 * time.Millisecond)
+		return echoBlock(req)
+	})
+	opts := fastClientOptions()
+	opts.CallTimeout = 50 * time.Millisecond
+	opts.RateLimiter = rpc.NewRateLimiter(rpc.RateLimiterOptions{Default: chainstypes.RateLimit{RequestsPerSecond: 20, Burst: 1}})
+	client, err := rpc.NewClient(registerMockChain(t, 7856, node.URL), opts)
+	require.NoError(t, err)
+
+	// The batch and four calls take five tokens, 50ms apart; the calls together take longer than
+	// one call's deadline.
+	start := time.Now()
+	batch, _ := blockBatch(4)
+	require.NoError(t, client.BatchCall(context.Background(), batch))
+	for i := range batch {
+		require.NoError(t, batch[i].Error)
+	}
+	assert.Equal(t, 4, node.singles)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+// TestCheckRPCs_BatchSupport tests batch support detection in the health checks.
+func TestCheckRPCs_BatchSupport(t *testing.T) {
+	respond := func(req chainstypes.JsonRPCRequest) chainstypes.JsonRPCResponse {
+		return chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID, Result: json.RawMessage(`"0x1"`)}
+	}
+	batching := newBatchNode(t, 0, respond)
+	single := newBatchNode(t, -1, respond)
+	chainID := registerMockChain(t, 7793, batching.URL, single.URL)
+
+	opts := rpc.DefaultCheckOptions()
+	opts.TimeoutPerCheck = 2 * time.Second
+	opts.CheckBatch = true
+	statuses, err := rpc.CheckRPCs(context.Background(), chainID, opts)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	for _, s := range statuses {
+		require.True(t, s.IsAvailable, s.URL)
+		assert.Equal(t, s.URL == batching.URL, s.SupportsBatch, s.URL)
+	}
+}
@@ -0,0 +1,289 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+)
+
+// BreakerState is the state of an endpoint's circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets requests through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects requests until the cooldown has elapsed.
+	BreakerOpen
+	// BreakerHalfOpen rejects requests while a single health probe decides whether to close again.
+	BreakerHalfOpen
+)
+
+// String returns the name of the state.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// BreakerOptions defines when endpoint circuit breakers trip and recover.
+type BreakerOptions struct {
+	// FailureThreshold trips the breaker after this many consecutive failures (0 disables).
+	FailureThreshold int
+	// ErrorRateThreshold trips the breaker when the failure ratio over Window reaches it (0 disables).
+	ErrorRateThreshold float64
+	// Window is the number of recent outcomes used for the error rate.
+	Window int
+	// MinRequests is the number of outcomes required before the error rate is considered.
+	MinRequests int
+	// Cooldown is how long a breaker stays open before a probe is attempted.
+	Cooldown time.Duration
+	// ProbeTimeout bounds the half-open health probe.
+	ProbeTimeout time.Duration
+	// OnStateChange, if set, is called after every state transition, without any lock held, so it
+	// may use the Breakers. It should not block, since it runs on the goroutine that caused the
+	// transition.
+	OnStateChange func(url string, from, to BreakerState)
+	// Clock is used for cooldowns (defaults to the system clock).
+	Clock Clock
+}
+
+// DefaultBreakerOptions returns options that trip after 5 consecutive failures or a 50% error
+// rate over the last 20 requests, and probe again after 30 seconds.
+func DefaultBreakerOptions() BreakerOptions {
+	return BreakerOptions{
+		FailureThreshold:   5,
+		ErrorRateThreshold: 0.5,
+		Window:             20,
+		MinRequests:        10,
+		Cooldown:           30 * time.Second,
+		ProbeTimeout:       5 * time.Second,
+		Clock:              systemClock{},
+	}
+}
+
+// Breakers holds one circuit breaker per RPC URL. It implements selector.Gate, so the same
+// instance can be shared by selectors (via RPCCriteria.Gates) and clients (via ClientOptions).
+type Breakers struct {
+	opts BreakerOptions
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+	// probes holds how to reach each URL, registered by the clients using the breakers.
+	probes map[string]probeTarget
+	// changes holds the transitions OnStateChange has not been called for yet.
+	changes []stateChange
+}
+
+// probeTarget is what a half-open probe of a URL needs: its credentials and the HTTP client,
+// with the proxy and TLS settings, of the client that uses it.
+type probeTarget struct {
+	auth       *types.AuthConfig
+	httpClient *http.Client
+}
+
+// stateChange is a breaker transition waiting to be reported.
+type stateChange struct {
+	url      string
+	from, to BreakerState
+}
+
+// breaker is the state of a single endpoint.
+type breaker struct {
+	state               BreakerState
+	consecutiveFailures int
+	outcomes            []bool
+	openedAt            time.Time
+}
+
+// NewBreakers creates an empty set of circuit breakers.
+func NewBreakers(opts BreakerOptions) *Breakers {
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+	if opts.Window <= 0 {
+		opts.Window = 1
+	}
+	return &Breakers{
+		opts:     opts,
+		breakers: make(map[string]*breaker),
+		probes:   make(map[string]probeTarget),
+	}
+}
+
+// Permits reports whether requests may be sent to url. If an open breaker's cooldown has
+// elapsed, it moves to half-open and starts a single background probe.
+func (b *Breakers) Permits(url string) bool {
+	defer b.notify()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.breakers[url]
+	if !ok {
+		return true
+	}
+	switch br.state {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if b.opts.Clock.Now().Sub(br.openedAt) >= b.opts.Cooldown {
+			b.transition(url, br, BreakerHalfOpen)
+			go b.runProbe(url)
+		}
+	}
+	return false
+}
+
+// RecordSuccess records a successful request to url.
+func (b *Breakers) RecordSuccess(url string) {
+	b.record(url, true)
+}
+
+// RecordFailure records a failed request to url, possibly tripping its breaker.
+func (b *Breakers) RecordFailure(url string) {
+	b.record(url, false)
+}
+
+// State returns the current state of url's breaker.
+func (b *Breakers) State(url string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if br, ok := b.breakers[url]; ok {
+		return br.state
+	}
+	return BreakerClosed
+}
+
+// States returns the state of every endpoint that has recorded outcomes.
+func (b *Breakers) States() map[string]BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	states := make(map[string]BreakerState, len(b.breakers))
+	for url, br := range b.breakers {
+		states[url] = br.state
+	}
+	return states
+}
+
+// record applies a request outcome to url's breaker.
+func (b *Breakers) record(url string, success bool) {
+	defer b.notify()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br, ok := b.breakers[url]
+	if !ok {
+		br = &breaker{}
+		b.breakers[url] = br
+	}
+	// Outcomes of requests started before the breaker opened do not count.
+	if br.state != BreakerClosed {
+		return
+	}
+
+	br.outcomes = appendWindow(br.outcomes, success, b.opts.Window)
+	if success {
+		br.consecutiveFailures = 0
+		return
+	}
+	br.consecutiveFailures++
+
+	if b.opts.FailureThreshold > 0 && br.consecutiveFailures >= b.opts.FailureThreshold {
+		b.trip(url, br)
+		return
+	}
+	if b.opts.ErrorRateThreshold > 0 && len(br.outcomes) >= max(b.opts.MinRequests, 1) {
+		var failures int
+		for _, ok := range br.outcomes {
+			if !ok {
+				failures++
+			}
+		}
+		if float64(failures)/float64(len(br.outcomes)) >= b.opts.ErrorRateThreshold {
+			b.trip(url, br)
+		}
+	}
+}
+
+// runProbe checks a half-open endpoint and closes or re-opens its breaker.
+func (b *Breakers) runProbe(url string) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.opts.ProbeTimeout)
+	defer cancel()
+	err := b.checkEndpoint(ctx, url)
+
+	defer b.notify()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	br := b.breakers[url]
+	if br.state != BreakerHalfOpen {
+		return
+	}
+	if err != nil {
+		b.trip(url, br)
+		return
+	}
+	br.consecutiveFailures = 0
+	br.outcomes = nil
+	b.transition(url, br, BreakerClosed)
+}
+
+// checkEndpoint probes url with the same eth_blockNumber check used by CheckRPCs, through the
+// HTTP client registered for it.
+func (b *Breakers) checkEndpoint(ctx context.Context, url string) error {
+	b.mu.Lock()
+	target := b.probes[url]
+	b.mu.Unlock()
+	opts := DefaultCheckOptions()
+	opts.TimeoutPerCheck = b.opts.ProbeTimeout
+	opts.HTTPClient = target.httpClient
+	check := checkHTTP
+	if strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://") {
+		check = checkWebSocket
+	}
+	return check(ctx, url, target.auth, opts).Error
+}
+
+// setProbe registers the credentials and HTTP client used to probe url.
+func (b *Breakers) setProbe(url string, auth *types.AuthConfig, httpClient *http.Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probes[url] = probeTarget{auth: auth, httpClient: httpClient}
+}
+
+// trip opens the breaker and starts its cooldown. The caller must hold b.mu.
+func (b *Breakers) trip(url string, br *breaker) {
+	br.openedAt = b.opts.Clock.Now()
+	b.transition(url, br, BreakerOpen)
+}
+
+// transition changes the breaker state and queues the change for the observer. The caller must
+// hold b.mu and call notify once it released it.
+func (b *Breakers) transition(url string, br *breaker, to BreakerState) {
+	from := br.state
+	br.state = to
+	if b.opts.OnStateChange != nil && from != to {
+		b.changes = append(b.changes, stateChange{url: url, from: from, to: to})
+	}
+}
+
+// notify calls OnStateChange for every queued transition. The caller must not hold b.mu.
+func (b *Breakers) notify() {
+	b.mu.Lock()
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+	for _, c := range changes {
+		b.opts.OnStateChange(c.url, c.from, c.to)
+	}
+}
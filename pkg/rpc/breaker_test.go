@@ -0,0 +1,172 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/rpc"
+	"go-ethereum-chains/pkg/selector"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateChange is a recorded breaker transition.
+type stateChange struct {
+	url      string
+	from, to rpc.BreakerState
+}
+
+// TestBreakers_TripAndRecover trips a breaker through the client, checks that the selector and
+// client skip the endpoint, and lets a half-open probe close it again.
+func TestBreakers_TripAndRecover(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	flapping := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		writeResult(w, req, `"0x10"`)
+	})
+	healthy := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `"0x10"`)
+	})
+	chainID := registerMockChain(t, 7785, flapping.URL, healthy.URL)
+
+	changes := make(chan stateChange, 10)
+	clock := newFakeClock()
+	breakerOpts := rpc.DefaultBreakerOptions()
+	breakerOpts.FailureThreshold = 2
+	breakerOpts.Cooldown = time.Minute
+	breakerOpts.ProbeTimeout = time.Second
+	breakerOpts.Clock = clock
+	var breakers *rpc.Breakers
+	breakerOpts.OnStateChange = func(url string, from, to rpc.BreakerState) {
+		// The observer may use the breakers it observes.
+		breakers.Permits(url)
+		changes <- stateChange{url, from, to}
+	}
+	breakers = rpc.NewBreakers(breakerOpts)
+
+	opts := fastClientOptions()
+	opts.Retry.MaxAttempts = 1
+	opts.Breakers = breakers
+	client, err := rpc.NewClient(chainID, opts)
+	require.NoError(t, err)
+
+	// Two consecutive failures trip the first endpoint's breaker.
+	for i := 0; i < 2; i++ {
+		breakers.RecordFailure(flapping.URL)
+	}
+	assert.Equal(t, stateChange{flapping.URL, rpc.BreakerClosed, rpc.BreakerOpen}, <-changes)
+	assert.Equal(t, rpc.BreakerOpen, breakers.State(flapping.URL))
+
+	// The client skips the open endpoint.
+	require.NoError(t, client.Call(context.Background(), nil, "eth_blockNumber"))
+	assert.EqualValues(t, 0, flapping.calls.Load())
+	assert.EqualValues(t, 1, healthy.calls.Load())
+
+	// The selector skips it as well.
+	criteria := selector.DefaultRPCCriteria()
+	criteria.Gates = []selector.Gate{breakers}
+	urls, err := selector.GetRPCs(chainID, criteria)
+	require.NoError(t, err)
+	assert.Equal(t, []string{healthy.URL}, urls)
+
+	// After the cooldown a failing probe re-opens the breaker.
+	clock.Advance(time.Minute)
+	assert.False(t, breakers.Permits(flapping.URL))
+	assert.Equal(t, stateChange{flapping.URL, rpc.BreakerOpen, rpc.BreakerHalfOpen}, <-changes)
+	assert.Equal(t, stateChange{flapping.URL, rpc.BreakerHalfOpen, rpc.BreakerOpen}, <-changes)
+
+	// Once the endpoint recovers, the next probe closes it.
+	failing.Store(false)
+	clock.Advance(time.Minute)
+	assert.False(t, breakers.Permits(flapping.URL))
+	assert.Equal(t, stateChange{flapping.URL, rpc.BreakerOpen, rpc.BreakerHalfOpen}, <-changes)
+	assert.Equal(t, stateChange{flapping.URL, rpc.BreakerHalfOpen, rpc.BreakerClosed}, <-changes)
+	assert.True(t, breakers.Permits(flapping.URL))
+	assert.Equal(t, rpc.BreakerClosed, breakers.States()[flapping.URL])
+}
+
+// TestBreakers_ProbeTransport tests that half-open probes go through the transport of the
+// client using the breakers, e.g. to reach endpoints behind a proxy.
+func TestBreakers_ProbeTransport(t *testing.T) {
+	node := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `"0x10"`)
+	})
+	clock := newFakeClock()
+	breakerOpts := rpc.DefaultBreakerOptions()
+	breakerOpts.FailureThreshold = 1
+	breakerOpts.ProbeTimeout = time.Second
+	breakerOpts.Clock = clock
+	breakers := rpc.NewBreakers(breakerOpts)
+
+	transport := &countingTransport{next: http.DefaultTransport}
+	opts := fastClientOptions()
+	opts.Transport = transport
+	opts.Breakers = breakers
+	_, err := rpc.NewClient(registerMockChain(t, 7865, node.URL), opts)
+	require.NoError(t, err)
+
+	breakers.RecordFailure(node.URL)
+	clock.Advance(breakerOpts.Cooldown)
+	assert.False(t, breakers.Permits(node.URL))
+	require.Eventually(t, func() bool { return breakers.State(node.URL) == rpc.BreakerClosed }, 3*time.Second, time.Millisecond)
+	assert.Positive(t, transport.count.Load())
+}
+
+// TestBreakers_ClientRecordsOutcomes tests that the client trips breakers on endpoint
+// failures but not on JSON-RPC execution errors.
+func TestBreakers_ClientRecordsOutcomes(t *testing.T) {
+	down := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	reverting := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeError(w, req, 3, "execution reverted")
+	})
+
+	breakerOpts := rpc.DefaultBreakerOptions()
+	breakerOpts.FailureThreshold = 1
+	breakers := rpc.NewBreakers(breakerOpts)
+
+	opts := fastClientOptions()
+	opts.Breakers = breakers
+
+	client, err := rpc.NewClient(registerMockChain(t, 7786, down.URL, reverting.URL), opts)
+	require.NoError(t, err)
+
+	err = client.Call(context.Background(), nil, "eth_call")
+	assert.Error(t, err)
+	assert.Equal(t, rpc.BreakerOpen, breakers.State(down.URL))
+	assert.Equal(t, rpc.BreakerClosed, breakers.State(reverting.URL))
+
+	// With every endpoint open the client refuses to send.
+	client, err = rpc.NewClient(registerMockChain(t, 7787, down.URL), opts)
+	require.NoError(t, err)
+	err = client.Call(context.Background(), nil, "eth_blockNumber")
+	assert.ErrorIs(t, err, rpc.ErrNoPermittedEndpoint)
+}
+
+// TestBreakers_ErrorRate tests that a high error rate trips the breaker without consecutive failures.
+func TestBreakers_ErrorRate(t *testing.T) {
+	opts := rpc.DefaultBreakerOptions()
+	opts.FailureThreshold = 0
+	opts.ErrorRateThreshold = 0.5
+	opts.Window = 4
+	opts.MinRequests = 4
+	breakers := rpc.NewBreakers(opts)
+
+	const url = "http://error-rate.test"
+	breakers.RecordSuccess(url)
+	breakers.RecordFailure(url)
+	breakers.RecordSuccess(url)
+	assert.Equal(t, rpc.BreakerClosed, breakers.State(url))
+	breakers.RecordFailure(url)
+	assert.Equal(t, rpc.BreakerOpen, breakers.State(url))
+}
@@ -0,0 +1,477 @@
+package rpc
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+// CacheEntry is a cached JSON-RPC result.
+type CacheEntry struct {
+	Result json.RawMessage
+	// Expires is when the entry goes stale; the zero time marks an immutable entry.
+	Expires time.Time
+	// Head is the chain head the entry was stored at. An expiring entry also goes stale once
+	// the chain's head moves past it.
+	Head uint64
+}
+
+// CacheBackend stores cache entries. Implementations must be safe for concurrent use.
+type CacheBackend interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry)
+	Delete(key string)
+}
+
+// LRUCache is an in-memory CacheBackend that evicts the least recently used entries.
+type LRUCache struct {
+	size int
+
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewLRUCache creates an LRUCache holding at most size entries.
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:  max(size, 1),
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry stored under key and marks it as recently used.
+func (c *LRUCache) Get(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruItem).entry, true
+}
+
+// Set stores entry under key, evicting the least recently used entry if the cache is full.
+func (c *LRUCache) Set(key string, entry CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+// Delete removes the entry stored under key.
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+		delete(c.items, key)
+	}
+}
+
+// Len returns the number of stored entries.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// CacheOptions defines parameters for a response Cache.
+type CacheOptions struct {
+	// Backend stores the entries. If nil, an LRUCache of Size entries is used.
+	Backend CacheBackend
+	// Size is the capacity of the default LRUCache.
+	Size int
+	// HeadTTL is how long results that depend on the chain head, e.g. of "latest" requests,
+	// are cached. They also go stale as soon as a newer head is seen.
+	HeadTTL time.Duration
+	// FinalizedRefresh is how often the finalized block is re-read when a request is pinned
+	// to a block number above the known finalized block.
+	FinalizedRefresh time.Duration
+	// Clock is used for expiry (defaults to the system clock).
+	Clock Clock
+}
+
+// DefaultCacheOptions returns options for a 10000 entry in-memory cache that keeps head
+// dependent results for at most 2s.
+func DefaultCacheOptions() CacheOptions {
+	return CacheOptions{
+		Size:             10000,
+		HeadTTL:          2 * time.Second,
+		FinalizedRefresh: 12 * time.Second,
+		Clock:            systemClock{},
+	}
+}
+
+// CacheStats counts the lookups of cacheable requests.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// Cache caches the results of read-only JSON-RPC calls by chain, method and canonical params.
+// Results pinned to a block hash, to a block at or below the finalized block or to the
+// genesis block never change and are kept until evicted; results that depend on the chain
+// head are kept for HeadTTL. Set it in ClientOptions.Cache; it may be shared by several clients.
+type Cache struct {
+	opts CacheOptions
+
+	mu     sync.Mutex
+	chains map[string]*chainHeads
+	stats  CacheStats
+}
+
+// chainHeads is what a Cache knows about the head and the finalized block of a chain.
+type chainHeads struct {
+	head      uint64
+	finalized uint64
+	// checked is when the finalized block was last read.
+	checked time.Time
+}
+
+// NewCache creates a Cache.
+func NewCache(opts CacheOptions) *Cache {
+	if opts.Backend == nil {
+		opts.Backend = NewLRUCache(opts.Size)
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+	return &Cache{opts: opts, chains: make(map[string]*chainHeads)}
+}
+
+// Stats returns the hit and miss counters since the Cache was created.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// SetHead records a new head of the chain, which makes cached head dependent results stale.
+// eth_blockNumber results are recorded automatically; a newHeads subscription can report
+// heads as they arrive.
+func (c *Cache) SetHead(chainID *big.Int, head uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	heads := c.heads(chainID)
+	heads.head = max(heads.head, head)
+}
+
+// SetFinalized records the finalized block of the chain.
+func (c *Cache) SetFinalized(chainID *big.Int, finalized uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	heads := c.heads(chainID)
+	heads.finalized = max(heads.finalized, finalized)
+	heads.checked = c.opts.Clock.Now()
+}
+
+// heads returns the state of a chain. c.mu must be held.
+func (c *Cache) heads(chainID *big.Int) *chainHeads {
+	heads, ok := c.chains[chainID.String()]
+	if !ok {
+		heads = &chainHeads{}
+		c.chains[chainID.String()] = heads
+	}
+	return heads
+}
+
+// call answers a call from the cache or sends it through client and caches the result.
+func (c *Cache) call(ctx context.Context, client *Client, method string, params []any) (json.RawMessage, error) {
+	rule := cacheRuleFor(method, params)
+	if rule.kind == cacheNever {
+		return client.callRaw(ctx, method, params)
+	}
+	key, err := cacheKey(client.chain.ID, method, params)
+	if err != nil {
+		return client.callRaw(ctx, method, params)
+	}
+
+	if entry, ok := c.opts.Backend.Get(key); ok {
+		if c.fresh(client.chain.ID, entry) {
+			c.count(true)
+			return entry.Result, nil
+		}
+		c.opts.Backend.Delete(key)
+	}
+	c.count(false)
+
+	result, err := client.callRaw(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	if method == "eth_blockNumber" {
+		var head string
+		if json.Unmarshal(result, &head) == nil {
+			if n, err := ethtypes.DecodeUint64(head); err == nil {
+				c.SetHead(client.chain.ID, n)
+			}
+		}
+	}
+	c.store(ctx, client, key, rule, result)
+	return result, nil
+}
+
+// store caches result as immutable if rule and the finalized block allow it, or else for
+// HeadTTL if the result depends on the chain head.
+func (c *Cache) store(ctx context.Context, client *Client, key string, rule cacheRule, result json.RawMessage) {
+	null := isNull(result)
+	immutable := false
+	switch rule.kind {
+	case cacheImmutable:
+		immutable = !null
+	case cacheBlockHashLogs:
+		immutable = !null && !isEmptyList(result)
+	case cacheBlock:
+		immutable = !null && rule.block <= c.finalized(ctx, client, rule.block)
+	case cacheTransaction:
+		// A transaction is only immutable once the block it was included in is finalized.
+		var tx struct {
+			BlockNumber string `json:"blockNumber"`
+		}
+		if null || json.Unmarshal(result, &tx) != nil {
+			return
+		}
+		block, err := ethtypes.DecodeUint64(tx.BlockNumber)
+		if err != nil || block > c.finalized(ctx, client, block) {
+			return
+		}
+		immutable = true
+	}
+
+	if immutable {
+		c.opts.Backend.Set(key, CacheEntry{Result: result})
+		return
+	}
+	if c.opts.HeadTTL <= 0 || rule.kind == cacheImmutable || rule.kind == cacheBlockHashLogs {
+		return
+	}
+	c.mu.Lock()
+	head := c.heads(client.chain.ID).head
+	c.mu.Unlock()
+	c.opts.Backend.Set(key, CacheEntry{Result: result, Expires: c.opts.Clock.Now().Add(c.opts.HeadTTL), Head: head})
+}
+
+// fresh reports whether entry may still be served.
+func (c *Cache) fresh(chainID *big.Int, entry CacheEntry) bool {
+	if entry.Expires.IsZero() {
+		return true
+	}
+	c.mu.Lock()
+	head := c.heads(chainID).head
+	c.mu.Unlock()
+	return c.opts.Clock.Now().Before(entry.Expires) && entry.Head >= head
+}
+
+// finalized returns the finalized block of the client's chain, re-reading it at most every
+// FinalizedRefresh if the known one is below block.
+func (c *Cache) finalized(ctx context.Context, client *Client, block uint64) uint64 {
+	c.mu.Lock()
+	heads := c.heads(client.chain.ID)
+	known, checked := heads.finalized, heads.checked
+	c.mu.Unlock()
+	if block <= known || c.opts.Clock.Now().Sub(checked) < c.opts.FinalizedRefresh {
+		return known
+	}
+
+	var header struct {
+		Number string `json:"number"`
+	}
+	raw, err := client.callRaw(ctx, "eth_getBlockByNumber", []any{string(ethtypes.Finalized), false})
+	if err == nil && json.Unmarshal(raw, &header) == nil {
+		if n, err := ethtypes.DecodeUint64(header.Number); err == nil {
+			known = max(known, n)
+		}
+	}
+	// Chains without a finalized tag are not asked again before the next refresh.
+	c.SetFinalized(client.chain.ID, known)
+	return known
+}
+
+func (c *Cache) count(hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hit {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+}
+
+// cacheKind classifies how the result of a request may be cached.
+type cacheKind int
+
+const (
+	// cacheNever marks writes, subscriptions and unknown methods.
+	cacheNever cacheKind = iota
+	// cacheImmutable results never change once they are known, e.g. a block by hash.
+	cacheImmutable
+	// cacheHead results change with the chain head, e.g. of "latest" requests.
+	cacheHead
+	// cacheBlock results are pinned to a block number and immutable once it is finalized.
+	cacheBlock
+	// cacheTransaction results are immutable once the including block is finalized.
+	cacheTransaction
+	// cacheBlockHashLogs results are the logs of a block by hash. They are immutable, except
+	// that a node which has not seen the block may answer with no logs, so that is not cached.
+	cacheBlockHashLogs
+)
+
+type cacheRule struct {
+	kind  cacheKind
+	block uint64
+}
+
+// blockParams maps methods taking a block parameter to its position in the params.
+var blockParams = map[string]int{
+	"eth_getBalance":                          1,
+	"eth_getCode":                             1,
+	"eth_getTransactionCount":                 1,
+	"eth_getStorageAt":                        2,
+	"eth_call":                                1,
+	"eth_getProof":                            2,
+	"eth_getBlockByNumber":                    0,
+	"eth_getBlockReceipts":                    0,
+	"eth_getBlockTransactionCountByNumber":    0,
+	"eth_getTransactionByBlockNumberAndIndex": 0,
+	"eth_getUncleByBlockNumberAndIndex":       0,
+	"eth_getUncleCountByBlockNumber":          0,
+}
+
+// cacheKinds classifies the cacheable methods without a block parameter.
+var cacheKinds = map[string]cacheKind{
+	"eth_chainId":                           cacheImmutable,
+	"net_version":                           cacheImmutable,
+	"eth_getBlockByHash":                    cacheImmutable,
+	"eth_getBlockTransactionCountByHash":    cacheImmutable,
+	"eth_getTransactionByBlockHashAndIndex": cacheImmutable,
+	"eth_getUncleByBlockHashAndIndex":       cacheImmutable,
+	"eth_getUncleCountByBlockHash":          cacheImmutable,
+	"eth_getTransactionByHash":              cacheTransaction,
+	"eth_getTransactionReceipt":             cacheTransaction,
+	"eth_blockNumber":                       cacheHead,
+	"eth_gasPrice":                          cacheHead,
+	"eth_maxPriorityFeePerGas":              cacheHead,
+	"eth_blobBaseFee":                       cacheHead,
+	"eth_feeHistory":                        cacheHead,
+}
+
+// cacheRuleFor classifies a request by its method and block parameter.
+func cacheRuleFor(method string, params []any) cacheRule {
+	if i, ok := blockParams[method]; ok {
+		var block any
+		if i < len(params) {
+			block = params[i]
+		}
+		return blockRule(block)
+	}
+	if method == "eth_getLogs" {
+		return logsRule(params)
+	}
+	return cacheRule{kind: cacheKinds[method]}
+}
+
+// blockRule classifies a block parameter: a tag, a hex number or an EIP-1898 object.
+func blockRule(block any) cacheRule {
+	if block == nil {
+		return cacheRule{kind: cacheHead}
+	}
+	raw, err := json.Marshal(block)
+	if err != nil {
+		return cacheRule{}
+	}
+	var selector struct {
+		BlockHash   string `json:"blockHash"`
+		BlockNumber string `json:"blockNumber"`
+	}
+	var tag string
+	if json.Unmarshal(raw, &tag) != nil {
+		if json.Unmarshal(raw, &selector) != nil {
+			return cacheRule{}
+		}
+		if selector.BlockHash != "" {
+			return cacheRule{kind: cacheImmutable}
+		}
+		tag = selector.BlockNumber
+	}
+
+	switch t := ethtypes.BlockTag(tag); {
+	case t == ethtypes.Earliest:
+		return cacheRule{kind: cacheImmutable}
+	case t.IsNamed(), t == "":
+		return cacheRule{kind: cacheHead}
+	default:
+		n, ok := t.Number()
+		if !ok {
+			return cacheRule{}
+		}
+		return cacheRule{kind: cacheBlock, block: n}
+	}
+}
+
+// logsRule classifies an eth_getLogs filter by its block hash or its toBlock.
+func logsRule(params []any) cacheRule {
+	if len(params) != 1 {
+		return cacheRule{}
+	}
+	raw, err := json.Marshal(params[0])
+	if err != nil {
+		return cacheRule{}
+	}
+	var filter struct {
+		BlockHash string `json:"blockHash"`
+		ToBlock   any    `json:"toBlock"`
+	}
+	if json.Unmarshal(raw, &filter) != nil {
+		return cacheRule{}
+	}
+	if filter.BlockHash != "" {
+		return cacheRule{kind: cacheBlockHashLogs}
+	}
+	return blockRule(filter.ToBlock)
+}
+
+// cacheKey identifies a request by chain, method and canonical params.
+func cacheKey(chainID *big.Int, method string, params []any) (string, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	canonical, err := canonicalResult(raw)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s/%s", chainID, method, canonical), nil
+}
+
+func isNull(result json.RawMessage) bool {
+	return len(result) == 0 || strings.TrimSpace(string(result)) == "null"
+}
+
+func isEmptyList(result json.RawMessage) bool {
+	var list []json.RawMessage
+	return json.Unmarshal(result, &list) == nil && len(list) == 0
+}
@@ -0,0 +1,164 @@
+package rpc_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClient_Cache tests which results are cached and for how long.
+func TestClient_Cache(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]int)
+	)
+	node := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		mu.Lock()
+		calls[req.Method]++
+		mu.Unlock()
+		switch req.Method {
+		case "eth_getBlockByNumber":
+			if req.Params[0] == "finalized" {
+				writeResult(w, req, `{"number":"0x64"}`)
+				return
+			}
+			writeResult(w, req, `{"number":"`+req.Params[0].(string)+`"}`)
+		case "eth_getBlockByHash":
+			if req.Params[0] == "0x02" {
+				writeResult(w, req, `null`)
+				return
+			}
+			writeResult(w, req, `{"number":"0x10"}`)
+		case "eth_getTransactionReceipt":
+			switch req.Params[0] {
+			case "0xa1":
+				writeResult(w, req, `{"blockNumber":"0x10"}`)
+			case "0xa2":
+				writeResult(w, req, `{"blockNumber":"0xc8"}`)
+			default:
+				writeResult(w, req, `null`)
+			}
+		case "eth_getLogs":
+			if req.Params[0].(map[string]any)["blockHash"] == "0xb2" {
+				writeResult(w, req, `[]`)
+				return
+			}
+			writeResult(w, req, `[{"logIndex":"0x0"}]`)
+		case "eth_blockNumber":
+			writeResult(w, req, `"0xc8"`)
+		default:
+			writeResult(w, req, `"0x1"`)
+		}
+	})
+	upstream := func(method string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[method]
+	}
+
+	clock := newFakeClock()
+	cacheOpts := rpc.DefaultCacheOptions()
+	cacheOpts.Clock = clock
+	cache := rpc.NewCache(cacheOpts)
+	opts := fastClientOptions()
+	opts.Cache = cache
+	chainID := registerMockChain(t, 7830, node.URL)
+	client, err := rpc.NewClient(chainID, opts)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	callTwice := func(method string, params ...any) {
+		t.Helper()
+		for range 2 {
+			require.NoError(t, client.Call(ctx, nil, method, params...))
+		}
+	}
+
+	t.Run("immutable methods", func(t *testing.T) {
+		callTwice("eth_chainId")
+		assert.Equal(t, 1, upstream("eth_chainId"))
+		callTwice("eth_getBlockByHash", "0x01", false)
+		assert.Equal(t, 1, upstream("eth_getBlockByHash"))
+		// An unknown block may still appear.
+		callTwice("eth_getBlockByHash", "0x02", false)
+		assert.Equal(t, 3, upstream("eth_getBlockByHash"))
+	})
+
+	t.Run("finalized block numbers", func(t *testing.T) {
+		const account = "0x52908400098527886E0F7030069857D2E4169EE7"
+		callTwice("eth_getBalance", account, "0x10")
+		// Params are compared canonically, so a lowercase address is the same request.
+		require.NoError(t, client.Call(ctx, nil, "eth_getBalance", "0x52908400098527886e0f7030069857d2e4169ee7", "0x10"))
+		// An EIP-1898 block object is a different request, finalized all the same.
+		require.NoError(t, client.Call(ctx, nil, "eth_getBalance", account, map[string]any{"blockNumber": "0x10"}))
+		assert.Equal(t, 2, upstream("eth_getBalance"))
+		assert.Equal(t, 1, upstream("eth_getBlockByNumber"), "the finalized block is read once")
+
+		// Above the finalized block results are kept until the head moves on.
+		callTwice("eth_getBalance", account, "0xc8")
+		assert.Equal(t, 3, upstream("eth_getBalance"))
+		cache.SetHead(chainID, 201)
+		callTwice("eth_getBalance", account, "0xc8")
+		assert.Equal(t, 4, upstream("eth_getBalance"))
+	})
+
+	t.Run("latest expires", func(t *testing.T) {
+		callTwice("eth_call", map[string]any{"to": "0x01", "data": "0x"}, "latest")
+		assert.Equal(t, 1, upstream("eth_call"))
+		clock.Advance(cacheOpts.HeadTTL)
+		callTwice("eth_call", map[string]any{"to": "0x01", "data": "0x"}, "latest")
+		assert.Equal(t, 2, upstream("eth_call"))
+	})
+
+	t.Run("receipts", func(t *testing.T) {
+		callTwice("eth_getTransactionReceipt", "0xa1")
+		assert.Equal(t, 1, upstream("eth_getTransactionReceipt"))
+		callTwice("eth_getTransactionReceipt", "0xa2")
+		callTwice("eth_getTransactionReceipt", "0xa3")
+		assert.Equal(t, 5, upstream("eth_getTransactionReceipt"), "unfinalized and unknown receipts are not cached")
+	})
+
+	t.Run("logs by block hash", func(t *testing.T) {
+		callTwice("eth_getLogs", map[string]any{"blockHash": "0xb1"})
+		assert.Equal(t, 1, upstream("eth_getLogs"))
+		// No logs may come from a node that has not seen the block yet.
+		callTwice("eth_getLogs", map[string]any{"blockHash": "0xb2"})
+		assert.Equal(t, 3, upstream("eth_getLogs"))
+	})
+
+	t.Run("writes", func(t *testing.T) {
+		callTwice("eth_sendRawTransaction", "0x01")
+		assert.Equal(t, 2, upstream("eth_sendRawTransaction"))
+	})
+
+	assert.Equal(t, rpc.CacheStats{Hits: 10, Misses: 18}, cache.Stats())
+}
+
+// TestLRUCache tests eviction of the least recently used entry.
+func TestLRUCache(t *testing.T) {
+	lru := rpc.NewLRUCache(2)
+	lru.Set("a", rpc.CacheEntry{Result: []byte(`1`)})
+	lru.Set("b", rpc.CacheEntry{Result: []byte(`2`)})
+	_, ok := lru.Get("a")
+	require.True(t, ok)
+	lru.Set("c", rpc.CacheEntry{Result: []byte(`3`)})
+
+	_, ok = lru.Get("b")
+	assert.False(t, ok, "b was used least recently")
+	entry, ok := lru.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, "1", string(entry.Result))
+	assert.Equal(t, 2, lru.Len())
+
+	lru.Delete("a")
+	_, ok = lru.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, lru.Len())
+}
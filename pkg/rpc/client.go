@@ -0,0 +1,422 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/selector"
+)
+
+// ErrNonIdempotent is wrapped into the error of a non-idempotent call that failed and was not
+// resent to another endpoint because RetryPolicy.RetryNonIdempotent is off.
+var ErrNonIdempotent = errors.New("non-idempotent call not retried on another endpoint")
+
+// ErrNoPermittedEndpoint is returned when every endpoint is currently excluded by a gate,
+// e.g. because all circuit breakers are open.
+var ErrNoPermittedEndpoint = errors.New("no endpoint currently permitted")
+
+// RetryPolicy defines how a Client retries failed calls.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts per call, across all endpoints.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential backoff.
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after each retry.
+	Multiplier float64
+	// Jitter randomises each backoff by up to this fraction (0..1) in either direction.
+	Jitter float64
+	// RetryableCodes are JSON-RPC error codes that are retried on the next endpoint. Rate-limit
+	// errors are retried whatever their code.
+	RetryableCodes []int
+	// NonIdempotentMethods are only resent to another endpoint if RetryNonIdempotent is set.
+	NonIdempotentMethods []string
+	// RetryNonIdempotent opts in to failing over non-idempotent methods.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a policy with three attempts and exponential backoff from 100ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableCodes: []int{
+			-32002, // resource unavailable
+			-32603, // internal error
+		},
+		NonIdempotentMethods: []string{
+			"eth_sendRawTransaction",
+			"eth_sendTransaction",
+			"eth_sendRawTransactionConditional",
+		},
+	}
+}
+
+// backoff returns the delay before retry number n (starting at 1).
+func (p RetryPolicy) backoff(n int) time.Duration {
+	if p.InitialBackoff <= 0 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(n-1))
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// isIdempotent reports whether method may be resent to a different endpoint.
+func (p RetryPolicy) isIdempotent(method string) bool {
+	return p.RetryNonIdempotent || !slices.Contains(p.NonIdempotentMethods, method)
+}
+
+// ClientOptions defines parameters for a failover JSON-RPC Client.
+type ClientOptions struct {
+	// Criteria selects the chain's HTTP endpoints the client fails over between, in order.
+	Criteria selector.RPCCriteria
+	// CallTimeout bounds every single attempt (0 relies on the caller's context only).
+	CallTimeout time.Duration
+	// Retry configures retries and failover.
+	Retry RetryPolicy
+	// HTTPClient is used for all requests. If nil, Transport is used, or else DefaultTransport.
+	HTTPClient *http.Client
+	// Transport sends requests when HTTPClient is nil.
+	Transport http.RoundTripper
+	// Breakers, if set, records the outcome of every attempt and skips endpoints whose breaker is not closed.
+	Breakers *Breakers
+	// RateLimiter, if set, throttles requests per host, skips hosts that are backing off and
+	// backs off from hosts that answer with rate-limit errors.
+	RateLimiter *RateLimiter
+	// MaxBatchSize is the largest number of calls sent in one batch request (0 means unlimited).
+	MaxBatchSize int
+	// Hedger, if set, sends idempotent calls that are slow to answer to a second endpoint as well.
+	// Batches are not hedged.
+	Hedger *Hedger
+	// Cache, if set, answers repeated read-only calls from cached results. Batches are not cached.
+	Cache *Cache
+	// Coalesce lets concurrent identical calls share one upstream request, so they also share
+	// its result or error. It is off by default. Batches are not coalesced, nor are calls whose
+	// context carries per-call options such as WithExpectedErrors, since a shared request could
+	// only honour the first caller's.
+	Coalesce bool
+	// CoalesceWrites also coalesces the retry policy's NonIdempotentMethods, which are excluded
+	// by default.
+	CoalesceWrites bool
+	// Statuses, if set, provides endpoint block heights, e.g. from a Monitor, so calls in a
+	// Session skip endpoints that are behind. Heights the endpoints report in answers are used too.
+	Statuses StatusSource
+}
+
+// DefaultClientOptions returns default options for NewClient.
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Criteria:     selector.DefaultRPCCriteria(),
+		CallTimeout:  10 * time.Second,
+		Retry:        DefaultRetryPolicy(),
+		MaxBatchSize: 100,
+	}
+}
+
+// Client sends JSON-RPC calls to one of a chain's endpoints and fails over to the next one on
+// network errors, HTTP 5xx and 429 responses, and retryable JSON-RPC error codes.
+type Client struct {
+	chain     types.Chain
+	endpoints []string
+	gates     []selector.Gate
+	opts      ClientOptions
+	// clients holds the HTTP client per endpoint, carrying the endpoint's authentication.
+	clients map[string]*http.Client
+
+	nextID    atomic.Int64
+	preferred atomic.Int64
+	// batchLimits holds the learned batch size limit per endpoint URL (0 means no batch support).
+	batchLimits sync.Map
+	flights     flightGroup
+	// heights holds the highest head each endpoint URL has reported in an answer.
+	heights sync.Map
+}
+
+// NewClient creates a Client for a registered chain identified by ID or name.
+func NewClient(identifier any, opts ClientOptions) (*Client, error) {
+	chain, err := registry.FindChain(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calls are plain HTTP POSTs, so WebSocket endpoints are never used. Gates are applied per
+	// attempt rather than here, so endpoints excluded right now are not dropped for good.
+	criteria := opts.Criteria
+	criteria.AllowHTTP = true
+	criteria.AllowWebSocket = false
+	criteria.Gates = nil
+	endpoints, err := selector.GetRPCs(chain.ID, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	gates := slices.Clone(opts.Criteria.Gates)
+	if opts.Breakers != nil {
+		gates = append(gates, opts.Breakers)
+	}
+	if opts.RateLimiter != nil {
+		opts.RateLimiter.Configure(chain)
+		gates = append(gates, rateLimitGate{opts.RateLimiter})
+	}
+
+	opts.HTTPClient = httpClientFor(opts.HTTPClient, opts.Transport)
+	clients := make(map[string]*http.Client, len(endpoints))
+	for _, url := range endpoints {
+		auth := chain.AuthFor(url)
+		clients[url] = withAuth(opts.HTTPClient, auth)
+		if opts.Breakers != nil {
+			opts.Breakers.setProbe(url, auth, opts.HTTPClient)
+		}
+	}
+	if opts.Retry.MaxAttempts <= 0 {
+		opts.Retry.MaxAttempts = 1
+	}
+
+	return &Client{
+		chain:     chain,
+		endpoints: endpoints,
+		gates:     gates,
+		opts:      opts,
+		clients:   clients,
+	}, nil
+}
+
+// Chain returns the chain the client was created for.
+func (c *Client) Chain() types.Chain {
+	return c.chain
+}
+
+// Endpoints returns the endpoints the client fails over between, in order.
+func (c *Client) Endpoints() []string {
+	return slices.Clone(c.endpoints)
+}
+
+// Call performs a JSON-RPC call and decodes its result into result, which may be nil.
+func (c *Client) Call(ctx context.Context, result any, method string, params ...any) error {
+	raw, err := c.CallRaw(ctx, method, params...)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		return fmt.Errorf("failed to unmarshal %s result (%s): %w", method, string(raw), err)
+	}
+	return nil
+}
+
+// CallRaw performs a JSON-RPC call and returns the undecoded result.
+func (c *Client) CallRaw(ctx context.Context, method string, params ...any) (json.RawMessage, error) {
+	if s := SessionFrom(ctx); s != nil {
+		params = s.pin(method, params)
+		result, err := c.callUpstream(ctx, method, params)
+		if err == nil {
+			if block, ok := resultBlock(method, params, result); ok {
+				s.Observe(block)
+			}
+		}
+		return result, err
+	}
+	if c.opts.Cache != nil {
+		return c.opts.Cache.call(ctx, c, method, params)
+	}
+	return c.callRaw(ctx, method, params)
+}
+
+// callRaw performs a JSON-RPC call, bypassing the cache, and shares it with concurrent
+// identical calls if coalescing is enabled for the method and ctx sets no per-call options.
+func (c *Client) callRaw(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+	if c.opts.Coalesce && !hasExpectedErrors(ctx) && (c.opts.CoalesceWrites || !slices.Contains(c.opts.Retry.NonIdempotentMethods, method)) {
+		if key, err := cacheKey(c.chain.ID, method, params); err == nil {
+			return c.flights.do(ctx, key, func(ctx context.Context) (json.RawMessage, error) {
+				return c.callUpstream(ctx, method, params)
+			})
+		}
+	}
+	return c.callUpstream(ctx, method, params)
+}
+
+// callUpstream sends a JSON-RPC call with failover and, if configured, hedging.
+func (c *Client) callUpstream(ctx context.Context, method string, params []any) (json.RawMessage, error) {
+	req := c.newRequest(method, params)
+	idempotent := c.opts.Retry.isIdempotent(method)
+	var (
+		mu     sync.Mutex
+		result json.RawMessage
+	)
+	err := c.withFailover(ctx, method, idempotent, c.opts.Hedger != nil && idempotent, func(ctx context.Context, url string) error {
+		raw, err := callHTTP(ctx, c.clients[url], url, req)
+		if err != nil {
+			return err
+		}
+		if err := c.checkHeight(ctx, url, method, params, raw); err != nil {
+			return err
+		}
+		// A hedged call can be answered twice; the first answer is kept.
+		mu.Lock()
+		defer mu.Unlock()
+		if result == nil {
+			result = raw
+		}
+		return nil
+	})
+	return result, err
+}
+
+// newRequest builds a JSON-RPC 2.0 request with a fresh ID.
+func (c *Client) newRequest(method string, params []any) types.JsonRPCRequest {
+	if params == nil {
+		params = []any{}
+	}
+	return types.JsonRPCRequest{
+		Version: "2.0",
+		Method:  method,
+		Params:  params,
+		ID:      types.NumberID(c.nextID.Add(1)),
+	}
+}
+
+// withFailover runs send against the preferred endpoint, failing over to the next permitted
+// endpoint and backing off according to the retry policy. label names the call in errors. If
+// hedge is set, slow attempts are hedged and send must be safe for concurrent use.
+func (c *Client) withFailover(ctx context.Context, label string, idempotent, hedge bool, send func(ctx context.Context, url string) error) error {
+	endpoint := int(c.preferred.Load())
+	var lastErr error
+	for attempt := 1; attempt <= c.opts.Retry.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%s: %w (last error: %v)", label, ctx.Err(), lastErr)
+			case <-time.After(c.opts.Retry.backoff(attempt - 1)):
+			}
+		}
+
+		var ok bool
+		if endpoint, ok = c.nextPermitted(ctx, endpoint); !ok {
+			if lastErr != nil {
+				return fmt.Errorf("%w: %w", ErrNoPermittedEndpoint, lastErr)
+			}
+			return ErrNoPermittedEndpoint
+		}
+		url := c.endpoints[endpoint]
+
+		var err error
+		if hedge {
+			err = c.hedgedAttempt(ctx, endpoint, send)
+		} else {
+			err = c.attempt(ctx, url, send)
+			c.observe(ctx, url, err)
+		}
+		retryable := isRetryable(err, c.opts.Retry.RetryableCodes) && !isExpected(ctx, err)
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("%s: %w", url, err)
+
+		if ctx.Err() != nil || !retryable {
+			return lastErr
+		}
+		if !idempotent {
+			if !isNotDelivered(err) {
+				return fmt.Errorf("%w: %w", ErrNonIdempotent, lastErr)
+			}
+			// The request never reached the node: retry the same endpoint.
+			continue
+		}
+
+		// Fail over and remember the move so later calls skip the failing endpoint.
+		endpoint++
+		c.preferred.Store(int64(endpoint % len(c.endpoints)))
+	}
+
+	return fmt.Errorf("%s failed after %d attempts: %w", label, c.opts.Retry.MaxAttempts, lastErr)
+}
+
+// nextPermitted returns the first endpoint index from start on, wrapping around, that every gate
+// permits for a call with ctx.
+func (c *Client) nextPermitted(ctx context.Context, start int) (int, bool) {
+	gates := c.gatesFor(ctx)
+	for i := 0; i < len(c.endpoints); i++ {
+		idx := (start + i) % len(c.endpoints)
+		if selector.Permitted(c.endpoints[idx], gates) {
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// observe reports the outcome of an attempt to the rate limiter and the circuit breakers. An
+// expected error is the endpoint's answer and counts as a success.
+func (c *Client) observe(ctx context.Context, url string, err error) {
+	if isExpected(ctx, err) {
+		c.recordOutcome(url, nil, false)
+		return
+	}
+	limited := c.opts.RateLimiter != nil && c.opts.RateLimiter.Observe(url, err)
+	if !limited {
+		c.recordOutcome(url, err, isRetryable(err, c.opts.Retry.RetryableCodes))
+	}
+}
+
+// recordOutcome reports an attempt to the circuit breakers. Only endpoint failures count
+// against an endpoint; an answered JSON-RPC error such as a revert is a healthy response.
+func (c *Client) recordOutcome(url string, err error, retryable bool) {
+	if c.opts.Breakers == nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	if err != nil && retryable {
+		c.opts.Breakers.RecordFailure(url)
+	} else {
+		c.opts.Breakers.RecordSuccess(url)
+	}
+}
+
+// rateLimitGate skips hosts that are backing off without waiting for tokens, which Wait handles.
+type rateLimitGate struct {
+	limiter *RateLimiter
+}
+
+func (g rateLimitGate) Permits(url string) bool {
+	return g.limiter.ThrottledUntil(url).IsZero()
+}
+
+// attempt runs send against one endpoint once it is within its rate limit, bounded by the per-call deadline.
+func (c *Client) attempt(ctx context.Context, url string, send func(ctx context.Context, url string) error) error {
+	if c.opts.RateLimiter != nil {
+		if err := c.opts.RateLimiter.Wait(ctx, url); err != nil {
+			return err
+		}
+	}
+	if c.opts.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.CallTimeout)
+		defer cancel()
+	}
+	return send(ctx, url)
+}
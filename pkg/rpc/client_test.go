@@ -0,0 +1,265 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockNode is an HTTP JSON-RPC server whose behaviour can be switched per test.
+type mockNode struct {
+	URL   string
+	calls atomic.Int64
+}
+
+// newMockNode starts a server that answers every request through respond.
+func newMockNode(t *testing.T, respond func(w http.ResponseWriter, req chainstypes.JsonRPCRequest)) *mockNode {
+	t.Helper()
+	node := &mockNode{}
+	server := setupHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		node.calls.Add(1)
+		var req chainstypes.JsonRPCRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		respond(w, req)
+	})
+	node.URL = server.URL
+	return node
+}
+
+// writeResult writes a successful JSON-RPC response.
+func writeResult(w http.ResponseWriter, req chainstypes.JsonRPCRequest, result string) {
+	_ = json.NewEncoder(w).Encode(chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID, Result: json.RawMessage(result)})
+}
+
+// writeError writes a JSON-RPC error response.
+func writeError(w http.ResponseWriter, req chainstypes.JsonRPCRequest, code int, message string) {
+	_ = json.NewEncoder(w).Encode(chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID, Error: &chainstypes.JsonRPCError{Code: code, Message: message}})
+}
+
+// registerMockChain registers a chain whose default provider lists the given URLs.
+func registerMockChain(t testing.TB, id int64, urls ...string) *big.Int {
+	t.Helper()
+	chainID := big.NewInt(id)
+	registry.RegisterChain(chainstypes.Chain{
+		ID:      chainID,
+		Name:    t.Name(),
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {Http: urls}},
+	})
+	return chainID
+}
+
+// fastClientOptions returns client options without noticeable backoff.
+func fastClientOptions() rpc.ClientOptions {
+	opts := rpc.DefaultClientOptions()
+	opts.CallTimeout = time.Second
+	opts.Retry.InitialBackoff = time.Millisecond
+	opts.Retry.MaxBackoff = 5 * time.Millisecond
+	return opts
+}
+
+// TestClient_Failover tests failover on HTTP 5xx, 429 and retryable JSON-RPC errors.
+func TestClient_Failover(t *testing.T) {
+	down := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	limited := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	internal := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeError(w, req, -32603, "internal error")
+	})
+	healthy := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `"0x2a"`)
+	})
+
+	chainID := registerMockChain(t, 7780, down.URL, limited.URL, internal.URL, healthy.URL)
+	opts := fastClientOptions()
+	opts.Retry.MaxAttempts = 4
+
+	client, err := rpc.NewClient(chainID, opts)
+	require.NoError(t, err)
+
+	var result string
+	require.NoError(t, client.Call(context.Background(), &result, "eth_blockNumber"))
+	assert.Equal(t, "0x2a", result)
+	assert.EqualValues(t, 1, down.calls.Load())
+	assert.EqualValues(t, 1, limited.calls.Load())
+	assert.EqualValues(t, 1, internal.calls.Load())
+
+	// The client sticks to the endpoint that worked.
+	require.NoError(t, client.Call(context.Background(), &result, "eth_blockNumber"))
+	assert.EqualValues(t, 1, down.calls.Load())
+	assert.EqualValues(t, 2, healthy.calls.Load())
+}
+
+// TestClient_NonRetryableError tests that execution errors are returned without failover.
+func TestClient_NonRetryableError(t *testing.T) {
+	reverting := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeError(w, req, 3, "execution reverted")
+	})
+	other := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `"0x"`)
+	})
+
+	chainID := registerMockChain(t, 7781, reverting.URL, other.URL)
+	client, err := rpc.NewClient(chainID, fastClientOptions())
+	require.NoError(t, err)
+
+	err = client.Call(context.Background(), nil, "eth_call", map[string]string{"to": "0x0"}, "latest")
+	var rpcErr *chainstypes.JsonRPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, 3, rpcErr.Code)
+	assert.EqualValues(t, 0, other.calls.Load())
+
+	// Params that cannot be encoded fail the same way everywhere and are not retried.
+	breakers := rpc.NewBreakers(rpc.DefaultBreakerOptions())
+	opts := fastClientOptions()
+	opts.Breakers = breakers
+	client, err = rpc.NewClient(chainID, opts)
+	require.NoError(t, err)
+	err = client.Call(context.Background(), nil, "eth_call", make(chan int), "latest")
+	assert.ErrorContains(t, err, "failed to marshal json-rpc request")
+	assert.NotContains(t, err.Error(), "attempts")
+	assert.EqualValues(t, 1, reverting.calls.Load())
+	assert.EqualValues(t, 0, other.calls.Load())
+	assert.Equal(t, rpc.BreakerClosed, breakers.State(reverting.URL))
+}
+
+// TestClient_ExpectedErrors tests that errors a caller expects are neither retried nor held
+// against the endpoint.
+func TestClient_ExpectedErrors(t *testing.T) {
+	busy := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeError(w, req, -32603, "query timeout exceeded")
+	})
+	other := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `[]`)
+	})
+
+	breakers := rpc.NewBreakers(rpc.BreakerOptions{FailureThreshold: 1})
+	opts := fastClientOptions()
+	opts.Breakers = breakers
+	client, err := rpc.NewClient(registerMockChain(t, 7864, busy.URL, other.URL), opts)
+	require.NoError(t, err)
+
+	ctx := rpc.WithExpectedErrors(context.Background(), func(err error) bool {
+		return strings.Contains(err.Error(), "query timeout")
+	})
+	err = client.Call(ctx, nil, "eth_getLogs", map[string]string{"fromBlock": "0x0"})
+	assert.ErrorContains(t, err, "query timeout exceeded")
+	assert.EqualValues(t, 0, other.calls.Load())
+	assert.Equal(t, rpc.BreakerClosed, breakers.State(busy.URL))
+
+	// Without the expectation the internal error fails over and trips the breaker.
+	require.NoError(t, client.Call(context.Background(), nil, "eth_getLogs", map[string]string{"fromBlock": "0x0"}))
+	assert.EqualValues(t, 1, other.calls.Load())
+	assert.Equal(t, rpc.BreakerOpen, breakers.State(busy.URL))
+}
+
+// TestClient_NonIdempotent tests that raw transactions are not resent to another node unless opted in.
+func TestClient_NonIdempotent(t *testing.T) {
+	flaky := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	healthy := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `"0xabc"`)
+	})
+
+	chainID := registerMockChain(t, 7782, flaky.URL, healthy.URL)
+	client, err := rpc.NewClient(chainID, fastClientOptions())
+	require.NoError(t, err)
+
+	err = client.Call(context.Background(), nil, "eth_sendRawTransaction", "0x01")
+	assert.ErrorIs(t, err, rpc.ErrNonIdempotent)
+	assert.EqualValues(t, 0, healthy.calls.Load())
+
+	opts := fastClientOptions()
+	opts.Retry.RetryNonIdempotent = true
+	client, err = rpc.NewClient(chainID, opts)
+	require.NoError(t, err)
+
+	var hash string
+	require.NoError(t, client.Call(context.Background(), &hash, "eth_sendRawTransaction", "0x01"))
+	assert.Equal(t, "0xabc", hash)
+}
+
+// TestClient_CallTimeout tests that a hanging endpoint is abandoned after the per-call deadline.
+func TestClient_CallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	hanging := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		<-release
+	})
+	t.Cleanup(func() { close(release) }) // Runs before the server is closed.
+	healthy := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `"0x1"`)
+	})
+
+	chainID := registerMockChain(t, 7783, hanging.URL, healthy.URL)
+	opts := fastClientOptions()
+	opts.CallTimeout = 50 * time.Millisecond
+	client, err := rpc.NewClient(chainID, opts)
+	require.NoError(t, err)
+
+	start := time.Now()
+	var result string
+	require.NoError(t, client.Call(context.Background(), &result, "eth_chainId"))
+	assert.Equal(t, "0x1", result)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// TestClient_Exhausted tests the error returned when every attempt fails.
+func TestClient_Exhausted(t *testing.T) {
+	down := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	chainID := registerMockChain(t, 7784, down.URL)
+	client, err := rpc.NewClient(chainID, fastClientOptions())
+	require.NoError(t, err)
+
+	err = client.Call(context.Background(), nil, "eth_blockNumber")
+	var httpErr *rpc.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusInternalServerError, httpErr.StatusCode)
+	assert.EqualValues(t, 3, down.calls.Load())
+	assert.False(t, errors.Is(err, rpc.ErrNonIdempotent))
+}
+
+// TestClient_Envelopes tests string IDs echoed by proxies and revert data on errors.
+func TestClient_Envelopes(t *testing.T) {
+	node := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		n, _ := req.ID.Int64()
+		id := chainstypes.StringID(strconv.FormatInt(n, 10))
+		if req.Method == "eth_call" {
+			_ = json.NewEncoder(w).Encode(chainstypes.JsonRPCResponse{Version: "2.0", ID: id, Error: &chainstypes.JsonRPCError{
+				Code: 3, Message: "execution reverted", Data: json.RawMessage(`"0xdeadbeef"`),
+			}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(chainstypes.JsonRPCResponse{Version: "2.0", ID: id, Result: json.RawMessage(`"0x10"`)})
+	})
+	client, err := rpc.NewClient(registerMockChain(t, 7794, node.URL), fastClientOptions())
+	require.NoError(t, err)
+
+	var block string
+	require.NoError(t, client.Call(context.Background(), &block, "eth_blockNumber"))
+	assert.Equal(t, "0x10", block)
+
+	err = client.Call(context.Background(), nil, "eth_call", map[string]string{"to": "0x0"}, "latest")
+	var rpcErr *chainstypes.JsonRPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.JSONEq(t, `"0xdeadbeef"`, string(rpcErr.Data))
+}
@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// flightGroup lets concurrent identical calls share one upstream request.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is an upstream request and the callers waiting for it.
+type flight struct {
+	done    chan struct{}
+	result  json.RawMessage
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// do returns the result of fn for key, starting fn unless a call for key is already in flight.
+// fn runs detached from any single caller's context and is cancelled once every waiting caller
+// has given up, so one cancelled caller does not fail the others.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (json.RawMessage, error)) (json.RawMessage, error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	f, ok := g.flights[key]
+	if !ok {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		g.flights[key] = f
+		go func() {
+			f.result, f.err = fn(flightCtx)
+			cancel()
+			g.mu.Lock()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+			g.mu.Unlock()
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// Nobody waits for the result any more: abandon the request so a new caller
+			// starts a fresh one.
+			f.cancel()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,140 @@
+package rpc_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callConcurrently makes n identical calls at once and returns their results.
+func callConcurrently(t testing.TB, ctx context.Context, client *rpc.Client, n int, method string, params ...any) []string {
+	results := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = client.Call(ctx, &results[i], method, params...)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	return results
+}
+
+// TestClient_Coalesce tests that concurrent identical calls share one upstream request.
+func TestClient_Coalesce(t *testing.T) {
+	ctx := context.Background()
+	newClient := func(t *testing.T, id int64, url string, writes bool) *rpc.Client {
+		opts := fastClientOptions()
+		opts.Coalesce = true
+		opts.CoalesceWrites = writes
+		client, err := rpc.NewClient(registerMockChain(t, id, url), opts)
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("reads", func(t *testing.T) {
+		node := newSlowNode(t, 50*time.Millisecond, `"0x10"`)
+		client := newClient(t, 7831, node.URL, false)
+
+		for _, result := range callConcurrently(t, ctx, client, 50, "eth_getBlockByNumber", "0x10", false) {
+			assert.Equal(t, "0x10", result)
+		}
+		assert.Equal(t, int64(1), node.calls.Load())
+
+		// Calls with different params are not shared, and finished calls are not reused.
+		callConcurrently(t, ctx, client, 5, "eth_getBlockByNumber", "0x11", false)
+		callConcurrently(t, ctx, client, 5, "eth_getBlockByNumber", "0x10", false)
+		assert.Equal(t, int64(3), node.calls.Load())
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		node := newSlowNode(t, 50*time.Millisecond, `"0x10"`)
+		client, err := rpc.NewClient(registerMockChain(t, 7857, node.URL), fastClientOptions())
+		require.NoError(t, err)
+		callConcurrently(t, ctx, client, 5, "eth_getBlockByNumber", "0x10", false)
+		assert.Equal(t, int64(5), node.calls.Load())
+	})
+
+	t.Run("writes are excluded", func(t *testing.T) {
+		node := newSlowNode(t, 50*time.Millisecond, `"0xabc"`)
+		client := newClient(t, 7832, node.URL, false)
+		callConcurrently(t, ctx, client, 5, "eth_sendRawTransaction", "0x01")
+		assert.Equal(t, int64(5), node.calls.Load())
+	})
+
+	t.Run("writes included", func(t *testing.T) {
+		node := newSlowNode(t, 50*time.Millisecond, `"0xabc"`)
+		client := newClient(t, 7833, node.URL, true)
+		callConcurrently(t, ctx, client, 5, "eth_sendRawTransaction", "0x01")
+		assert.Equal(t, int64(1), node.calls.Load())
+	})
+
+	t.Run("per-call options are excluded", func(t *testing.T) {
+		node := newSlowNode(t, 50*time.Millisecond, `"0x10"`)
+		client := newClient(t, 7872, node.URL, false)
+		expected := rpc.WithExpectedErrors(ctx, func(err error) bool { return true })
+		callConcurrently(t, expected, client, 5, "eth_getBlockByNumber", "0x10", false)
+		assert.Equal(t, int64(5), node.calls.Load())
+	})
+
+	t.Run("cancelled caller", func(t *testing.T) {
+		node := newSlowNode(t, 100*time.Millisecond, `"0x10"`)
+		client := newClient(t, 7834, node.URL, false)
+
+		cancelled, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		errc := make(chan error, 1)
+		go func() { errc <- client.Call(cancelled, nil, "eth_blockNumber") }()
+		time.Sleep(5 * time.Millisecond)
+
+		var result string
+		require.NoError(t, client.Call(ctx, &result, "eth_blockNumber"))
+		assert.Equal(t, "0x10", result)
+		assert.ErrorIs(t, <-errc, context.DeadlineExceeded)
+		assert.Equal(t, int64(1), node.calls.Load())
+	})
+}
+
+// BenchmarkClient_Coalesce compares upstream requests per call for concurrent identical reads
+// with and without coalescing.
+func BenchmarkClient_Coalesce(b *testing.B) {
+	for _, tc := range []struct {
+		name     string
+		coalesce bool
+		id       int64
+	}{
+		{"off", false, 7835},
+		{"on", true, 7836},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			node := newSlowNode(b, time.Millisecond, `"0x10"`)
+			opts := fastClientOptions()
+			opts.Coalesce = tc.coalesce
+			client, err := rpc.NewClient(registerMockChain(b, tc.id, node.URL), opts)
+			require.NoError(b, err)
+			ctx := context.Background()
+
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := client.Call(ctx, nil, "eth_blockNumber"); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+			b.ReportMetric(float64(node.calls.Load())/float64(b.N), "upstream/op")
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+)
+
+// errBadResponse is wrapped by errors about a response body that could not be read or is not
+// valid JSON-RPC. Like transport failures, they are endpoint problems.
+var errBadResponse = errors.New("bad json-rpc response")
+
+// HTTPError is returned when an endpoint answers with a non-2xx HTTP status.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by a Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Body)
+}
+
+// isRetryable reports whether err is worth retrying, possibly on another endpoint.
+func isRetryable(err error, retryableCodes []int) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500
+	}
+
+	var rpcErr *types.JsonRPCError
+	if errors.As(err, &rpcErr) {
+		limited, _ := rateLimited(rpcErr)
+		return limited || slices.Contains(retryableCodes, rpcErr.Code)
+	}
+
+	// Transport failures, per-attempt deadlines, malformed responses and heads behind the session
+	// are endpoint problems.
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, errBadResponse) || errors.Is(err, ErrBehindSession) {
+		return true
+	}
+
+	// Anything else, such as params that cannot be encoded, fails the same way on every endpoint.
+	return false
+}
+
+type expectedErrorsKey struct{}
+
+// WithExpectedErrors returns a context whose calls take the errors expected reports as the
+// endpoint's answer rather than a failure, e.g. an eth_getLogs range limit the caller handles:
+// they are not retried, back off no rate limit and count as a success for the circuit breakers.
+func WithExpectedErrors(ctx context.Context, expected func(err error) bool) context.Context {
+	return context.WithValue(ctx, expectedErrorsKey{}, expected)
+}
+
+// isExpected reports whether err is expected by the calls of ctx.
+func isExpected(ctx context.Context, err error) bool {
+	expected, _ := ctx.Value(expectedErrorsKey{}).(func(err error) bool)
+	return err != nil && expected != nil && expected(err)
+}
+
+// hasExpectedErrors reports whether ctx was returned by WithExpectedErrors.
+func hasExpectedErrors(ctx context.Context) bool {
+	return ctx.Value(expectedErrorsKey{}) != nil
+}
+
+// isNotDelivered reports whether err proves the request never reached the endpoint's node,
+// which makes it safe to resend even non-idempotent calls.
+func isNotDelivered(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial"
+	}
+	return false
+}
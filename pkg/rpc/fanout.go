@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// EndpointResult is the answer of one endpoint to a call sent to several.
+type EndpointResult struct {
+	URL    string
+	Result json.RawMessage
+	Err    error
+}
+
+// CallAll sends the call to every permitted endpoint in parallel, or to the first n if n > 0,
+// and returns the answer of each endpoint in endpoint order. Endpoints are not retried. It is
+// meant for calls that should reach as many nodes as possible, e.g. broadcasting a transaction.
+func (c *Client) CallAll(ctx context.Context, n int, method string, params ...any) ([]EndpointResult, error) {
+	targets := c.permittedEndpoints(ctx)
+	if len(targets) == 0 {
+		return nil, ErrNoPermittedEndpoint
+	}
+	if n > 0 && n < len(targets) {
+		targets = targets[:n]
+	}
+
+	results := make([]EndpointResult, len(targets))
+	var wg sync.WaitGroup
+	for i, url := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := c.CallEndpoint(ctx, url, method, params...)
+			results[i] = EndpointResult{URL: url, Result: result, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// CallEndpoint performs a JSON-RPC call against one of the client's endpoints, without failover.
+func (c *Client) CallEndpoint(ctx context.Context, url, method string, params ...any) (json.RawMessage, error) {
+	if !slices.Contains(c.endpoints, url) {
+		return nil, fmt.Errorf("%s is not an endpoint of chain %s", url, c.chain.ID)
+	}
+	req := c.newRequest(method, params)
+	var result json.RawMessage
+	err := c.attempt(ctx, url, func(ctx context.Context, url string) error {
+		var err error
+		result, err = callHTTP(ctx, c.clients[url], url, req)
+		return err
+	})
+	if ctx.Err() == nil {
+		c.observe(ctx, url, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	return result, nil
+}
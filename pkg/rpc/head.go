@@ -0,0 +1,106 @@
+package rpc
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+)
+
+// HeadStrategy defines how the consensus head of a chain is derived from endpoint block heights.
+type HeadStrategy int
+
+const (
+	// HeadMax uses the highest block reported by any healthy endpoint.
+	HeadMax HeadStrategy = iota
+	// HeadMedian uses the median block of healthy endpoints, which tolerates a single endpoint running ahead.
+	HeadMedian
+)
+
+// String returns the name of the strategy.
+func (s HeadStrategy) String() string {
+	switch s {
+	case HeadMax:
+		return "max"
+	case HeadMedian:
+		return "median"
+	default:
+		return fmt.Sprintf("HeadStrategy(%d)", int(s))
+	}
+}
+
+// ConsensusHead computes the chain head from the statuses of available, non-syncing endpoints.
+// It returns nil if no endpoint reported a block number.
+func ConsensusHead(statuses []types.RPCStatus, strategy HeadStrategy) *big.Int {
+	var heights []*big.Int
+	for _, s := range statuses {
+		if s.IsAvailable && !s.IsSyncing && s.BlockNumber != nil {
+			heights = append(heights, s.BlockNumber)
+		}
+	}
+	if len(heights) == 0 {
+		return nil
+	}
+
+	sort.Slice(heights, func(i, j int) bool {
+		return heights[i].Cmp(heights[j]) < 0
+	})
+
+	switch strategy {
+	case HeadMedian:
+		return new(big.Int).Set(heights[len(heights)/2])
+	default:
+		return new(big.Int).Set(heights[len(heights)-1])
+	}
+}
+
+// AnnotateHeadLag sets BlockLag on every available status relative to the consensus head and
+// marks endpoints that are lagging, syncing, serving a stale head or failing a follow-up check
+// as degraded.
+// It returns the consensus head, or nil if none could be determined.
+func AnnotateHeadLag(statuses []types.RPCStatus, opts CheckRPCOptions) *big.Int {
+	head := ConsensusHead(statuses, opts.HeadStrategy)
+	now := time.Now()
+
+	for i := range statuses {
+		s := &statuses[i]
+		if !s.IsAvailable {
+			continue
+		}
+
+		var reasons []string
+		if head != nil && s.BlockNumber != nil && s.BlockNumber.Cmp(head) < 0 {
+			lag := new(big.Int).Sub(head, s.BlockNumber)
+			if lag.IsUint64() {
+				s.BlockLag = lag.Uint64()
+			} else {
+				s.BlockLag = ^uint64(0)
+			}
+		} else {
+			s.BlockLag = 0
+		}
+		if opts.MaxBlockLag > 0 && s.BlockLag > opts.MaxBlockLag {
+			reasons = append(reasons, fmt.Sprintf("%d blocks behind head %s", s.BlockLag, head))
+		}
+		if s.IsSyncing {
+			reasons = append(reasons, "node is syncing")
+		}
+		if opts.MaxBlockAge > 0 && !s.BlockTimestamp.IsZero() {
+			if age := now.Sub(s.BlockTimestamp); age > opts.MaxBlockAge {
+				reasons = append(reasons, fmt.Sprintf("latest block is %s old", age.Truncate(time.Second)))
+			}
+		}
+
+		if s.CheckError != nil {
+			reasons = append(reasons, s.CheckError.Error())
+		}
+
+		s.IsDegraded = len(reasons) > 0
+		s.DegradedReason = strings.Join(reasons, "; ")
+	}
+
+	return head
+}
@@ -0,0 +1,198 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HedgeOptions defines when a Hedger sends a second copy of a slow request.
+type HedgeOptions struct {
+	// Delay is how long to wait for the first endpoint before hedging. It is used until
+	// MinSamples latencies of the endpoint are known, or always if Percentile is 0.
+	Delay time.Duration
+	// Percentile (0..1] of the endpoint's observed latencies replaces Delay once enough
+	// samples are known, e.g. 0.95 hedges the slowest 5% of requests.
+	Percentile float64
+	// MinDelay is the lower bound of a derived delay, so a fast endpoint is not hedged on jitter.
+	MinDelay time.Duration
+	// MinSamples is the number of observed latencies needed before Percentile applies.
+	MinSamples int
+	// Window is the number of recent latencies kept per endpoint.
+	Window int
+	// Budget is the fraction of requests that may be hedged, e.g. 0.1 allows at most 10%
+	// extra traffic. Every request earns Budget tokens and every hedge spends one.
+	Budget float64
+	// Burst caps the saved up tokens, i.e. the number of hedges allowed in a row.
+	Burst float64
+}
+
+// DefaultHedgeOptions returns options that hedge after the endpoint's p95 latency (500ms until
+// 20 latencies are known, at least 20ms) and spend at most 10% extra requests.
+func DefaultHedgeOptions() HedgeOptions {
+	return HedgeOptions{
+		Delay:      500 * time.Millisecond,
+		Percentile: 0.95,
+		MinDelay:   20 * time.Millisecond,
+		MinSamples: 20,
+		Window:     100,
+		Budget:     0.1,
+		Burst:      10,
+	}
+}
+
+// HedgeStats counts the requests seen by a Hedger.
+type HedgeStats struct {
+	// Requests is the number of hedgeable requests.
+	Requests int64
+	// Hedged is the number of requests sent to a second endpoint.
+	Hedged int64
+	// Won is the number of hedged requests answered first by the second endpoint.
+	Won int64
+	// OverBudget is the number of slow requests that were not hedged for lack of budget.
+	OverBudget int64
+}
+
+// Hedger decides when read-only calls are hedged and keeps the latencies and budget this is
+// based on. Set it in ClientOptions.Hedger; it may be shared by several clients.
+type Hedger struct {
+	opts HedgeOptions
+
+	mu        sync.Mutex
+	latencies map[string][]time.Duration
+	tokens    float64
+	stats     HedgeStats
+}
+
+// NewHedger creates a Hedger.
+func NewHedger(opts HedgeOptions) *Hedger {
+	if opts.Window <= 0 {
+		opts.Window = 1
+	}
+	if opts.Burst < 1 {
+		opts.Burst = 1
+	}
+	return &Hedger{
+		opts:      opts,
+		latencies: make(map[string][]time.Duration),
+		tokens:    opts.Burst,
+	}
+}
+
+// Delay returns how long a request to url is given before it is hedged.
+func (h *Hedger) Delay(url string) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	samples := h.latencies[url]
+	if h.opts.Percentile <= 0 || len(samples) == 0 || len(samples) < h.opts.MinSamples {
+		return h.opts.Delay
+	}
+	return max(percentile(samples, h.opts.Percentile), h.opts.MinDelay)
+}
+
+// Stats returns the counters since the Hedger was created.
+func (h *Hedger) Stats() HedgeStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats
+}
+
+// begin counts a hedgeable request and earns its share of the budget.
+func (h *Hedger) begin() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats.Requests++
+	h.tokens = min(h.tokens+h.opts.Budget, h.opts.Burst)
+}
+
+// allow spends a token for a hedge and reports whether one was available.
+func (h *Hedger) allow() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tokens < 1 {
+		h.stats.OverBudget++
+		return false
+	}
+	h.tokens--
+	h.stats.Hedged++
+	return true
+}
+
+// record adds the latency of a request and counts a hedge that won.
+func (h *Hedger) record(url string, latency time.Duration, won bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.latencies[url] = appendWindow(h.latencies[url], latency, h.opts.Window)
+	if won {
+		h.stats.Won++
+	}
+}
+
+// hedgedAttempt runs send against the endpoint at index endpoint and, if it has not answered
+// within the hedging delay, also against the next permitted endpoint. The first success wins
+// and cancels the other request; send must therefore be safe for concurrent use. The outcome of
+// every request that was not cancelled is observed. A first request beaten by the hedge is
+// sampled with the time it ran, a lower bound of its latency, so the delay is not derived from
+// fast answers only. If both fail, the first endpoint's error is returned.
+func (c *Client) hedgedAttempt(ctx context.Context, endpoint int, send func(ctx context.Context, url string) error) error {
+	h := c.opts.Hedger
+	h.begin()
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		url   string
+		err   error
+		hedge bool
+	}
+	outcomes := make(chan outcome, 2)
+	run := func(url string, hedge bool) {
+		start := time.Now()
+		err := c.attempt(ctx, url, send)
+		if ctx.Err() == nil {
+			c.observe(ctx, url, err)
+			if err == nil {
+				h.record(url, time.Since(start), hedge)
+			}
+		} else if !hedge && parent.Err() == nil {
+			h.record(url, time.Since(start), false)
+		}
+		outcomes <- outcome{url: url, err: err, hedge: hedge}
+	}
+
+	primary := c.endpoints[endpoint]
+	go run(primary, false)
+
+	timer := time.NewTimer(h.Delay(primary))
+	defer timer.Stop()
+
+	running := 1
+	var primaryErr error
+	for running > 0 {
+		select {
+		case <-timer.C:
+			// Hedge to the next permitted endpoint other than the first.
+			next, ok := c.nextPermitted(ctx, endpoint+1)
+			if !ok || c.endpoints[next] == primary || !h.allow() {
+				continue
+			}
+			running++
+			go run(c.endpoints[next], true)
+		case o := <-outcomes:
+			running--
+			if o.err == nil {
+				return nil
+			}
+			if !o.hedge {
+				primaryErr = o.err
+				// A failed first request is failed over by the caller rather than hedged.
+				if running == 0 {
+					return primaryErr
+				}
+			}
+		}
+	}
+	return primaryErr
+}
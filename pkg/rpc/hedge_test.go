@@ -0,0 +1,158 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowNode is an endpoint that answers after delay unless the request is cancelled first.
+type slowNode struct {
+	URL       string
+	calls     atomic.Int64
+	cancelled atomic.Int64
+}
+
+func newSlowNode(t testing.TB, delay time.Duration, result string) *slowNode {
+	t.Helper()
+	node := &slowNode{}
+	server := setupHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		node.calls.Add(1)
+		var req chainstypes.JsonRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			node.cancelled.Add(1)
+		case <-time.After(delay):
+			writeResult(w, req, result)
+		}
+	})
+	node.URL = server.URL
+	return node
+}
+
+// TestClient_Hedge tests hedging of slow calls, cancellation of the loser and the budget cap.
+func TestClient_Hedge(t *testing.T) {
+	ctx := context.Background()
+	fixedDelay := func() rpc.HedgeOptions {
+		opts := rpc.DefaultHedgeOptions()
+		opts.Delay = 20 * time.Millisecond
+		opts.Percentile = 0
+		return opts
+	}
+	newClient := func(t *testing.T, id int64, hedger *rpc.Hedger, urls ...string) *rpc.Client {
+		opts := fastClientOptions()
+		opts.CallTimeout = 5 * time.Second
+		opts.Hedger = hedger
+		client, err := rpc.NewClient(registerMockChain(t, id, urls...), opts)
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("slow endpoint is hedged", func(t *testing.T) {
+		slow := newSlowNode(t, 3*time.Second, `"0x1"`)
+		fast := newSlowNode(t, 0, `"0x2"`)
+		hedger := rpc.NewHedger(fixedDelay())
+		client := newClient(t, 7825, hedger, slow.URL, fast.URL)
+
+		start := time.Now()
+		var result string
+		require.NoError(t, client.Call(ctx, &result, "eth_blockNumber"))
+		assert.Equal(t, "0x2", result)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, rpc.HedgeStats{Requests: 1, Hedged: 1, Won: 1}, hedger.Stats())
+		assert.Eventually(t, func() bool { return slow.cancelled.Load() == 1 }, time.Second, 5*time.Millisecond,
+			"the losing request should be cancelled")
+	})
+
+	t.Run("fast endpoint is not hedged", func(t *testing.T) {
+		first := newSlowNode(t, 0, `"0x1"`)
+		second := newSlowNode(t, 0, `"0x2"`)
+		hedger := rpc.NewHedger(fixedDelay())
+		client := newClient(t, 7826, hedger, first.URL, second.URL)
+
+		var result string
+		require.NoError(t, client.Call(ctx, &result, "eth_blockNumber"))
+		assert.Equal(t, "0x1", result)
+		assert.Equal(t, int64(0), second.calls.Load())
+		assert.Equal(t, rpc.HedgeStats{Requests: 1}, hedger.Stats())
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		slow := newSlowNode(t, 100*time.Millisecond, `"0x1"`)
+		fast := newSlowNode(t, 0, `"0x2"`)
+		opts := fixedDelay()
+		opts.Budget = 0.5
+		opts.Burst = 1
+		hedger := rpc.NewHedger(opts)
+		client := newClient(t, 7827, hedger, slow.URL, fast.URL)
+
+		// The first hedge spends the burst; the next two calls earn the token for the fourth.
+		want := []string{"0x2", "0x1", "0x2", "0x1"}
+		for i, w := range want {
+			var result string
+			require.NoError(t, client.Call(ctx, &result, "eth_blockNumber"))
+			assert.Equal(t, w, result, "call %d", i)
+		}
+		assert.Equal(t, rpc.HedgeStats{Requests: 4, Hedged: 2, Won: 2, OverBudget: 2}, hedger.Stats())
+		assert.Equal(t, int64(2), fast.calls.Load())
+	})
+
+	t.Run("non-idempotent calls are not hedged", func(t *testing.T) {
+		slow := newSlowNode(t, 100*time.Millisecond, `"0xabc"`)
+		fast := newSlowNode(t, 0, `"0xdef"`)
+		hedger := rpc.NewHedger(fixedDelay())
+		client := newClient(t, 7828, hedger, slow.URL, fast.URL)
+
+		var hash string
+		require.NoError(t, client.Call(ctx, &hash, "eth_sendRawTransaction", "0x01"))
+		assert.Equal(t, "0xabc", hash)
+		assert.Equal(t, int64(0), fast.calls.Load())
+		assert.Equal(t, rpc.HedgeStats{}, hedger.Stats())
+	})
+
+	t.Run("delay from observed latency", func(t *testing.T) {
+		node := newSlowNode(t, 0, `"0x1"`)
+		opts := rpc.DefaultHedgeOptions()
+		opts.MinSamples = 5
+		opts.MinDelay = time.Millisecond
+		hedger := rpc.NewHedger(opts)
+		client := newClient(t, 7829, hedger, node.URL)
+
+		assert.Equal(t, opts.Delay, hedger.Delay(node.URL))
+		for range opts.MinSamples {
+			require.NoError(t, client.Call(ctx, nil, "eth_blockNumber"))
+		}
+		delay := hedger.Delay(node.URL)
+		assert.GreaterOrEqual(t, delay, opts.MinDelay)
+		assert.Less(t, delay, opts.Delay)
+	})
+
+	t.Run("beaten requests are sampled", func(t *testing.T) {
+		slow := newSlowNode(t, 3*time.Second, `"0x1"`)
+		fast := newSlowNode(t, 0, `"0x2"`)
+		opts := fixedDelay()
+		opts.Percentile = 0.95
+		opts.MinSamples = 1
+		opts.MinDelay = time.Millisecond
+		hedger := rpc.NewHedger(opts)
+		client := newClient(t, 7873, hedger, slow.URL, fast.URL)
+
+		require.NoError(t, client.Call(ctx, nil, "eth_blockNumber"))
+		assert.Equal(t, int64(1), hedger.Stats().Won)
+		// The slow endpoint ran past the hedging delay before it was cancelled.
+		assert.Eventually(t, func() bool { return hedger.Delay(slow.URL) > opts.Delay }, time.Second, 5*time.Millisecond)
+	})
+}
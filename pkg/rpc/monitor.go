@@ -0,0 +1,293 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/registry"
+)
+
+// ErrMonitorStarted is returned by Run when the Monitor already ran, since its Changes channel is
+// closed when the first Run returns.
+var ErrMonitorStarted = errors.New("monitor already started")
+
+// Clock abstracts time so the Monitor can be driven deterministically in tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// MonitorOptions defines parameters for the background RPC health monitor.
+type MonitorOptions struct {
+	// Interval is the base delay between two probe rounds.
+	Interval time.Duration
+	// Jitter is the maximum random delay added to each Interval.
+	Jitter time.Duration
+	// Check configures the probes run each round.
+	Check CheckRPCOptions
+	// Window is the number of recent probes used for percentiles and the success ratio.
+	Window int
+	// EWMAAlpha is the smoothing factor (0, 1] of the latency moving average.
+	EWMAAlpha float64
+	// EventBuffer is the capacity of the change-notification channel.
+	EventBuffer int
+	// Clock is used for scheduling and timestamps (defaults to the system clock).
+	Clock Clock
+}
+
+// DefaultMonitorOptions returns default options for a Monitor.
+func DefaultMonitorOptions() MonitorOptions {
+	return MonitorOptions{
+		Interval:    30 * time.Second,
+		Jitter:      5 * time.Second,
+		Check:       DefaultCheckOptions(),
+		Window:      20,
+		EWMAAlpha:   0.3,
+		EventBuffer: 64,
+		Clock:       systemClock{},
+	}
+}
+
+// MonitorEvent is emitted when an endpoint is first seen or its health changes.
+type MonitorEvent struct {
+	Previous types.EndpointStats
+	Current  types.EndpointStats
+}
+
+// Monitor periodically probes the RPC endpoints of a set of chains and keeps rolling statistics.
+type Monitor struct {
+	identifiers []any
+	opts        MonitorOptions
+	rand        *rand.Rand
+	started     atomic.Bool
+
+	mu        sync.RWMutex
+	endpoints map[string]*endpointState
+	events    chan MonitorEvent
+}
+
+// endpointState is the mutable rolling state behind an EndpointStats snapshot.
+type endpointState struct {
+	stats     types.EndpointStats
+	latencies []time.Duration
+	outcomes  []bool
+}
+
+// NewMonitor creates a Monitor for the chains identified by ID or name.
+func NewMonitor(identifiers []any, opts MonitorOptions) (*Monitor, error) {
+	if len(identifiers) == 0 {
+		return nil, fmt.Errorf("monitor requires at least one chain")
+	}
+	for _, identifier := range identifiers {
+		if _, err := registry.FindChain(identifier); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Interval <= 0 {
+		return nil, fmt.Errorf("monitor interval must be positive, got %s", opts.Interval)
+	}
+	if opts.Window <= 0 {
+		opts.Window = 1
+	}
+	if opts.EWMAAlpha <= 0 || opts.EWMAAlpha > 1 {
+		opts.EWMAAlpha = 1
+	}
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+
+	return &Monitor{
+		identifiers: identifiers,
+		opts:        opts,
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		endpoints:   make(map[string]*endpointState),
+		events:      make(chan MonitorEvent, opts.EventBuffer),
+	}, nil
+}
+
+// Changes returns the channel on which health changes are published.
+// Events are dropped if the channel is full, and it is closed when Run returns.
+func (m *Monitor) Changes() <-chan MonitorEvent {
+	return m.events
+}
+
+// Run probes all endpoints immediately and then every Interval plus jitter until ctx is done.
+// A Monitor runs once: later calls return ErrMonitorStarted.
+func (m *Monitor) Run(ctx context.Context) error {
+	if !m.started.CompareAndSwap(false, true) {
+		return ErrMonitorStarted
+	}
+	defer close(m.events)
+
+	for {
+		m.probe(ctx)
+
+		delay := m.opts.Interval
+		if m.opts.Jitter > 0 {
+			delay += time.Duration(m.rand.Int63n(int64(m.opts.Jitter) + 1))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.opts.Clock.After(delay):
+		}
+	}
+}
+
+// Snapshot returns the current statistics of all known endpoints, ordered by chain ID and URL.
+func (m *Monitor) Snapshot() []types.EndpointStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make([]types.EndpointStats, 0, len(m.endpoints))
+	for _, state := range m.endpoints {
+		snapshot = append(snapshot, state.stats)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if c := snapshot[i].ChainID.Cmp(snapshot[j].ChainID); c != 0 {
+			return c < 0
+		}
+		return snapshot[i].URL < snapshot[j].URL
+	})
+	return snapshot
+}
+
+// Statuses returns the last probe result of every endpoint of a chain, suitable for the selector.
+func (m *Monitor) Statuses(chainID *big.Int) []types.RPCStatus {
+	var statuses []types.RPCStatus
+	for _, s := range m.Snapshot() {
+		if s.ChainID.Cmp(chainID) == 0 {
+			statuses = append(statuses, s.LastStatus)
+		}
+	}
+	return statuses
+}
+
+// probe runs one round of checks against every chain.
+func (m *Monitor) probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, identifier := range m.identifiers {
+		chain, err := registry.FindChain(identifier)
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(chainID *big.Int) {
+			defer wg.Done()
+			statuses, _ := CheckRPCs(ctx, chainID, m.opts.Check)
+			if ctx.Err() != nil {
+				return
+			}
+			m.record(chainID, statuses)
+		}(chain.ID)
+	}
+	wg.Wait()
+}
+
+// record folds a round of statuses into the rolling statistics and publishes changes.
+func (m *Monitor) record(chainID *big.Int, statuses []types.RPCStatus) {
+	now := m.opts.Clock.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, status := range statuses {
+		key := chainID.String() + "|" + status.URL
+		state, seen := m.endpoints[key]
+		if !seen {
+			state = &endpointState{stats: types.EndpointStats{
+				ChainID:     new(big.Int).Set(chainID),
+				URL:         status.URL,
+				IsHTTP:      status.IsHTTP,
+				IsWebSocket: status.IsWebSocket,
+			}}
+			m.endpoints[key] = state
+		}
+		previous := state.stats
+		state.observe(status, now, m.opts.Window, m.opts.EWMAAlpha)
+
+		if !seen || previous.IsHealthy() != state.stats.IsHealthy() {
+			select {
+			case m.events <- MonitorEvent{Previous: previous, Current: state.stats}:
+			default:
+			}
+		}
+	}
+}
+
+// observe applies a single probe result to the endpoint state.
+func (s *endpointState) observe(status types.RPCStatus, now time.Time, window int, alpha float64) {
+	s.stats.LastStatus = status
+	s.stats.LastChecked = now
+
+	s.outcomes = appendWindow(s.outcomes, status.IsAvailable, window)
+	if status.IsAvailable {
+		s.stats.ConsecutiveFailures = 0
+		s.stats.LastSuccess = now
+		if status.BlockNumber != nil {
+			s.stats.LastSeenBlock = new(big.Int).Set(status.BlockNumber)
+		}
+		if s.stats.LatencyEWMA == 0 {
+			s.stats.LatencyEWMA = status.Latency
+		} else {
+			s.stats.LatencyEWMA = time.Duration(alpha*float64(status.Latency) + (1-alpha)*float64(s.stats.LatencyEWMA))
+		}
+		s.latencies = appendWindow(s.latencies, status.Latency, window)
+	} else {
+		s.stats.ConsecutiveFailures++
+	}
+
+	var successes int
+	for _, ok := range s.outcomes {
+		if ok {
+			successes++
+		}
+	}
+	s.stats.Samples = len(s.outcomes)
+	s.stats.SuccessRatio = float64(successes) / float64(len(s.outcomes))
+	s.stats.LatencyP50 = percentile(s.latencies, 0.50)
+	s.stats.LatencyP95 = percentile(s.latencies, 0.95)
+}
+
+// appendWindow appends v and drops the oldest elements beyond size.
+func appendWindow[T any](window []T, v T, size int) []T {
+	window = append(window, v)
+	if len(window) > size {
+		window = window[len(window)-size:]
+	}
+	return window
+}
+
+// percentile returns the nearest-rank percentile p (0..1] of the samples.
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
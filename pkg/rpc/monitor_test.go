@@ -0,0 +1,171 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced rpc.Clock.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1_700_000_000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward and fires all expired timers.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if !w.at.After(c.now) {
+			w.ch <- c.now
+		} else {
+			pending = append(pending, w)
+		}
+	}
+	c.waiters = pending
+}
+
+// waitForTimer blocks until at least one timer is pending.
+func (c *fakeClock) waitForTimer(t *testing.T) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.waiters) > 0
+	}, 2*time.Second, time.Millisecond)
+}
+
+// TestMonitor_RollingStats drives the monitor with a fake clock against a flapping endpoint.
+func TestMonitor_RollingStats(t *testing.T) {
+	var failing atomic.Bool
+	var block atomic.Int64
+	block.Store(100)
+	server := setupHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var req chainstypes.JsonRPCRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		result, _ := json.Marshal("0x" + big.NewInt(block.Load()).Text(16))
+		_ = json.NewEncoder(w).Encode(chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID, Result: result})
+	})
+
+	chainID := big.NewInt(7779)
+	registry.RegisterChain(chainstypes.Chain{
+		ID:      chainID,
+		Name:    "Mock Monitor Chain",
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {Http: []string{server.URL}}},
+	})
+
+	clock := newFakeClock()
+	opts := rpc.DefaultMonitorOptions()
+	opts.Interval = time.Minute
+	opts.Jitter = 0
+	opts.Window = 4
+	opts.Clock = clock
+	opts.Check.TimeoutPerCheck = 2 * time.Second
+
+	monitor, err := rpc.NewMonitor([]any{chainID}, opts)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- monitor.Run(ctx) }()
+
+	// First round: endpoint is discovered healthy.
+	ev := <-monitor.Changes()
+	assert.Equal(t, server.URL, ev.Current.URL)
+	assert.True(t, ev.Current.IsHealthy())
+	assert.Equal(t, 0, ev.Current.LastSeenBlock.Cmp(big.NewInt(100)))
+
+	// Second round: endpoint fails.
+	failing.Store(true)
+	clock.waitForTimer(t)
+	clock.Advance(time.Minute)
+	ev = <-monitor.Changes()
+	assert.False(t, ev.Current.IsHealthy())
+	assert.True(t, ev.Previous.IsHealthy())
+	assert.Equal(t, 1, ev.Current.ConsecutiveFailures)
+
+	// Third round: endpoint recovers with a new head.
+	failing.Store(false)
+	block.Store(105)
+	clock.waitForTimer(t)
+	clock.Advance(time.Minute)
+	ev = <-monitor.Changes()
+	assert.True(t, ev.Current.IsHealthy())
+
+	snapshot := monitor.Snapshot()
+	require.Len(t, snapshot, 1)
+	stats := snapshot[0]
+	assert.Equal(t, 3, stats.Samples)
+	assert.InDelta(t, 2.0/3.0, stats.SuccessRatio, 1e-9)
+	assert.Equal(t, 0, stats.ConsecutiveFailures)
+	assert.Equal(t, 0, stats.LastSeenBlock.Cmp(big.NewInt(105)))
+	assert.Equal(t, clock.Now(), stats.LastChecked)
+	assert.Positive(t, stats.LatencyEWMA)
+	assert.LessOrEqual(t, stats.LatencyP50, stats.LatencyP95)
+	assert.Len(t, monitor.Statuses(chainID), 1)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	_, open := <-monitor.Changes()
+	assert.False(t, open, "changes channel should be closed after Run returns")
+
+	// A second run, concurrent or after a stop, is rejected instead of closing the channel again.
+	assert.ErrorIs(t, monitor.Run(context.Background()), rpc.ErrMonitorStarted)
+}
+
+// TestNewMonitor_Validation tests option and chain validation.
+func TestNewMonitor_Validation(t *testing.T) {
+	_, err := rpc.NewMonitor(nil, rpc.DefaultMonitorOptions())
+	assert.Error(t, err)
+
+	_, err = rpc.NewMonitor([]any{big.NewInt(424242)}, rpc.DefaultMonitorOptions())
+	assert.ErrorIs(t, err, registry.ErrChainNotFound)
+
+	opts := rpc.DefaultMonitorOptions()
+	opts.Interval = 0
+	_, err = rpc.NewMonitor([]any{big.NewInt(7779)}, opts)
+	assert.Error(t, err)
+}
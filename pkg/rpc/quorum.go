@@ -0,0 +1,261 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/selector"
+)
+
+// ErrNoQuorum is wrapped by every *QuorumError.
+var ErrNoQuorum = errors.New("no quorum")
+
+// QuorumPolicy defines how many endpoints must agree on a result.
+type QuorumPolicy int
+
+const (
+	// QuorumMajority requires more than half of the queried endpoints to agree.
+	QuorumMajority QuorumPolicy = iota
+	// QuorumUnanimous requires every queried endpoint to agree.
+	QuorumUnanimous
+	// QuorumThreshold requires QuorumOptions.Threshold endpoints to agree.
+	QuorumThreshold
+)
+
+// QuorumOptions defines parameters for quorum calls.
+type QuorumOptions struct {
+	// Policy selects how many agreeing results are required.
+	Policy QuorumPolicy
+	// Endpoints is the number of endpoints queried (0 queries every permitted endpoint).
+	Endpoints int
+	// Threshold is the number of agreeing results QuorumThreshold requires.
+	Threshold int
+	// Normalize maps a result to the key results are compared by. The default compares
+	// canonical JSON with lowercased hex strings; a custom function can e.g. drop fields that
+	// legitimately differ between node implementations.
+	Normalize func(result json.RawMessage) (string, error)
+}
+
+// DefaultQuorumOptions returns options that query three endpoints and require a majority.
+func DefaultQuorumOptions() QuorumOptions {
+	return QuorumOptions{Policy: QuorumMajority, Endpoints: 3}
+}
+
+// required returns the number of agreeing results needed out of n queried endpoints.
+func (o QuorumOptions) required(n int) int {
+	switch o.Policy {
+	case QuorumUnanimous:
+		return n
+	case QuorumThreshold:
+		return max(o.Threshold, 1)
+	}
+	return n/2 + 1
+}
+
+// QuorumError reports that the queried endpoints did not reach the required agreement.
+type QuorumError struct {
+	Method string
+	// Required and Agreeing are the needed and the largest number of agreeing endpoints.
+	Required int
+	Agreeing int
+	// Dissenters are the endpoints whose result differs from the largest agreeing group.
+	Dissenters []string
+	// Failed holds the error of each endpoint that returned no comparable result.
+	Failed map[string]error
+}
+
+func (e *QuorumError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %v: %d of %d required endpoints agree", e.Method, ErrNoQuorum, e.Agreeing, e.Required)
+	if len(e.Dissenters) > 0 {
+		fmt.Fprintf(&b, "; dissenting: %s", strings.Join(e.Dissenters, ", "))
+	}
+	if len(e.Failed) > 0 {
+		urls := make([]string, 0, len(e.Failed))
+		for url := range e.Failed {
+			urls = append(urls, url)
+		}
+		sort.Strings(urls)
+		for i, url := range urls {
+			urls[i] = fmt.Sprintf("%s (%v)", url, e.Failed[url])
+		}
+		fmt.Fprintf(&b, "; failed: %s", strings.Join(urls, ", "))
+	}
+	return b.String()
+}
+
+func (e *QuorumError) Unwrap() error {
+	return ErrNoQuorum
+}
+
+// QuorumCall sends the call to several endpoints and decodes the result they agree on into
+// result, which may be nil. See QuorumCallRaw.
+func (c *Client) QuorumCall(ctx context.Context, opts QuorumOptions, result any, method string, params ...any) error {
+	raw, err := c.QuorumCallRaw(ctx, opts, method, params...)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, result); err != nil {
+		return fmt.Errorf("failed to unmarshal %s result (%s): %w", method, string(raw), err)
+	}
+	return nil
+}
+
+// QuorumCallRaw sends the call to opts.Endpoints permitted endpoints in parallel and returns
+// the result once enough of them agree, cancelling the outstanding requests. Endpoints that
+// answer with the same non-retryable JSON-RPC error, such as a revert with the same code,
+// message and data, agree on that error, which is then returned. Transport failures and retryable errors do not count as
+// answers. Without agreement the error is a *QuorumError, reported once every queried endpoint
+// answered so that all dissenters are named.
+func (c *Client) QuorumCallRaw(ctx context.Context, opts QuorumOptions, method string, params ...any) (json.RawMessage, error) {
+	targets := c.permittedEndpoints(ctx)
+	if len(targets) == 0 {
+		return nil, ErrNoPermittedEndpoint
+	}
+	if opts.Endpoints > 0 && opts.Endpoints < len(targets) {
+		targets = targets[:opts.Endpoints]
+	}
+	required := opts.required(len(targets))
+	if required > len(targets) {
+		return nil, &QuorumError{Method: method, Required: required}
+	}
+	normalize := opts.Normalize
+	if normalize == nil {
+		normalize = canonicalResult
+	}
+
+	type reply struct {
+		url    string
+		result json.RawMessage
+		err    error
+		key    string
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req := c.newRequest(method, params)
+	replies := make(chan reply, len(targets))
+	for _, url := range targets {
+		go func() {
+			var result json.RawMessage
+			err := c.attempt(ctx, url, func(ctx context.Context, url string) error {
+				var err error
+				result, err = callHTTP(ctx, c.clients[url], url, req)
+				return err
+			})
+			if ctx.Err() == nil {
+				c.observe(ctx, url, err)
+			}
+			r := reply{url: url, result: result, err: err}
+			var rpcErr *types.JsonRPCError
+			switch {
+			case err == nil:
+				if r.key, err = normalize(result); err != nil {
+					r.err = fmt.Errorf("failed to normalize result: %w", err)
+				}
+			case errors.As(err, &rpcErr) && !isRetryable(err, c.opts.Retry.RetryableCodes):
+				r.key = errorKey(rpcErr)
+			}
+			replies <- r
+		}()
+	}
+
+	groups := make(map[string][]reply)
+	keys := make(map[string]string)
+	var largest string
+	failed := make(map[string]error)
+	for range targets {
+		r := <-replies
+		if r.key == "" {
+			failed[r.url] = r.err
+		} else {
+			keys[r.url] = r.key
+			groups[r.key] = append(groups[r.key], r)
+			if len(groups[r.key]) > len(groups[largest]) {
+				largest = r.key
+			}
+		}
+
+		if agreed := groups[largest]; len(agreed) >= required {
+			if agreed[0].err != nil {
+				return nil, agreed[0].err
+			}
+			return agreed[0].result, nil
+		}
+	}
+
+	qerr := &QuorumError{Method: method, Required: required, Agreeing: len(groups[largest])}
+	for _, url := range targets {
+		if key, ok := keys[url]; ok && key != largest {
+			qerr.Dissenters = append(qerr.Dissenters, url)
+		}
+	}
+	if len(failed) > 0 {
+		qerr.Failed = failed
+	}
+	return nil, qerr
+}
+
+// permittedEndpoints returns the endpoints every gate currently permits for a call with ctx,
+// starting with the preferred one.
+func (c *Client) permittedEndpoints(ctx context.Context) []string {
+	gates := c.gatesFor(ctx)
+	start := int(c.preferred.Load())
+	var urls []string
+	for i := range c.endpoints {
+		url := c.endpoints[(start+i)%len(c.endpoints)]
+		if selector.Permitted(url, gates) {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// errorKey is the key an error answer is compared by: its code, message and normalized data, so
+// that different reverts disagree.
+func errorKey(e *types.JsonRPCError) string {
+	data := string(e.Data)
+	if canonical, err := canonicalResult(e.Data); err == nil {
+		data = canonical
+	}
+	return fmt.Sprintf("error %d %q %s", e.Code, e.Message, data)
+}
+
+// canonicalResult is the default quorum normalization: JSON with sorted object keys,
+// normalized numbers and lowercased hex strings, so checksummed and lowercase addresses agree.
+func canonicalResult(result json.RawMessage) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(result))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+	canonical, err := json.Marshal(lowerHex(v))
+	return string(canonical), err
+}
+
+func lowerHex(v any) any {
+	switch v := v.(type) {
+	case string:
+		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+			return strings.ToLower(v)
+		}
+	case []any:
+		for i := range v {
+			v[i] = lowerHex(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = lowerHex(v[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,140 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClient_Quorum tests the quorum policies, result normalization and conflict reporting.
+func TestClient_Quorum(t *testing.T) {
+	answer := func(result string) *mockNode {
+		return newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+			writeResult(w, req, result)
+		})
+	}
+	a := answer(`{"hash":"0xABCD","number":"0x10"}`)
+	b := answer(`{ "number": "0x10", "hash": "0xabcd" }`)
+	wrong := answer(`{"hash":"0x1234","number":"0x10"}`)
+	newDown := func() *mockNode {
+		return newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+	}
+	ctx := context.Background()
+
+	newClient := func(t *testing.T, id int64, nodes ...*mockNode) *rpc.Client {
+		urls := make([]string, len(nodes))
+		for i, n := range nodes {
+			urls[i] = n.URL
+		}
+		client, err := rpc.NewClient(registerMockChain(t, id, urls...), fastClientOptions())
+		require.NoError(t, err)
+		return client
+	}
+	var block struct {
+		Hash string `json:"hash"`
+	}
+
+	t.Run("majority", func(t *testing.T) {
+		client := newClient(t, 7819, a, wrong, b)
+		require.NoError(t, client.QuorumCall(ctx, rpc.DefaultQuorumOptions(), &block, "eth_getBlockByNumber", "finalized", false))
+		assert.Equal(t, "0xabcd", strings.ToLower(block.Hash))
+	})
+
+	t.Run("unanimous", func(t *testing.T) {
+		client := newClient(t, 7820, a, wrong, b)
+		opts := rpc.QuorumOptions{Policy: rpc.QuorumUnanimous}
+		err := client.QuorumCall(ctx, opts, &block, "eth_getBlockByNumber", "finalized", false)
+		require.ErrorIs(t, err, rpc.ErrNoQuorum)
+		var qerr *rpc.QuorumError
+		require.ErrorAs(t, err, &qerr)
+		assert.Equal(t, []string{wrong.URL}, qerr.Dissenters)
+		assert.Equal(t, 3, qerr.Required)
+		assert.Equal(t, 2, qerr.Agreeing)
+		assert.Contains(t, err.Error(), wrong.URL)
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		down := newDown()
+		client := newClient(t, 7821, down, a, b)
+		opts := rpc.QuorumOptions{Policy: rpc.QuorumThreshold, Threshold: 2}
+		require.NoError(t, client.QuorumCall(ctx, opts, nil, "eth_getBlockByNumber", "finalized", false))
+
+		opts.Threshold = 3
+		err := client.QuorumCall(ctx, opts, nil, "eth_getBlockByNumber", "finalized", false)
+		var qerr *rpc.QuorumError
+		require.ErrorAs(t, err, &qerr)
+		assert.Empty(t, qerr.Dissenters)
+		assert.Contains(t, qerr.Failed, down.URL)
+	})
+
+	t.Run("subset", func(t *testing.T) {
+		down := newDown()
+		client := newClient(t, 7822, a, b, wrong, down)
+		opts := rpc.QuorumOptions{Policy: rpc.QuorumUnanimous, Endpoints: 2}
+		require.NoError(t, client.QuorumCall(ctx, opts, nil, "eth_getBlockByNumber", "finalized", false))
+		assert.Zero(t, down.calls.Load())
+	})
+
+	t.Run("agreed error", func(t *testing.T) {
+		revert := func() *mockNode {
+			return newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+				writeError(w, req, 3, "execution reverted")
+			})
+		}
+		client := newClient(t, 7823, revert(), revert(), a)
+		_, err := client.QuorumCallRaw(ctx, rpc.DefaultQuorumOptions(), "eth_call")
+		var rpcErr *chainstypes.JsonRPCError
+		require.ErrorAs(t, err, &rpcErr)
+		assert.Equal(t, 3, rpcErr.Code)
+		assert.False(t, errors.Is(err, rpc.ErrNoQuorum))
+	})
+
+	t.Run("different errors", func(t *testing.T) {
+		revert := func(data string) *mockNode {
+			return newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+				_ = json.NewEncoder(w).Encode(chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID, Error: &chainstypes.JsonRPCError{
+					Code: 3, Message: "execution reverted", Data: json.RawMessage(data),
+				}})
+			})
+		}
+		insufficient, unauthorized := revert(`"0x08c379a0aa"`), revert(`"0x08c379a0bb"`)
+		client := newClient(t, 7870, insufficient, unauthorized, a)
+		_, err := client.QuorumCallRaw(ctx, rpc.DefaultQuorumOptions(), "eth_call")
+		require.ErrorIs(t, err, rpc.ErrNoQuorum)
+		var qerr *rpc.QuorumError
+		require.ErrorAs(t, err, &qerr)
+		assert.Equal(t, 1, qerr.Agreeing)
+
+		// The same revert data in another case still agrees.
+		client = newClient(t, 7871, insufficient, revert(`"0x08C379A0AA"`), a)
+		_, err = client.QuorumCallRaw(ctx, rpc.DefaultQuorumOptions(), "eth_call")
+		var rpcErr *chainstypes.JsonRPCError
+		require.ErrorAs(t, err, &rpcErr)
+		assert.False(t, errors.Is(err, rpc.ErrNoQuorum))
+	})
+
+	t.Run("early result", func(t *testing.T) {
+		server := setupHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+		})
+		client := newClient(t, 7824, &mockNode{URL: server.URL}, a, b)
+		start := time.Now()
+		require.NoError(t, client.QuorumCall(ctx, rpc.DefaultQuorumOptions(), nil, "eth_getBlockByNumber", "finalized", false))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
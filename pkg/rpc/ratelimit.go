@@ -0,0 +1,248 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+)
+
+// RateLimiterOptions defines request budgets and backoff behaviour of a RateLimiter.
+type RateLimiterOptions struct {
+	// Default applies to hosts without a configured limit (a zero rate means unlimited).
+	Default types.RateLimit
+	// Hosts holds explicit limits keyed by host, e.g. "rpc.ankr.com".
+	Hosts map[string]types.RateLimit
+	// DefaultBackoff is used when a host signals rate limiting without a Retry-After hint.
+	DefaultBackoff time.Duration
+	// MaxBackoff caps the backoff requested by a host.
+	MaxBackoff time.Duration
+	// Clock is used for token refills and backoffs (defaults to the system clock).
+	Clock Clock
+}
+
+// DefaultRateLimiterOptions returns options without a default limit and with a 5s backoff
+// (at most one minute) when a host signals rate limiting.
+func DefaultRateLimiterOptions() RateLimiterOptions {
+	return RateLimiterOptions{
+		DefaultBackoff: 5 * time.Second,
+		MaxBackoff:     time.Minute,
+		Clock:          systemClock{},
+	}
+}
+
+// RateLimiter throttles requests per RPC host with token buckets and backs off from hosts that
+// answer with HTTP 429 or a rate-limit JSON-RPC error. Hosts are shared across chains, so one
+// limiter should be used for all chains served by the same process. It implements selector.Gate.
+type RateLimiter struct {
+	opts RateLimiterOptions
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket is the budget of a single host.
+type tokenBucket struct {
+	limit        types.RateLimit
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// NewRateLimiter creates a RateLimiter.
+func NewRateLimiter(opts RateLimiterOptions) *RateLimiter {
+	if opts.Clock == nil {
+		opts.Clock = systemClock{}
+	}
+	return &RateLimiter{
+		opts:    opts,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Configure sets the limits of every host of a chain that declares RpcTarget.RateLimit.
+func (r *RateLimiter) Configure(chain types.Chain) {
+	for _, target := range chain.RPCUrls {
+		if target.RateLimit == nil {
+			continue
+		}
+		for _, u := range append(append([]string{}, target.Http...), target.WebSocket...) {
+			r.SetLimit(hostOf(u), *target.RateLimit)
+		}
+	}
+}
+
+// SetLimit sets the limit of a host, keeping any active backoff.
+func (r *RateLimiter) SetLimit(host string, limit types.RateLimit) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.buckets[host]
+	if !ok {
+		r.buckets[host] = &tokenBucket{limit: limit, tokens: float64(burstOf(limit)), last: r.opts.Clock.Now()}
+		return
+	}
+	b.limit = limit
+	b.tokens = min(b.tokens, float64(burstOf(limit)))
+}
+
+// Permits reports whether a request to rawURL could be sent right now without waiting.
+func (r *RateLimiter) Permits(rawURL string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.bucket(hostOf(rawURL))
+	now := r.opts.Clock.Now()
+	if now.Before(b.blockedUntil) {
+		return false
+	}
+	b.refill(now)
+	return b.limit.RequestsPerSecond <= 0 || b.tokens >= 1
+}
+
+// Wait blocks until a request to rawURL is within budget and takes a token.
+func (r *RateLimiter) Wait(ctx context.Context, rawURL string) error {
+	host := hostOf(rawURL)
+	for {
+		delay := r.reserve(host)
+		if delay <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.opts.Clock.After(delay):
+		}
+	}
+}
+
+// Backoff blocks rawURL's host for d, or DefaultBackoff if d is not positive.
+func (r *RateLimiter) Backoff(rawURL string, d time.Duration) {
+	if d <= 0 {
+		d = r.opts.DefaultBackoff
+	}
+	if r.opts.MaxBackoff > 0 && d > r.opts.MaxBackoff {
+		d = r.opts.MaxBackoff
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.bucket(hostOf(rawURL))
+	if until := r.opts.Clock.Now().Add(d); until.After(b.blockedUntil) {
+		b.blockedUntil = until
+	}
+}
+
+// ThrottledUntil returns the end of the active backoff of rawURL's host, or the zero time.
+func (r *RateLimiter) ThrottledUntil(rawURL string) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.bucket(hostOf(rawURL))
+	if r.opts.Clock.Now().Before(b.blockedUntil) {
+		return b.blockedUntil
+	}
+	return time.Time{}
+}
+
+// Observe backs off from rawURL's host if err signals rate limiting. It reports whether it did.
+func (r *RateLimiter) Observe(rawURL string, err error) bool {
+	limited, retryAfter := rateLimited(err)
+	if limited {
+		r.Backoff(rawURL, retryAfter)
+	}
+	return limited
+}
+
+// reserve takes a token if one is available, or returns how long to wait for one.
+func (r *RateLimiter) reserve(host string) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.bucket(host)
+	now := r.opts.Clock.Now()
+	if now.Before(b.blockedUntil) {
+		return b.blockedUntil.Sub(now)
+	}
+	if b.limit.RequestsPerSecond <= 0 {
+		return 0
+	}
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.RequestsPerSecond * float64(time.Second))
+}
+
+// bucket returns the bucket of host, creating it full. The caller must hold r.mu.
+func (r *RateLimiter) bucket(host string) *tokenBucket {
+	b, ok := r.buckets[host]
+	if !ok {
+		limit, ok := r.opts.Hosts[host]
+		if !ok {
+			limit = r.opts.Default
+		}
+		b = &tokenBucket{limit: limit, tokens: float64(burstOf(limit)), last: r.opts.Clock.Now()}
+		r.buckets[host] = b
+	}
+	return b
+}
+
+// refill adds the tokens accrued since the last refill.
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(b.tokens+elapsed.Seconds()*b.limit.RequestsPerSecond, float64(burstOf(b.limit)))
+		b.last = now
+	}
+}
+
+// burstOf returns the bucket capacity of a limit, at least one request.
+func burstOf(limit types.RateLimit) int {
+	return max(limit.Burst, 1)
+}
+
+// hostOf returns the host of an RPC URL, falling back to the URL itself.
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// rateLimitMessage matches the lowercased JSON-RPC error messages of rate-limited requests, e.g.
+// "too many requests" or "daily request count exceeded". The error code alone does not tell:
+// -32005 ("limit exceeded") also reports e.g. an eth_getLogs query with too many results.
+var rateLimitMessage = regexp.MustCompile(`rate.?limit|too many requests|request rate|requests? per |` +
+	`requests? (?:count |limit |quota )?exceeded|exceeded (?:the |your )?(?:\w+ )?(?:requests?|quota|capacity)\b`)
+
+// rateLimited reports whether err signals rate limiting and the backoff the endpoint asked for.
+func rateLimited(err error) (bool, time.Duration) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests, httpErr.RetryAfter
+	}
+	var rpcErr *types.JsonRPCError
+	if errors.As(err, &rpcErr) {
+		return rateLimitMessage.MatchString(strings.ToLower(rpcErr.Message)), 0
+	}
+	return false, 0
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package rpc_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/rpc"
+	"go-ethereum-chains/pkg/selector"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRateLimiter_TokenBucket tests per-host budgets configured from chain metadata.
+func TestRateLimiter_TokenBucket(t *testing.T) {
+	clock := newFakeClock()
+	opts := rpc.DefaultRateLimiterOptions()
+	opts.Clock = clock
+	limiter := rpc.NewRateLimiter(opts)
+
+	limiter.Configure(chainstypes.Chain{
+		ID: big.NewInt(1),
+		RPCUrls: map[string]chainstypes.RpcTarget{
+			"default": {
+				Http:      []string{"https://rpc.example.test/eth"},
+				RateLimit: &chainstypes.RateLimit{RequestsPerSecond: 2, Burst: 2},
+			},
+		},
+	})
+
+	ctx := context.Background()
+	// Another path on the same host shares the budget.
+	require.NoError(t, limiter.Wait(ctx, "https://rpc.example.test/eth"))
+	require.NoError(t, limiter.Wait(ctx, "https://rpc.example.test/arbitrum"))
+	assert.False(t, limiter.Permits("https://rpc.example.test/eth"))
+	assert.True(t, limiter.Permits("https://unlimited.example.test"))
+
+	clock.Advance(500 * time.Millisecond)
+	assert.True(t, limiter.Permits("https://rpc.example.test/eth"))
+
+	// Wait blocks until a token has been refilled.
+	require.NoError(t, limiter.Wait(ctx, "https://rpc.example.test/eth"))
+	done := make(chan error, 1)
+	go func() { done <- limiter.Wait(ctx, "https://rpc.example.test/eth") }()
+	clock.waitForTimer(t)
+	select {
+	case <-done:
+		t.Fatal("Wait returned before a token was available")
+	default:
+	}
+	clock.Advance(500 * time.Millisecond)
+	require.NoError(t, <-done)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, limiter.Wait(cancelled, "https://rpc.example.test/eth"), context.Canceled)
+}
+
+// TestCheckRPCs_RateLimitMetadata tests that checks are throttled by the chain's declared limits.
+func TestCheckRPCs_RateLimitMetadata(t *testing.T) {
+	node := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `"0x10"`)
+	})
+	registry.RegisterChain(chainstypes.Chain{
+		ID:   big.NewInt(7869),
+		Name: t.Name(),
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {
+			Http:      []string{node.URL},
+			RateLimit: &chainstypes.RateLimit{RequestsPerSecond: 1, Burst: 1},
+		}},
+	})
+	limiterOpts := rpc.DefaultRateLimiterOptions()
+	limiterOpts.Clock = newFakeClock()
+	limiter := rpc.NewRateLimiter(limiterOpts)
+
+	opts := httpOnlyCheckOptions()
+	opts.RateLimiter = limiter
+	statuses, err := rpc.CheckRPCs(context.Background(), 7869, opts)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].IsAvailable)
+	assert.False(t, limiter.Permits(node.URL), "the check used the host's only token")
+}
+
+// TestRateLimiter_RetryAfter tests that the client honors Retry-After and fails over.
+func TestRateLimiter_RetryAfter(t *testing.T) {
+	limited := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	healthy := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `"0x1"`)
+	})
+	chainID := registerMockChain(t, 7788, limited.URL, healthy.URL)
+
+	clock := newFakeClock()
+	limiterOpts := rpc.DefaultRateLimiterOptions()
+	limiterOpts.Clock = clock
+	limiter := rpc.NewRateLimiter(limiterOpts)
+
+	breakerOpts := rpc.DefaultBreakerOptions()
+	breakerOpts.FailureThreshold = 1
+	breakers := rpc.NewBreakers(breakerOpts)
+
+	opts := fastClientOptions()
+	opts.RateLimiter = limiter
+	opts.Breakers = breakers
+	client, err := rpc.NewClient(chainID, opts)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Call(context.Background(), nil, "eth_blockNumber"))
+	assert.Equal(t, clock.Now().Add(30*time.Second), limiter.ThrottledUntil(limited.URL))
+	assert.Equal(t, rpc.BreakerClosed, breakers.State(limited.URL), "rate limiting must not trip the breaker")
+
+	// The selector skips the throttled host until the backoff has elapsed.
+	criteria := selector.DefaultRPCCriteria()
+	criteria.Gates = []selector.Gate{limiter}
+	urls, err := selector.GetRPCs(chainID, criteria)
+	require.NoError(t, err)
+	assert.Equal(t, []string{healthy.URL}, urls)
+
+	clock.Advance(30 * time.Second)
+	urls, err = selector.GetRPCs(chainID, criteria)
+	require.NoError(t, err)
+	assert.Equal(t, []string{limited.URL, healthy.URL}, urls)
+}
+
+// TestRateLimiter_JsonRPCError tests backoff on provider-specific rate-limit errors.
+func TestRateLimiter_JsonRPCError(t *testing.T) {
+	limited := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeError(w, req, -32005, "daily request count exceeded, request rate limited")
+	})
+	chainID := registerMockChain(t, 7789, limited.URL)
+
+	clock := newFakeClock()
+	limiterOpts := rpc.DefaultRateLimiterOptions()
+	limiterOpts.Clock = clock
+	limiterOpts.DefaultBackoff = 10 * time.Second
+	limiter := rpc.NewRateLimiter(limiterOpts)
+
+	opts := rpc.DefaultCheckOptions()
+	opts.CheckWebSocket = false
+	opts.RateLimiter = limiter
+	statuses, err := rpc.CheckRPCs(context.Background(), chainID, opts)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].IsRateLimited)
+	assert.Equal(t, clock.Now().Add(10*time.Second), limiter.ThrottledUntil(limited.URL))
+	assert.False(t, limiter.Permits(limited.URL))
+}
+
+// TestRateLimiter_LimitExceeded tests that -32005 only backs off for rate-limit messages, so an
+// oversized eth_getLogs neither blocks the host nor fails over.
+func TestRateLimiter_LimitExceeded(t *testing.T) {
+	for message, want := range map[string]bool{
+		"daily request count exceeded, request rate limited": true,
+		"Too Many Requests":                      true,
+		"you have exceeded your request quota":   true,
+		"exceeded the requests per second limit": true,
+		"query returned more than 10000 results": false,
+		"Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range": false,
+		"limit exceeded": false,
+	} {
+		limiter := rpc.NewRateLimiter(rpc.DefaultRateLimiterOptions())
+		limited := limiter.Observe("https://limits.example.test", &chainstypes.JsonRPCError{Code: -32005, Message: message})
+		assert.Equal(t, want, limited, message)
+	}
+
+	oversized := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeError(w, req, -32005, "query returned more than 10000 results")
+	})
+	other := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		writeResult(w, req, `[]`)
+	})
+	limiter := rpc.NewRateLimiter(rpc.DefaultRateLimiterOptions())
+	opts := fastClientOptions()
+	opts.RateLimiter = limiter
+	client, err := rpc.NewClient(registerMockChain(t, 7855, oversized.URL, other.URL), opts)
+	require.NoError(t, err)
+
+	err = client.Call(context.Background(), nil, "eth_getLogs", map[string]string{"fromBlock": "0x0", "toBlock": "0x100000"})
+	var rpcErr *chainstypes.JsonRPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, -32005, rpcErr.Code)
+	assert.EqualValues(t, 1, oversized.calls.Load())
+	assert.EqualValues(t, 0, other.calls.Load())
+	assert.True(t, limiter.ThrottledUntil(oversized.URL).IsZero())
+}
+
+// TestRateLimiter_MaxBackoff tests that excessive Retry-After values are capped.
+func TestRateLimiter_MaxBackoff(t *testing.T) {
+	clock := newFakeClock()
+	opts := rpc.DefaultRateLimiterOptions()
+	opts.Clock = clock
+	opts.MaxBackoff = time.Minute
+	limiter := rpc.NewRateLimiter(opts)
+
+	limited := limiter.Observe("https://capped.example.test", &rpc.HTTPError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour})
+	assert.True(t, limited)
+	assert.Equal(t, clock.Now().Add(time.Minute), limiter.ThrottledUntil("https://capped.example.test"))
+	assert.False(t, limiter.Observe("https://capped.example.test", &rpc.HTTPError{StatusCode: http.StatusBadGateway}))
+}
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +26,29 @@ type CheckRPCOptions struct {
 	CheckHTTP       bool
 	CheckWebSocket  bool
 	Providers       []types.ProviderName
+
+	// HeadStrategy selects how the consensus head is derived from healthy endpoints.
+	HeadStrategy HeadStrategy
+	// MaxBlockLag marks endpoints trailing the consensus head by more blocks as degraded. It is 0
+	// by default, which disables the check; BlockLag is reported either way.
+	MaxBlockLag uint64
+	// CheckSyncing additionally queries eth_syncing and marks syncing endpoints as degraded.
+	CheckSyncing bool
+	// MaxBlockAge marks endpoints whose latest block is older than this as degraded (0 disables).
+	MaxBlockAge time.Duration
+	// CheckBatch additionally sends a JSON-RPC batch to HTTP endpoints to detect batch support.
+	CheckBatch bool
+	// RateLimiter, if set, throttles the checks and backs off from endpoints that rate limit them.
+	// It is configured with the chain's RpcTarget.RateLimit metadata.
+	RateLimiter *RateLimiter
+
+	// HTTPClient sends HTTP checks. If nil, Transport is used, or else DefaultTransport.
+	HTTPClient *http.Client
+	// Transport sends HTTP checks when HTTPClient is nil.
+	Transport http.RoundTripper
+	// Dialer opens WebSocket connections (defaults to DefaultDialer). A zero HandshakeTimeout
+	// is set from TimeoutPerCheck.
+	Dialer *websocket.Dialer
 }
 
 // DefaultCheckOptions returns default options for CheckRPCs.
@@ -36,18 +61,25 @@ func DefaultCheckOptions() CheckRPCOptions {
 	}
 }
 
+// checkTarget is an endpoint to be checked by CheckRPCs.
+type checkTarget struct {
+	url  string
+	isWS bool
+	auth *types.AuthConfig
+}
+
 // CheckRPCs checks availability and latency of RPCs for a chain identified by ID or name.
 func CheckRPCs(ctx context.Context, identifier any, opts CheckRPCOptions) ([]types.RPCStatus, error) {
 	chain, err := registry.FindChain(identifier)
 	if err != nil {
 		return nil, err // Error already includes ErrChainNotFound info
 	}
-
-	var urlsToCheck []struct {
-		url  string
-		isWS bool
+	if opts.RateLimiter != nil {
+		opts.RateLimiter.Configure(chain)
 	}
 
+	var urlsToCheck []checkTarget
+
 	providersToCheck := opts.Providers
 	if len(providersToCheck) == 0 {
 		providersToCheck = make([]types.ProviderName, 0, len(chain.RPCUrls))
@@ -61,20 +93,14 @@ func CheckRPCs(ctx context.Context, identifier any, opts CheckRPCOptions) ([]typ
 			if opts.CheckHTTP {
 				for _, u := range target.Http {
 					if u != "" {
-						urlsToCheck = append(urlsToCheck, struct {
-							url  string
-							isWS bool
-						}{u, false})
+						urlsToCheck = append(urlsToCheck, checkTarget{u, false, target.Auth})
 					}
 				}
 			}
 			if opts.CheckWebSocket {
 				for _, u := range target.WebSocket {
 					if u != "" {
-						urlsToCheck = append(urlsToCheck, struct {
-							url  string
-							isWS bool
-						}{u, true})
+						urlsToCheck = append(urlsToCheck, checkTarget{u, true, target.Auth})
 					}
 				}
 			}
@@ -90,23 +116,40 @@ func CheckRPCs(ctx context.Context, identifier any, opts CheckRPCOptions) ([]typ
 	wg.Add(len(urlsToCheck))
 
 	for i, u := range urlsToCheck {
-		go func(index int, urlToCheck struct {
-			url  string
-			isWS bool
-		}) {
+		go func(index int, urlToCheck checkTarget) {
 			defer wg.Done()
 			checkCtx, cancel := context.WithTimeout(ctx, opts.TimeoutPerCheck)
 			defer cancel()
+			if opts.RateLimiter != nil {
+				if err := opts.RateLimiter.Wait(checkCtx, urlToCheck.url); err != nil {
+					results[index] = types.RPCStatus{
+						URL:           urlToCheck.url,
+						IsHTTP:        !urlToCheck.isWS,
+						IsWebSocket:   urlToCheck.isWS,
+						IsRateLimited: true,
+						Error:         fmt.Errorf("rate limiter wait failed: %w", err),
+					}
+					return
+				}
+			}
 			if urlToCheck.isWS {
-				results[index] = checkWebSocket(checkCtx, urlToCheck.url, opts.TimeoutPerCheck)
+				results[index] = checkWebSocket(checkCtx, urlToCheck.url, urlToCheck.auth, opts)
 			} else {
-				results[index] = checkHTTP(checkCtx, urlToCheck.url, opts.TimeoutPerCheck)
+				results[index] = checkHTTP(checkCtx, urlToCheck.url, urlToCheck.auth, opts)
+			}
+			if limited, retryAfter := rateLimited(results[index].Error); limited {
+				results[index].IsRateLimited = true
+				if opts.RateLimiter != nil {
+					opts.RateLimiter.Backoff(urlToCheck.url, retryAfter)
+				}
 			}
 		}(i, u)
 	}
 
 	wg.Wait()
 
+	AnnotateHeadLag(results, opts)
+
 	if ctx.Err() != nil {
 		// Return partial results along with the context error (e.g., timeout)
 		return results, ctx.Err()
@@ -115,97 +158,148 @@ func CheckRPCs(ctx context.Context, identifier any, opts CheckRPCOptions) ([]typ
 	return results, nil
 }
 
-// checkHTTP performs the eth_blockNumber check against an HTTP endpoint.
-func checkHTTP(ctx context.Context, url string, timeout time.Duration) types.RPCStatus {
-	start := time.Now()
+// checkHTTP performs the eth_blockNumber check against an HTTP endpoint. If the check had to
+// open a new connection, it is repeated on the now warm connection, so Latency excludes the
+// TCP and TLS handshakes and ColdLatency reports them.
+func checkHTTP(ctx context.Context, url string, auth *types.AuthConfig, opts CheckRPCOptions) types.RPCStatus {
 	status := types.RPCStatus{URL: url, IsHTTP: true}
+	client := withAuth(httpClientFor(opts.HTTPClient, opts.Transport), auth)
 
-	client := http.Client{
-		Timeout: timeout,
-	}
-
-	reqBody := types.JsonRPCRequest{
-		Version: "2.0",
-		Method:  "eth_blockNumber",
-		Params:  []interface{}{},
-		ID:      1,
-	}
-	jsonData, err := json.Marshal(reqBody)
+	result, latency, reused, err := timedCallHTTP(ctx, client, url, newProbeRequest(1, "eth_blockNumber"))
 	if err != nil {
-		status.Error = fmt.Errorf("failed to marshal json-rpc request: %w", err)
+		status.Error = err
 		return status
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	blockNumber, err := decodeBlockNumber(result)
 	if err != nil {
-		status.Error = fmt.Errorf("failed to create http request: %w", err)
+		status.Error = err
 		return status
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
 
-	resp, err := client.Do(req)
-	if err != nil {
-		status.Error = fmt.Errorf("http request failed: %w", err)
-		return status
+	status.Latency = latency
+	status.IsAvailable = true
+	status.BlockNumber = blockNumber
+
+	if reused {
+		status.WarmLatency = latency
+	} else {
+		status.ColdLatency = latency
+		if _, warm, _, err := timedCallHTTP(ctx, client, url, newProbeRequest(4, "eth_blockNumber")); err == nil {
+			status.WarmLatency = warm
+			status.Latency = warm
+		}
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		status.Error = fmt.Errorf("failed to read response body: %w", err)
-		return status
+	// Follow-up failures leave the endpoint available; AnnotateHeadLag marks it degraded.
+	if opts.CheckSyncing {
+		result, err := callHTTP(ctx, client, url, newProbeRequest(2, "eth_syncing"))
+		if err == nil {
+			status.IsSyncing, err = decodeSyncing(result)
+		}
+		if err != nil {
+			status.CheckError = fmt.Errorf("eth_syncing check failed: %w", err)
+		}
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		status.Error = fmt.Errorf("http status %d: %s", resp.StatusCode, string(bodyBytes))
-		return status
+	if opts.MaxBlockAge > 0 {
+		result, err := callHTTP(ctx, client, url, newProbeRequest(3, "eth_getBlockByNumber", "latest", false))
+		if err == nil {
+			status.BlockTimestamp, err = decodeBlockTimestamp(result)
+		}
+		if err != nil {
+			status.CheckError = errors.Join(status.CheckError, fmt.Errorf("latest block check failed: %w", err))
+		}
+	}
+
+	if opts.CheckBatch {
+		status.SupportsBatch = checkBatchSupport(ctx, client, url)
+	}
+
+	return status
+}
+
+// callHTTP posts a single JSON-RPC request and returns its result.
+func callHTTP(ctx context.Context, client *http.Client, url string, reqBody types.JsonRPCRequest) (json.RawMessage, error) {
+	bodyBytes, err := postJSON(ctx, client, url, reqBody)
+	if err != nil {
+		return nil, err
 	}
 
 	var rpcResp types.JsonRPCResponse
 	if err := json.Unmarshal(bodyBytes, &rpcResp); err != nil {
-		status.Error = fmt.Errorf("failed to unmarshal json-rpc response (body: %s): %w", string(bodyBytes), err)
-		return status
+		return nil, fmt.Errorf("%w: failed to unmarshal json-rpc response (body: %s): %w", errBadResponse, string(bodyBytes), err)
 	}
 
-	if rpcResp.Error != nil {
-		status.Error = rpcResp.Error
-		return status
+	return checkResponse(reqBody, rpcResp)
+}
+
+// timedCallHTTP is callHTTP reporting the latency and whether an idle connection was reused.
+func timedCallHTTP(ctx context.Context, client *http.Client, url string, reqBody types.JsonRPCRequest) (json.RawMessage, time.Duration, bool, error) {
+	var reused bool
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused },
+	})
+	start := time.Now()
+	result, err := callHTTP(ctx, client, url, reqBody)
+	return result, time.Since(start), reused, err
+}
+
+// postJSON posts a JSON payload and returns the body of a 2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal json-rpc request: %w", err)
 	}
 
-	if rpcResp.ID != reqBody.ID {
-		status.Error = fmt.Errorf("rpc response id mismatch (got %d, expected %d)", rpcResp.ID, reqBody.ID)
-		return status
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
 
-	var blockNumberHex string
-	if err := json.Unmarshal(rpcResp.Result, &blockNumberHex); err != nil {
-		status.Error = fmt.Errorf("failed to unmarshal block number result (%s): %w", string(rpcResp.Result), err)
-		return status
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	blockNumber := new(big.Int)
-	if _, ok := blockNumber.SetString(strings.TrimPrefix(blockNumberHex, "0x"), 16); !ok {
-		status.Error = fmt.Errorf("failed to parse block number hex: %s", blockNumberHex)
-		return status
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read response body: %w", errBadResponse, err)
 	}
 
-	status.Latency = time.Since(start)
-	status.IsAvailable = true
-	status.BlockNumber = blockNumber
-	return status
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(bodyBytes),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	return bodyBytes, nil
 }
 
 // checkWebSocket performs the eth_blockNumber check against a WebSocket endpoint.
-func checkWebSocket(ctx context.Context, url string, timeout time.Duration) types.RPCStatus {
+func checkWebSocket(ctx context.Context, url string, auth *types.AuthConfig, opts CheckRPCOptions) types.RPCStatus {
 	start := time.Now()
 	status := types.RPCStatus{URL: url, IsWebSocket: true}
 
-	dialer := websocket.Dialer{
-		HandshakeTimeout: timeout / 2,
+	dialer := *DefaultDialer()
+	if opts.Dialer != nil {
+		dialer = *opts.Dialer
+	}
+	if dialer.HandshakeTimeout == 0 {
+		dialer.HandshakeTimeout = opts.TimeoutPerCheck / 2
 	}
 
-	conn, resp, err := dialer.DialContext(ctx, url, nil)
+	header, err := handshakeHeader(nil, auth)
+	if err != nil {
+		status.Error = err
+		return status
+	}
+	conn, resp, err := dialer.DialContext(ctx, url, header)
 	if err != nil {
 		errMsg := fmt.Sprintf("websocket dial failed: %v", err)
 		if resp != nil {
@@ -215,55 +309,144 @@ func checkWebSocket(ctx context.Context, url string, timeout time.Duration) type
 				errMsg = fmt.Sprintf("%s (status: %s, body: %s)", errMsg, resp.Status, string(bodyBytes))
 			}
 		}
-		status.Error = fmt.Errorf(errMsg)
+		status.Error = errors.New(errMsg)
 		return status
 	}
 	defer conn.Close()
 
-	deadline := time.Now().Add(timeout - time.Since(start))
+	deadline := time.Now().Add(opts.TimeoutPerCheck - time.Since(start))
 	_ = conn.SetReadDeadline(deadline)
 	_ = conn.SetWriteDeadline(deadline)
 
-	reqBody := types.JsonRPCRequest{
-		Version: "2.0",
-		Method:  "eth_blockNumber",
-		Params:  []interface{}{},
-		ID:      1,
-	}
-	if err := conn.WriteJSON(reqBody); err != nil {
-		status.Error = fmt.Errorf("websocket write json failed: %w", err)
+	callStart := time.Now()
+	result, err := callWebSocket(conn, newProbeRequest(1, "eth_blockNumber"))
+	if err != nil {
+		status.Error = err
 		return status
 	}
 
-	var rpcResp types.JsonRPCResponse
-	if err := conn.ReadJSON(&rpcResp); err != nil {
-		status.Error = fmt.Errorf("websocket read json failed: %w", err)
+	blockNumber, err := decodeBlockNumber(result)
+	if err != nil {
+		status.Error = err
 		return status
 	}
 
+	// The call itself runs on an established connection; the dial is the cold part.
+	status.ColdLatency = time.Since(start)
+	status.WarmLatency = time.Since(callStart)
+	status.Latency = status.WarmLatency
+	status.IsAvailable = true
+	status.BlockNumber = blockNumber
+
+	// Follow-up failures leave the endpoint available; AnnotateHeadLag marks it degraded.
+	if opts.CheckSyncing {
+		result, err := callWebSocket(conn, newProbeRequest(2, "eth_syncing"))
+		if err == nil {
+			status.IsSyncing, err = decodeSyncing(result)
+		}
+		if err != nil {
+			status.CheckError = fmt.Errorf("eth_syncing check failed: %w", err)
+		}
+	}
+
+	if opts.MaxBlockAge > 0 {
+		result, err := callWebSocket(conn, newProbeRequest(3, "eth_getBlockByNumber", "latest", false))
+		if err == nil {
+			status.BlockTimestamp, err = decodeBlockTimestamp(result)
+		}
+		if err != nil {
+			status.CheckError = errors.Join(status.CheckError, fmt.Errorf("latest block check failed: %w", err))
+		}
+	}
+
+	return status
+}
+
+// callWebSocket writes a single JSON-RPC request to an open connection and reads its response.
+func callWebSocket(conn *websocket.Conn, reqBody types.JsonRPCRequest) (json.RawMessage, error) {
+	if err := conn.WriteJSON(reqBody); err != nil {
+		return nil, fmt.Errorf("websocket write json failed: %w", err)
+	}
+
+	// Skip notifications of subscriptions that may share the connection.
+	for {
+		var msg types.JsonRPCMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return nil, fmt.Errorf("websocket read json failed: %w", err)
+		}
+		if !msg.IsNotification() {
+			return checkResponse(reqBody, msg.Response())
+		}
+	}
+}
+
+// newProbeRequest builds a JSON-RPC 2.0 request used by the health checks.
+func newProbeRequest(id int, method string, params ...interface{}) types.JsonRPCRequest {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return types.JsonRPCRequest{
+		Version: "2.0",
+		Method:  method,
+		Params:  params,
+		ID:      types.NumberID(int64(id)),
+	}
+}
+
+// checkResponse validates a JSON-RPC response against its request and returns the result.
+func checkResponse(reqBody types.JsonRPCRequest, rpcResp types.JsonRPCResponse) (json.RawMessage, error) {
 	if rpcResp.Error != nil {
-		status.Error = rpcResp.Error
-		return status
+		return nil, rpcResp.Error
 	}
-	if rpcResp.ID != reqBody.ID {
-		status.Error = fmt.Errorf("rpc response id mismatch (got %d, expected %d)", rpcResp.ID, reqBody.ID)
-		return status
+	if !rpcResp.ID.Matches(reqBody.ID) {
+		return nil, fmt.Errorf("%w: rpc response id mismatch (got %s, expected %s)", errBadResponse, rpcResp.ID, reqBody.ID)
 	}
+	return rpcResp.Result, nil
+}
 
+// decodeBlockNumber parses an eth_blockNumber result.
+func decodeBlockNumber(result json.RawMessage) (*big.Int, error) {
 	var blockNumberHex string
-	if err := json.Unmarshal(rpcResp.Result, &blockNumberHex); err != nil {
-		status.Error = fmt.Errorf("failed to unmarshal block number result (%s): %w", string(rpcResp.Result), err)
-		return status
+	if err := json.Unmarshal(result, &blockNumberHex); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block number result (%s): %w", string(result), err)
 	}
 
-	blockNumber := new(big.Int)
-	if _, ok := blockNumber.SetString(strings.TrimPrefix(blockNumberHex, "0x"), 16); !ok {
-		status.Error = fmt.Errorf("failed to parse block number hex: %s", blockNumberHex)
-		return status
+	blockNumber, ok := parseHexBig(blockNumberHex)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse block number hex: %s", blockNumberHex)
 	}
+	return blockNumber, nil
+}
 
-	status.Latency = time.Since(start)
-	status.IsAvailable = true
-	status.BlockNumber = blockNumber
-	return status
+// decodeSyncing parses an eth_syncing result, which is either false or a progress object.
+func decodeSyncing(result json.RawMessage) (bool, error) {
+	var syncing bool
+	if err := json.Unmarshal(result, &syncing); err == nil {
+		return syncing, nil
+	}
+	var progress map[string]json.RawMessage
+	if err := json.Unmarshal(result, &progress); err != nil {
+		return false, fmt.Errorf("failed to unmarshal syncing result (%s): %w", string(result), err)
+	}
+	return true, nil
+}
+
+// decodeBlockTimestamp extracts the timestamp from an eth_getBlockByNumber result.
+func decodeBlockTimestamp(result json.RawMessage) (time.Time, error) {
+	var block struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(result, &block); err != nil {
+		return time.Time{}, fmt.Errorf("failed to unmarshal block result (%s): %w", string(result), err)
+	}
+	ts, ok := parseHexBig(block.Timestamp)
+	if !ok || !ts.IsInt64() {
+		return time.Time{}, fmt.Errorf("failed to parse block timestamp hex: %s", block.Timestamp)
+	}
+	return time.Unix(ts.Int64(), 0), nil
+}
+
+// parseHexBig parses a 0x-prefixed hex quantity.
+func parseHexBig(s string) (*big.Int, bool) {
+	return new(big.Int).SetString(strings.TrimPrefix(s, "0x"), 16)
 }
@@ -6,6 +6,7 @@ import (
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
 	"time"
 
@@ -45,6 +46,14 @@ func TestCheckRPCs_Basic(t *testing.T) {
 		}
 		require.Equal(t, "eth_blockNumber", req.Method)
 
+		// A subscription notification interleaved before the response must be skipped.
+		err = conn.WriteJSON(chainstypes.JsonRPCNotification{
+			Version: "2.0",
+			Method:  "eth_subscription",
+			Params:  chainstypes.JsonRPCSubscription{Subscription: "0x1", Result: json.RawMessage(`{}`)},
+		})
+		require.NoError(t, err)
+
 		resp := chainstypes.JsonRPCResponse{
 			Version: "2.0",
 			ID:      req.ID,
@@ -122,7 +131,110 @@ func TestCheckRPCs_Basic(t *testing.T) {
 	assert.Equal(t, 1, wsFail, "Expected 1 failed WS check")
 }
 
-func setupHTTPServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+// TestCheckRPCs_HeadLag verifies that lagging, syncing and stale endpoints are marked degraded.
+func TestCheckRPCs_HeadLag(t *testing.T) {
+	now := time.Now().Unix()
+	newNode := func(blockHex string, syncing bool, timestamp int64, failing ...string) *httptest.Server {
+		return setupHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+			var req chainstypes.JsonRPCRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			resp := chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID}
+			switch {
+			case slices.Contains(failing, req.Method):
+				resp.Error = &chainstypes.JsonRPCError{Code: -32601, Message: "method not found"}
+			case req.Method == "eth_blockNumber":
+				resp.Result = json.RawMessage(`"` + blockHex + `"`)
+			case req.Method == "eth_syncing":
+				if syncing {
+					resp.Result = json.RawMessage(`{"currentBlock":"0x1","highestBlock":"0x2"}`)
+				} else {
+					resp.Result = json.RawMessage(`false`)
+				}
+			case req.Method == "eth_getBlockByNumber":
+				block := map[string]string{"number": blockHex, "timestamp": "0x" + big.NewInt(timestamp).Text(16)}
+				resp.Result, _ = json.Marshal(block)
+			default:
+				t.Errorf("unexpected method %s", req.Method)
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		})
+	}
+
+	healthy := newNode("0x3e8", false, now)     // 1000
+	lagging := newNode("0x2f4", false, now)     // 756
+	syncing := newNode("0x3e6", true, now)      // 998
+	stale := newNode("0x3e7", false, now-10*60) // 999, ten minutes old
+	unchecked := newNode("0x3e8", false, now, "eth_syncing")
+
+	mockChainID := big.NewInt(7778)
+	registry.RegisterChain(chainstypes.Chain{
+		ID:   mockChainID,
+		Name: "Mock Head Lag Chain",
+		RPCUrls: map[string]chainstypes.RpcTarget{
+			"default": {Http: []string{healthy.URL, lagging.URL, syncing.URL, stale.URL, unchecked.URL}},
+		},
+	})
+
+	opts := rpc.DefaultCheckOptions()
+	opts.TimeoutPerCheck = 2 * time.Second
+	opts.CheckSyncing = true
+	opts.MaxBlockAge = time.Minute
+	opts.MaxBlockLag = 5
+
+	statuses, err := rpc.CheckRPCs(context.Background(), mockChainID, opts)
+	require.NoError(t, err)
+	require.Len(t, statuses, 5)
+
+	byURL := make(map[string]chainstypes.RPCStatus, len(statuses))
+	for _, s := range statuses {
+		require.NoError(t, s.Error, s.URL)
+		require.True(t, s.IsAvailable, s.URL)
+		byURL[s.URL] = s
+	}
+
+	assert.False(t, byURL[healthy.URL].IsDegraded)
+	assert.Equal(t, uint64(0), byURL[healthy.URL].BlockLag)
+
+	assert.True(t, byURL[lagging.URL].IsDegraded)
+	assert.Equal(t, uint64(244), byURL[lagging.URL].BlockLag)
+
+	assert.True(t, byURL[syncing.URL].IsSyncing)
+	assert.True(t, byURL[syncing.URL].IsDegraded)
+	assert.Equal(t, uint64(2), byURL[syncing.URL].BlockLag)
+
+	assert.True(t, byURL[stale.URL].IsDegraded)
+	assert.Equal(t, uint64(1), byURL[stale.URL].BlockLag)
+	assert.Contains(t, byURL[stale.URL].DegradedReason, "old")
+
+	// A failed follow-up check degrades the endpoint without making it unavailable.
+	assert.ErrorContains(t, byURL[unchecked.URL].CheckError, "eth_syncing check failed")
+	assert.True(t, byURL[unchecked.URL].IsDegraded)
+	assert.Contains(t, byURL[unchecked.URL].DegradedReason, "eth_syncing check failed")
+}
+
+// TestConsensusHead tests the max and median head strategies and the default lag handling.
+func TestConsensusHead(t *testing.T) {
+	statuses := []chainstypes.RPCStatus{
+		{IsAvailable: true, BlockNumber: big.NewInt(100)},
+		{IsAvailable: true, BlockNumber: big.NewInt(5000)},
+		{IsAvailable: true, BlockNumber: big.NewInt(101)},
+		{IsAvailable: true, IsSyncing: true, BlockNumber: big.NewInt(9000)},
+		{IsAvailable: false},
+	}
+
+	assert.Equal(t, 0, rpc.ConsensusHead(statuses, rpc.HeadMax).Cmp(big.NewInt(5000)))
+	assert.Equal(t, 0, rpc.ConsensusHead(statuses, rpc.HeadMedian).Cmp(big.NewInt(101)))
+	assert.Nil(t, rpc.ConsensusHead(nil, rpc.HeadMax))
+
+	// Lag is reported but only marks endpoints degraded once MaxBlockLag is set.
+	lagging := statuses[:3:3]
+	rpc.AnnotateHeadLag(lagging, rpc.DefaultCheckOptions())
+	assert.Equal(t, uint64(4900), lagging[0].BlockLag)
+	assert.False(t, lagging[0].IsDegraded)
+}
+
+func setupHTTPServer(t testing.TB, handler func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
 	t.Helper()
 	ts := httptest.NewServer(http.HandlerFunc(handler))
 	t.Cleanup(ts.Close)
@@ -0,0 +1,309 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync/atomic"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/selector"
+)
+
+// ErrBehindSession is wrapped into the error of an attempt whose endpoint answered from a block
+// below the session's block. The call fails over to another endpoint.
+var ErrBehindSession = errors.New("endpoint is behind the session's block")
+
+// StatusSource provides the last known status of a chain's endpoints. *Monitor implements it.
+type StatusSource interface {
+	Statuses(chainID *big.Int) []types.RPCStatus
+}
+
+var _ StatusSource = (*Monitor)(nil)
+
+// SessionOptions defines parameters for a Session.
+type SessionOptions struct {
+	// PinBlockTag replaces "latest" and omitted block parameters with the session's block, so
+	// reads of the latest state do not move until the session sees a newer block, e.g. through
+	// eth_blockNumber.
+	PinBlockTag bool
+}
+
+// Session tracks the highest block a caller has seen, so that its reads never go backwards when
+// consecutive calls are served by different endpoints. Calls made with a context carrying a
+// Session are only routed to endpoints whose height is unknown or at least the session's
+// block, with the endpoints asked for their head when none is known to be high enough. An
+// eth_blockNumber answer below the session's block fails over, as does a null lookup answer from
+// an endpoint below it, and every block number in a result raises it. Such calls bypass the
+// Cache and coalescing. A Session is safe for concurrent use.
+type Session struct {
+	opts  SessionOptions
+	block atomic.Uint64
+}
+
+// NewSession creates a Session that has not seen any block.
+func NewSession(opts SessionOptions) *Session {
+	return &Session{opts: opts}
+}
+
+// Block returns the highest block the session has seen.
+func (s *Session) Block() uint64 {
+	return s.block.Load()
+}
+
+// Observe raises the session's block to block if it is higher.
+func (s *Session) Observe(block uint64) {
+	for {
+		current := s.block.Load()
+		if block <= current || s.block.CompareAndSwap(current, block) {
+			return
+		}
+	}
+}
+
+type sessionKey struct{}
+
+// WithSession returns a context that makes the calls using it part of the session.
+func WithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFrom returns the session of ctx, or nil.
+func SessionFrom(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// pin returns params with a "latest" or omitted block parameter replaced by the session's block.
+func (s *Session) pin(method string, params []any) []any {
+	i, ok := blockParams[method]
+	block := s.Block()
+	if !s.opts.PinBlockTag || !ok || block == 0 {
+		return params
+	}
+	if i < len(params) {
+		switch tag := params[i].(type) {
+		case nil:
+		case string:
+			if tag != string(ethtypes.Latest) {
+				return params
+			}
+		case ethtypes.BlockTag:
+			if tag != ethtypes.Latest {
+				return params
+			}
+		default:
+			return params
+		}
+	}
+	pinned := slices.Clone(params)
+	for len(pinned) <= i {
+		pinned = append(pinned, nil)
+	}
+	pinned[i] = ethtypes.BlockNumber(block)
+	return pinned
+}
+
+// resultBlock returns the highest block number a result reports: the head for eth_blockNumber,
+// or the block of the returned blocks, transactions, receipts and logs.
+func resultBlock(method string, params []any, result json.RawMessage) (uint64, bool) {
+	switch method {
+	case "eth_blockNumber":
+		var number string
+		if json.Unmarshal(result, &number) != nil {
+			return 0, false
+		}
+		n, err := ethtypes.DecodeUint64(number)
+		return n, err == nil
+	case "eth_getBlockByNumber":
+		if len(params) > 0 && isPending(params[0]) {
+			return 0, false
+		}
+	case "eth_getBlockByHash", "eth_getTransactionByHash", "eth_getTransactionReceipt", "eth_getLogs", "eth_getBlockReceipts":
+	default:
+		return 0, false
+	}
+
+	type object struct {
+		Number      string `json:"number"`
+		BlockNumber string `json:"blockNumber"`
+	}
+	var objects []object
+	if json.Unmarshal(result, &objects) != nil {
+		var single object
+		if json.Unmarshal(result, &single) != nil {
+			return 0, false
+		}
+		objects = []object{single}
+	}
+	var highest uint64
+	found := false
+	for _, o := range objects {
+		number := o.Number
+		if number == "" {
+			number = o.BlockNumber
+		}
+		if n, err := ethtypes.DecodeUint64(number); err == nil {
+			highest, found = max(highest, n), true
+		}
+	}
+	return highest, found
+}
+
+// endpointHeight returns the head of the endpoint that a result of a call reveals.
+func endpointHeight(method string, params []any, result json.RawMessage) (uint64, bool) {
+	switch method {
+	case "eth_blockNumber":
+	case "eth_getBlockByNumber":
+		if len(params) == 0 || blockRule(params[0]).kind != cacheHead || isPending(params[0]) {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	return resultBlock(method, params, result)
+}
+
+func isPending(block any) bool {
+	switch tag := block.(type) {
+	case string:
+		return tag == string(ethtypes.Pending)
+	case ethtypes.BlockTag:
+		return tag == ethtypes.Pending
+	}
+	return false
+}
+
+// checkHeight records the height an endpoint revealed and fails the attempt if it is below the
+// session's block. A block in any answer is a lower bound of the endpoint's head. A null answer
+// to a lookup in a session may come from an endpoint that has not reached the session's block,
+// so unless its height is known to be high enough, the endpoint is asked for its head.
+func (c *Client) checkHeight(ctx context.Context, url, method string, params []any, result json.RawMessage) error {
+	if block, ok := resultBlock(method, params, result); ok {
+		c.recordHeight(url, block)
+	}
+	s := SessionFrom(ctx)
+	if s == nil || s.Block() == 0 {
+		return nil
+	}
+	height, ok := endpointHeight(method, params, result)
+	if !ok && isNull(result) && isLookup(method) {
+		if known, _ := c.height(url); known < s.Block() {
+			var err error
+			if height, err = c.headOf(ctx, url); err != nil {
+				return err
+			}
+			ok = true
+		}
+	}
+	if ok && height < s.Block() {
+		return fmt.Errorf("%w: head %d < %d", ErrBehindSession, height, s.Block())
+	}
+	return nil
+}
+
+// isLookup reports whether method looks up a block, transaction or receipt that a node which
+// has not reached it answers with null.
+func isLookup(method string) bool {
+	switch method {
+	case "eth_getBlockByNumber", "eth_getBlockByHash", "eth_getTransactionByHash", "eth_getTransactionReceipt", "eth_getBlockReceipts":
+		return true
+	}
+	return false
+}
+
+// headOf asks an endpoint for its head and records it.
+func (c *Client) headOf(ctx context.Context, url string) (uint64, error) {
+	raw, err := callHTTP(ctx, c.clients[url], url, c.newRequest("eth_blockNumber", nil))
+	if err != nil {
+		return 0, err
+	}
+	height, ok := endpointHeight("eth_blockNumber", nil, raw)
+	if !ok {
+		return 0, fmt.Errorf("%w: invalid eth_blockNumber result %s", errBadResponse, raw)
+	}
+	c.recordHeight(url, height)
+	return height, nil
+}
+
+// recordHeight raises the known head of an endpoint to height.
+func (c *Client) recordHeight(url string, height uint64) {
+	for {
+		current, _ := c.heights.LoadOrStore(url, height)
+		if current.(uint64) >= height || c.heights.CompareAndSwap(url, current, height) {
+			return
+		}
+	}
+}
+
+// probeHeights asks the endpoints the client's gates permit for their head, so that a session
+// whose block is above every known height is not locked out by heights that went stale. It
+// stops at the first endpoint that has reached block.
+func (c *Client) probeHeights(ctx context.Context, block uint64) {
+	for _, url := range c.endpoints {
+		if !selector.Permitted(url, c.gates) {
+			continue
+		}
+		var height uint64
+		err := c.attempt(ctx, url, func(ctx context.Context, url string) error {
+			var err error
+			height, err = c.headOf(ctx, url)
+			return err
+		})
+		c.observe(ctx, url, err)
+		if err == nil && height >= block {
+			return
+		}
+	}
+}
+
+// height returns the highest known head of an endpoint, from its own answers and the status
+// source.
+func (c *Client) height(url string) (uint64, bool) {
+	var height uint64
+	known := false
+	if h, ok := c.heights.Load(url); ok {
+		height, known = h.(uint64), true
+	}
+	if c.opts.Statuses != nil {
+		for _, status := range c.opts.Statuses.Statuses(c.chain.ID) {
+			if status.URL == url && status.BlockNumber != nil && status.BlockNumber.IsUint64() {
+				height, known = max(height, status.BlockNumber.Uint64()), true
+			}
+		}
+	}
+	return height, known
+}
+
+// sessionGate permits endpoints whose height is unknown or at least the session's block.
+type sessionGate struct {
+	client *Client
+	block  uint64
+}
+
+func (g sessionGate) Permits(url string) bool {
+	height, known := g.client.height(url)
+	return !known || height >= g.block
+}
+
+// gatesFor returns the client's gates plus, for a call in a session, the session's height gate.
+// If the session gate would permit none of the endpoints the other gates permit, their heights
+// are probed first.
+func (c *Client) gatesFor(ctx context.Context) []selector.Gate {
+	s := SessionFrom(ctx)
+	if s == nil || s.Block() == 0 {
+		return c.gates
+	}
+	gates := append(slices.Clip(c.gates), sessionGate{client: c, block: s.Block()})
+	for _, url := range c.endpoints {
+		if selector.Permitted(url, gates) {
+			return gates
+		}
+	}
+	c.probeHeights(ctx, s.Block())
+	return gates
+}
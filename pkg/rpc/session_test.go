@@ -0,0 +1,186 @@
+package rpc_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticStatuses is an rpc.StatusSource with fixed endpoint heights.
+type staticStatuses map[string]int64
+
+func (s staticStatuses) Statuses(*big.Int) []chainstypes.RPCStatus {
+	var statuses []chainstypes.RPCStatus
+	for url, height := range s {
+		statuses = append(statuses, chainstypes.RPCStatus{URL: url, IsAvailable: true, BlockNumber: big.NewInt(height)})
+	}
+	return statuses
+}
+
+// headNode answers eth_blockNumber with head, receipts with a receipt in block 0x70 and
+// everything else with 0x0. The returned function reports the params of the last request.
+func headNode(t *testing.T, head string) (*mockNode, func() []any) {
+	var (
+		mu   sync.Mutex
+		last []any
+	)
+	node := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+		mu.Lock()
+		last = req.Params
+		mu.Unlock()
+		switch req.Method {
+		case "eth_blockNumber":
+			writeResult(w, req, `"`+head+`"`)
+		case "eth_getTransactionReceipt":
+			writeResult(w, req, `{"blockNumber":"0x70","status":"0x1"}`)
+		default:
+			writeResult(w, req, `"0x0"`)
+		}
+	})
+	return node, func() []any {
+		mu.Lock()
+		defer mu.Unlock()
+		return last
+	}
+}
+
+// TestClient_Session tests that reads in a session never go back to a lower block.
+func TestClient_Session(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("routes by status heights", func(t *testing.T) {
+		behind, _ := headNode(t, "0x50")
+		ahead, _ := headNode(t, "0x64")
+		opts := fastClientOptions()
+		opts.Statuses = staticStatuses{behind.URL: 0x50, ahead.URL: 0x64}
+		client, err := rpc.NewClient(registerMockChain(t, 7837, behind.URL, ahead.URL), opts)
+		require.NoError(t, err)
+
+		session := rpc.NewSession(rpc.SessionOptions{})
+		session.Observe(0x5a)
+		sctx := rpc.WithSession(ctx, session)
+		require.NoError(t, client.Call(sctx, nil, "eth_getTransactionReceipt", "0x01"))
+		assert.Equal(t, int64(0), behind.calls.Load())
+		assert.Equal(t, int64(1), ahead.calls.Load())
+		assert.Equal(t, uint64(0x70), session.Block(), "the receipt's block raises the session")
+
+		// Without a session the preferred endpoint is used.
+		require.NoError(t, client.Call(ctx, nil, "eth_getTransactionReceipt", "0x01"))
+		assert.Equal(t, int64(1), behind.calls.Load())
+
+		// Having served the receipt, the endpoint is known to have the receipt's block.
+		require.NoError(t, client.Call(sctx, nil, "eth_getTransactionReceipt", "0x01"))
+		assert.Equal(t, int64(2), behind.calls.Load())
+
+		// No endpoint has reached the session's block, even when asked for its head.
+		session.Observe(0x90)
+		err = client.Call(sctx, nil, "eth_getTransactionReceipt", "0x01")
+		assert.ErrorIs(t, err, rpc.ErrNoPermittedEndpoint)
+		assert.Equal(t, int64(3), behind.calls.Load(), "one eth_blockNumber probe")
+		assert.Equal(t, int64(2), ahead.calls.Load(), "one eth_blockNumber probe")
+	})
+
+	t.Run("fails over from a lower head", func(t *testing.T) {
+		behind, _ := headNode(t, "0x50")
+		ahead, _ := headNode(t, "0x64")
+		client, err := rpc.NewClient(registerMockChain(t, 7838, behind.URL, ahead.URL), fastClientOptions())
+		require.NoError(t, err)
+
+		session := rpc.NewSession(rpc.SessionOptions{})
+		session.Observe(0x5a)
+		sctx := rpc.WithSession(ctx, session)
+		var head string
+		require.NoError(t, client.Call(sctx, &head, "eth_blockNumber"))
+		assert.Equal(t, "0x64", head)
+		assert.Equal(t, uint64(0x64), session.Block())
+
+		// Later calls go straight to the endpoint that is ahead.
+		require.NoError(t, client.Call(sctx, &head, "eth_blockNumber"))
+		assert.Equal(t, int64(1), behind.calls.Load())
+		assert.Equal(t, int64(2), ahead.calls.Load())
+	})
+
+	t.Run("stale heights", func(t *testing.T) {
+		var head atomic.Value
+		head.Store("0x50")
+		node := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+			switch req.Method {
+			case "eth_blockNumber":
+				writeResult(w, req, `"`+head.Load().(string)+`"`)
+			case "eth_getTransactionReceipt":
+				writeResult(w, req, `{"blockNumber":"0x70","status":"0x1"}`)
+			default:
+				writeResult(w, req, `"0x0"`)
+			}
+		})
+		client, err := rpc.NewClient(registerMockChain(t, 7858, node.URL), fastClientOptions())
+		require.NoError(t, err)
+
+		session := rpc.NewSession(rpc.SessionOptions{})
+		sctx := rpc.WithSession(ctx, session)
+		require.NoError(t, client.Call(sctx, nil, "eth_blockNumber"))
+		require.NoError(t, client.Call(sctx, nil, "eth_getTransactionReceipt", "0x01"))
+		assert.Equal(t, uint64(0x70), session.Block())
+		require.NoError(t, client.Call(sctx, nil, "eth_getBalance", "0x01", "latest"))
+
+		// A block seen elsewhere is above every known height: the endpoint is asked for its head.
+		head.Store("0x80")
+		session.Observe(0x78)
+		require.NoError(t, client.Call(sctx, nil, "eth_getBalance", "0x01", "latest"))
+		assert.Equal(t, int64(5), node.calls.Load())
+	})
+
+	t.Run("null lookup from a lagging endpoint", func(t *testing.T) {
+		lagging := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) {
+			if req.Method == "eth_blockNumber" {
+				writeResult(w, req, `"0x50"`)
+				return
+			}
+			writeResult(w, req, `null`)
+		})
+		ahead, _ := headNode(t, "0x80")
+		client, err := rpc.NewClient(registerMockChain(t, 7859, lagging.URL, ahead.URL), fastClientOptions())
+		require.NoError(t, err)
+
+		session := rpc.NewSession(rpc.SessionOptions{})
+		session.Observe(0x70)
+		var receipt struct {
+			BlockNumber string `json:"blockNumber"`
+		}
+		require.NoError(t, client.Call(rpc.WithSession(ctx, session), &receipt, "eth_getTransactionReceipt", "0x01"))
+		assert.Equal(t, "0x70", receipt.BlockNumber)
+		assert.Equal(t, int64(2), lagging.calls.Load(), "the receipt and its head")
+	})
+
+	t.Run("pins the block tag", func(t *testing.T) {
+		node, lastParams := headNode(t, "0x64")
+		client, err := rpc.NewClient(registerMockChain(t, 7839, node.URL), fastClientOptions())
+		require.NoError(t, err)
+
+		session := rpc.NewSession(rpc.SessionOptions{PinBlockTag: true})
+		sctx := rpc.WithSession(ctx, session)
+		const account = "0x52908400098527886e0f7030069857d2e4169ee7"
+
+		// Nothing to pin to before the session has seen a block.
+		require.NoError(t, client.Call(sctx, nil, "eth_getBalance", account, "latest"))
+		assert.Equal(t, []any{account, "latest"}, lastParams())
+
+		require.NoError(t, client.Call(sctx, nil, "eth_blockNumber"))
+		require.NoError(t, client.Call(sctx, nil, "eth_getBalance", account, ethtypes.Latest))
+		assert.Equal(t, []any{account, "0x64"}, lastParams())
+		require.NoError(t, client.Call(sctx, nil, "eth_getBalance", account))
+		assert.Equal(t, []any{account, "0x64"}, lastParams())
+		require.NoError(t, client.Call(sctx, nil, "eth_getBalance", account, "0x10"))
+		assert.Equal(t, []any{account, "0x10"}, lastParams())
+	})
+}
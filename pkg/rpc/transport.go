@@ -0,0 +1,159 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TransportOptions configures the HTTP transport and WebSocket dialer used for RPC traffic.
+type TransportOptions struct {
+	// ProxyURL routes all traffic through an HTTP(S) proxy. If empty, the HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables apply.
+	ProxyURL string
+	// CAFile is a PEM bundle of additional root CAs trusted besides the system roots.
+	CAFile string
+	// InsecureSkipVerify disables TLS certificate verification. Use only against test nodes.
+	InsecureSkipVerify bool
+	// DialTimeout bounds establishing a TCP connection.
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout bounds the TLS handshake.
+	TLSHandshakeTimeout time.Duration
+	// MaxIdleConns caps idle connections across all hosts.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost caps idle connections kept per RPC host.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes idle connections after this long.
+	IdleConnTimeout time.Duration
+}
+
+// DefaultTransportOptions returns options tuned for many concurrent requests to a few RPC hosts.
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		DialTimeout:         5 * time.Second,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        256,
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+var (
+	sharedTransport = mustTransport(DefaultTransportOptions())
+	sharedClient    = &http.Client{Transport: sharedTransport}
+	sharedDialer    = &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: DefaultTransportOptions().TLSHandshakeTimeout,
+	}
+)
+
+// DefaultTransport returns the transport shared by health checks and clients that are not
+// given their own, so connections are reused across all of them.
+func DefaultTransport() *http.Transport {
+	return sharedTransport
+}
+
+// DefaultHTTPClient returns an HTTP client using DefaultTransport.
+func DefaultHTTPClient() *http.Client {
+	return sharedClient
+}
+
+// DefaultDialer returns the WebSocket dialer used when none is configured.
+func DefaultDialer() *websocket.Dialer {
+	return sharedDialer
+}
+
+// NewTransport creates an HTTP transport, e.g. to go through a corporate proxy with a custom
+// CA bundle. Share the result between checks and clients to reuse connections.
+func NewTransport(opts TransportOptions) (*http.Transport, error) {
+	proxy, tlsConfig, err := opts.proxyAndTLS()
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: opts.DialTimeout, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		Proxy:               proxy,
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: opts.TLSHandshakeTimeout,
+		MaxIdleConns:        opts.MaxIdleConns,
+		MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:     opts.IdleConnTimeout,
+	}, nil
+}
+
+// NewDialer creates a WebSocket dialer with the same proxy and TLS settings as NewTransport.
+func NewDialer(opts TransportOptions) (*websocket.Dialer, error) {
+	proxy, tlsConfig, err := opts.proxyAndTLS()
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: opts.DialTimeout, KeepAlive: 30 * time.Second}
+	return &websocket.Dialer{
+		Proxy:            proxy,
+		NetDialContext:   dialer.DialContext,
+		TLSClientConfig:  tlsConfig,
+		HandshakeTimeout: opts.TLSHandshakeTimeout,
+	}, nil
+}
+
+// proxyAndTLS builds the proxy function and TLS configuration shared by transports and dialers.
+func (opts TransportOptions) proxyAndTLS() (func(*http.Request) (*url.URL, error), *tls.Config, error) {
+	proxy := http.ProxyFromEnvironment
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipVerify, //nolint:gosec // explicit opt-in for test nodes
+	}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	return proxy, tlsConfig, nil
+}
+
+// mustTransport creates a transport from options that cannot fail.
+func mustTransport(opts TransportOptions) *http.Transport {
+	t, err := NewTransport(opts)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// httpClientFor returns the client to use given an optional client and round tripper.
+func httpClientFor(client *http.Client, transport http.RoundTripper) *http.Client {
+	switch {
+	case client != nil:
+		return client
+	case transport != nil:
+		return &http.Client{Transport: transport}
+	default:
+		return sharedClient
+	}
+}
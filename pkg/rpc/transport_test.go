@@ -0,0 +1,174 @@
+package rpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/rpc"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockNumberHandler answers every request with block 0x10.
+func blockNumberHandler(w http.ResponseWriter, r *http.Request) {
+	var req chainstypes.JsonRPCRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	writeResult(w, req, `"0x10"`)
+}
+
+// countingTransport counts the requests it forwards.
+type countingTransport struct {
+	next  http.RoundTripper
+	count atomic.Int64
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.count.Add(1)
+	return c.next.RoundTrip(req)
+}
+
+func httpOnlyCheckOptions() rpc.CheckRPCOptions {
+	opts := rpc.DefaultCheckOptions()
+	opts.TimeoutPerCheck = 2 * time.Second
+	opts.CheckWebSocket = false
+	return opts
+}
+
+// TestCheckRPCs_ConnectionReuse tests cold and warm latency over a reused connection.
+func TestCheckRPCs_ConnectionReuse(t *testing.T) {
+	var newConns atomic.Int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(blockNumberHandler))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			newConns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	chainID := registerMockChain(t, 7804, server.URL)
+
+	transport, err := rpc.NewTransport(rpc.DefaultTransportOptions())
+	require.NoError(t, err)
+	opts := httpOnlyCheckOptions()
+	opts.Transport = transport
+
+	statuses, err := rpc.CheckRPCs(context.Background(), chainID, opts)
+	require.NoError(t, err)
+	require.True(t, statuses[0].IsAvailable)
+	assert.Positive(t, statuses[0].ColdLatency)
+	assert.Positive(t, statuses[0].WarmLatency)
+	assert.Equal(t, statuses[0].WarmLatency, statuses[0].Latency)
+
+	statuses, err = rpc.CheckRPCs(context.Background(), chainID, opts)
+	require.NoError(t, err)
+	require.True(t, statuses[0].IsAvailable)
+	assert.Zero(t, statuses[0].ColdLatency, "the second check reuses the idle connection")
+	assert.Positive(t, statuses[0].WarmLatency)
+	assert.Equal(t, int64(1), newConns.Load())
+}
+
+// TestTransport_CABundleAndProxy tests a custom CA bundle and an explicit proxy.
+func TestTransport_CABundleAndProxy(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(blockNumberHandler))
+	t.Cleanup(tlsServer.Close)
+	chainID := registerMockChain(t, 7805, tlsServer.URL)
+
+	statuses, err := rpc.CheckRPCs(context.Background(), chainID, httpOnlyCheckOptions())
+	require.NoError(t, err)
+	assert.False(t, statuses[0].IsAvailable, "the test CA is not trusted by default")
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certPEM, 0o600))
+
+	transportOpts := rpc.DefaultTransportOptions()
+	transportOpts.CAFile = caFile
+	transport, err := rpc.NewTransport(transportOpts)
+	require.NoError(t, err)
+	opts := httpOnlyCheckOptions()
+	opts.Transport = transport
+	statuses, err = rpc.CheckRPCs(context.Background(), chainID, opts)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].IsAvailable, "%v", statuses[0].Error)
+
+	// Plain HTTP requests reach a forward proxy with the absolute target URL.
+	node := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) { writeResult(w, req, `"0x10"`) })
+	var proxied atomic.Int64
+	proxy := setupHTTPServer(t, func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		require.True(t, strings.HasPrefix(r.RequestURI, node.URL))
+		r.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(r)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+	})
+	transportOpts = rpc.DefaultTransportOptions()
+	transportOpts.ProxyURL = proxy.URL
+	transport, err = rpc.NewTransport(transportOpts)
+	require.NoError(t, err)
+
+	clientOpts := fastClientOptions()
+	clientOpts.Transport = transport
+	client, err := rpc.NewClient(registerMockChain(t, 7806, node.URL), clientOpts)
+	require.NoError(t, err)
+	require.NoError(t, client.Call(context.Background(), nil, "eth_blockNumber"))
+	assert.Equal(t, int64(1), proxied.Load())
+
+	_, err = rpc.NewTransport(rpc.TransportOptions{CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+}
+
+// TestInjectedTransportAndDialer tests that checks and clients use injected transports and dialers.
+func TestInjectedTransportAndDialer(t *testing.T) {
+	node := newMockNode(t, func(w http.ResponseWriter, req chainstypes.JsonRPCRequest) { writeResult(w, req, `"0x10"`) })
+	_, wsURL := setupWSServer(t, func(conn *websocket.Conn) {
+		var req chainstypes.JsonRPCRequest
+		if conn.ReadJSON(&req) == nil {
+			_ = conn.WriteJSON(chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID, Result: json.RawMessage(`"0x10"`)})
+		}
+	})
+	registry.RegisterChain(chainstypes.Chain{
+		ID:      big.NewInt(7807),
+		Name:    t.Name(),
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {Http: []string{node.URL}, WebSocket: []string{wsURL}}},
+	})
+
+	transport := &countingTransport{next: http.DefaultTransport}
+	var dials atomic.Int64
+	netDialer := &net.Dialer{}
+	opts := rpc.DefaultCheckOptions()
+	opts.Transport = transport
+	opts.Dialer = &websocket.Dialer{NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		return netDialer.DialContext(ctx, network, addr)
+	}}
+
+	statuses, err := rpc.CheckRPCs(context.Background(), 7807, opts)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	for _, s := range statuses {
+		assert.True(t, s.IsAvailable, "%s: %v", s.URL, s.Error)
+		assert.Positive(t, s.ColdLatency)
+		assert.Positive(t, s.WarmLatency)
+	}
+	assert.Positive(t, transport.count.Load())
+	assert.Equal(t, int64(1), dials.Load())
+}
@@ -0,0 +1,191 @@
+package selector
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/registry"
+)
+
+// ScoreWeights defines the relative weight of each penalty in the composite endpoint score.
+type ScoreWeights struct {
+	Latency   float64
+	BlockLag  float64
+	ErrorRate float64
+}
+
+// DefaultScoreWeights returns weights that favour fresh, reliable endpoints over raw speed.
+func DefaultScoreWeights() ScoreWeights {
+	return ScoreWeights{
+		Latency:   1,
+		BlockLag:  2,
+		ErrorRate: 2,
+	}
+}
+
+// RankedRPC is an endpoint together with the health data it was ranked by.
+type RankedRPC struct {
+	URL       string
+	Score     float64 // 0 (worst) to 1 (best)
+	Latency   time.Duration
+	BlockLag  uint64
+	ErrorRate float64
+}
+
+// endpointHealth is the common input of the ranking functions.
+type endpointHealth struct {
+	url       string
+	available bool
+	syncing   bool
+	degraded  bool
+	latency   time.Duration
+	blockLag  uint64
+	errorRate float64
+}
+
+// RankRPCs orders the endpoints matching criteria by composite score using one-shot statuses,
+// e.g. from rpc.CheckRPCs. Unavailable, syncing or degraded endpoints, endpoints without a
+// status, and endpoints exceeding criteria.MaxLatency or criteria.MaxBlockLag are left out.
+// A single status carries no error rate, so weights.ErrorRate only matters for RankRPCsByStats.
+func RankRPCs(identifier any, criteria RPCCriteria, statuses []types.RPCStatus, weights ScoreWeights) ([]RankedRPC, error) {
+	health := make([]endpointHealth, 0, len(statuses))
+	for _, s := range statuses {
+		health = append(health, endpointHealth{
+			url:       s.URL,
+			available: s.IsAvailable,
+			syncing:   s.IsSyncing,
+			degraded:  s.IsDegraded,
+			latency:   s.Latency,
+			blockLag:  s.BlockLag,
+		})
+	}
+	return rank(identifier, criteria, health, weights)
+}
+
+// RankRPCsByStats orders the endpoints matching criteria by composite score using rolling
+// statistics, e.g. from rpc.Monitor.Snapshot. The latency used is the moving average and the
+// error rate is derived from the success ratio.
+func RankRPCsByStats(identifier any, criteria RPCCriteria, stats []types.EndpointStats, weights ScoreWeights) ([]RankedRPC, error) {
+	health := make([]endpointHealth, 0, len(stats))
+	for _, s := range stats {
+		health = append(health, endpointHealth{
+			url:       s.URL,
+			available: s.LastStatus.IsAvailable,
+			syncing:   s.LastStatus.IsSyncing,
+			degraded:  s.LastStatus.IsDegraded,
+			latency:   s.LatencyEWMA,
+			blockLag:  s.LastStatus.BlockLag,
+			errorRate: 1 - s.SuccessRatio,
+		})
+	}
+	return rank(identifier, criteria, health, weights)
+}
+
+// GetBestRPC returns the endpoint with the highest composite score.
+func GetBestRPC(identifier any, criteria RPCCriteria, statuses []types.RPCStatus) (string, error) {
+	ranked, err := RankRPCs(identifier, criteria, statuses, DefaultScoreWeights())
+	if err != nil {
+		return "", err
+	}
+	return ranked[0].URL, nil
+}
+
+// GetFastestRPC returns the healthy endpoint with the lowest latency.
+func GetFastestRPC(identifier any, criteria RPCCriteria, statuses []types.RPCStatus) (string, error) {
+	ranked, err := RankRPCs(identifier, criteria, statuses, ScoreWeights{Latency: 1})
+	if err != nil {
+		return "", err
+	}
+	return ranked[0].URL, nil
+}
+
+// GetHealthiestRPC returns the endpoint closest to the chain head, preferring lower latency on ties.
+func GetHealthiestRPC(identifier any, criteria RPCCriteria, statuses []types.RPCStatus) (string, error) {
+	ranked, err := RankRPCs(identifier, criteria, statuses, ScoreWeights{BlockLag: 1000, ErrorRate: 1000, Latency: 1})
+	if err != nil {
+		return "", err
+	}
+	return ranked[0].URL, nil
+}
+
+// rank filters health data down to the chain's matching endpoints and sorts them by score.
+func rank(identifier any, criteria RPCCriteria, health []endpointHealth, weights ScoreWeights) ([]RankedRPC, error) {
+	chain, err := registry.FindChain(identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain %v: %w", identifier, err)
+	}
+
+	order := make(map[string]int)
+	for i, u := range matchingRPCs(chain, criteria) {
+		order[u] = i
+	}
+
+	var eligible []endpointHealth
+	var maxLatency time.Duration
+	var maxLag uint64
+	for _, h := range health {
+		if _, ok := order[h.url]; !ok || !h.available || h.syncing || h.degraded {
+			continue
+		}
+		if criteria.MaxLatency > 0 && h.latency > criteria.MaxLatency {
+			continue
+		}
+		if criteria.MaxBlockLag > 0 && h.blockLag > criteria.MaxBlockLag {
+			continue
+		}
+		eligible = append(eligible, h)
+		if h.latency > maxLatency {
+			maxLatency = h.latency
+		}
+		if h.blockLag > maxLag {
+			maxLag = h.blockLag
+		}
+	}
+
+	if len(eligible) == 0 {
+		return nil, fmt.Errorf("no healthy RPC URL found matching criteria for %v", identifier)
+	}
+
+	// Penalties are normalised against the configured limits, or the worst candidate otherwise.
+	if criteria.MaxLatency > 0 {
+		maxLatency = criteria.MaxLatency
+	}
+	if criteria.MaxBlockLag > 0 {
+		maxLag = criteria.MaxBlockLag
+	}
+	totalWeight := weights.Latency + weights.BlockLag + weights.ErrorRate
+
+	ranked := make([]RankedRPC, 0, len(eligible))
+	for _, h := range eligible {
+		var penalty float64
+		if maxLatency > 0 {
+			penalty += weights.Latency * float64(h.latency) / float64(maxLatency)
+		}
+		if maxLag > 0 {
+			penalty += weights.BlockLag * float64(h.blockLag) / float64(maxLag)
+		}
+		penalty += weights.ErrorRate * h.errorRate
+
+		score := 1.0
+		if totalWeight > 0 {
+			score -= penalty / totalWeight
+		}
+		ranked = append(ranked, RankedRPC{
+			URL:       h.url,
+			Score:     score,
+			Latency:   h.latency,
+			BlockLag:  h.blockLag,
+			ErrorRate: h.errorRate,
+		})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return order[ranked[i].URL] < order[ranked[j].URL]
+	})
+	return ranked, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/big"
 	"sort"
+	"time"
 
 	"go-ethereum-chains/internal/types"
 	"go-ethereum-chains/pkg/registry"
@@ -15,6 +16,18 @@ type RPCCriteria struct {
 	AllowHTTP      bool
 	AllowWebSocket bool
 	Providers      []types.ProviderName
+
+	// MaxLatency excludes endpoints slower than this from health-aware selection (0 disables).
+	MaxLatency time.Duration
+	// MaxBlockLag excludes endpoints trailing the head by more blocks from health-aware selection (0 disables).
+	MaxBlockLag uint64
+	// Gates exclude endpoints that any gate currently does not permit, e.g. open circuit breakers.
+	Gates []Gate
+}
+
+// Gate reports whether an endpoint may currently be handed out.
+type Gate interface {
+	Permits(url string) bool
 }
 
 // DefaultRPCCriteria returns default criteria (HTTP only, default/public providers).
@@ -28,33 +41,9 @@ func DefaultRPCCriteria() RPCCriteria {
 
 // GetRandomRPC selects a random configured RPC URL matching criteria using crypto/rand (no availability check).
 func GetRandomRPC(identifier any, criteria RPCCriteria) (string, error) {
-	chain, err := registry.FindChain(identifier)
+	candidates, err := GetRPCs(identifier, criteria)
 	if err != nil {
-		return "", fmt.Errorf("failed to get chain %v: %w", identifier, err)
-	}
-
-	var candidates []string
-	providersToCheck := criteria.Providers
-	if len(providersToCheck) == 0 {
-		providersToCheck = make([]types.ProviderName, 0, len(chain.RPCUrls))
-		for provider := range chain.RPCUrls {
-			providersToCheck = append(providersToCheck, types.ProviderName(provider))
-		}
-	}
-
-	for _, provider := range providersToCheck {
-		if target, ok := chain.RPCUrls[string(provider)]; ok {
-			if criteria.AllowHTTP {
-				candidates = append(candidates, target.Http...)
-			}
-			if criteria.AllowWebSocket {
-				candidates = append(candidates, target.WebSocket...)
-			}
-		}
-	}
-
-	if len(candidates) == 0 {
-		return "", fmt.Errorf("no RPC URL found matching criteria for %v", identifier)
+		return "", err
 	}
 
 	idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(candidates))))
@@ -67,11 +56,31 @@ func GetRandomRPC(identifier any, criteria RPCCriteria) (string, error) {
 
 // GetFirstRPC finds the first configured RPC URL matching criteria (no availability check).
 func GetFirstRPC(identifier any, criteria RPCCriteria) (string, error) {
+	candidates, err := GetRPCs(identifier, criteria)
+	if err != nil {
+		return "", err
+	}
+	return candidates[0], nil
+}
+
+// GetRPCs returns all configured RPC URLs matching criteria, without duplicates (no availability check).
+// Providers are visited in the order given by criteria, or alphabetically if none are given,
+// and HTTP URLs come before WebSocket URLs of the same provider.
+func GetRPCs(identifier any, criteria RPCCriteria) ([]string, error) {
 	chain, err := registry.FindChain(identifier)
 	if err != nil {
-		return "", fmt.Errorf("failed to get chain %v: %w", identifier, err)
+		return nil, fmt.Errorf("failed to get chain %v: %w", identifier, err)
 	}
 
+	candidates := matchingRPCs(chain, criteria)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no RPC URL found matching criteria for %v", identifier)
+	}
+	return candidates, nil
+}
+
+// matchingRPCs collects the chain's RPC URLs that match the criteria.
+func matchingRPCs(chain types.Chain, criteria RPCCriteria) []string {
 	providersToCheck := criteria.Providers
 	if len(providersToCheck) == 0 {
 		providersToCheck = make([]types.ProviderName, 0, len(chain.RPCUrls))
@@ -83,20 +92,37 @@ func GetFirstRPC(identifier any, criteria RPCCriteria) (string, error) {
 		})
 	}
 
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(urls []string) {
+		for _, u := range urls {
+			if u != "" && !seen[u] && Permitted(u, criteria.Gates) {
+				seen[u] = true
+				candidates = append(candidates, u)
+			}
+		}
+	}
+
 	for _, provider := range providersToCheck {
 		if target, ok := chain.RPCUrls[string(provider)]; ok {
 			if criteria.AllowHTTP {
-				if len(target.Http) > 0 {
-					return target.Http[0], nil
-				}
+				add(target.Http)
 			}
 			if criteria.AllowWebSocket {
-				if len(target.WebSocket) > 0 {
-					return target.WebSocket[0], nil
-				}
+				add(target.WebSocket)
 			}
 		}
 	}
 
-	return "", fmt.Errorf("no RPC URL found matching criteria for %v", identifier)
+	return candidates
+}
+
+// Permitted reports whether every gate permits the endpoint.
+func Permitted(url string, gates []Gate) bool {
+	for _, g := range gates {
+		if !g.Permits(url) {
+			return false
+		}
+	}
+	return true
 }
@@ -1,9 +1,12 @@
 package selector
 
 import (
+	"fmt"
 	"math/big"
 	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	"go-ethereum-chains/internal/types"
 	"go-ethereum-chains/pkg/registry"
@@ -214,6 +217,288 @@ func TestGetRandomRPC(t *testing.T) {
 	}
 }
 
+// mockStatuses returns statuses for the test chain's HTTP endpoints.
+func mockStatuses() []types.RPCStatus {
+	return []types.RPCStatus{
+		{URL: "http://default1.com", IsHTTP: true, IsAvailable: true, Latency: 300 * time.Millisecond, BlockNumber: big.NewInt(1000)},
+		{URL: "http://default2.com", IsHTTP: true, IsAvailable: true, Latency: 50 * time.Millisecond, BlockNumber: big.NewInt(960), BlockLag: 40},
+		{URL: "http://public1.com", IsHTTP: true, IsAvailable: true, Latency: 120 * time.Millisecond, BlockNumber: big.NewInt(999), BlockLag: 1},
+		{URL: "ws://default1.com", IsWebSocket: true, IsAvailable: true, Latency: 10 * time.Millisecond, BlockNumber: big.NewInt(1000)},
+		{URL: "http://unknown.com", IsHTTP: true, IsAvailable: true, Latency: time.Millisecond},
+	}
+}
+
+// TestRankRPCs tests health-aware ranking with mocked statuses.
+func TestRankRPCs(t *testing.T) {
+	setupSelectorTest()
+
+	tests := []struct {
+		name     string
+		criteria RPCCriteria
+		statuses []types.RPCStatus
+		weights  ScoreWeights
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "Default Weights Prefer Fresh Endpoints",
+			criteria: DefaultRPCCriteria(),
+			statuses: mockStatuses(),
+			weights:  DefaultScoreWeights(),
+			want:     []string{"http://public1.com", "http://default1.com", "http://default2.com"},
+		},
+		{
+			name:     "Latency Only",
+			criteria: DefaultRPCCriteria(),
+			statuses: mockStatuses(),
+			weights:  ScoreWeights{Latency: 1},
+			want:     []string{"http://default2.com", "http://public1.com", "http://default1.com"},
+		},
+		{
+			name:     "Max Block Lag Excludes Lagging Endpoint",
+			criteria: RPCCriteria{AllowHTTP: true, MaxBlockLag: 10},
+			statuses: mockStatuses(),
+			weights:  ScoreWeights{Latency: 1},
+			want:     []string{"http://public1.com", "http://default1.com"},
+		},
+		{
+			name:     "Max Latency Excludes Slow Endpoint",
+			criteria: RPCCriteria{AllowHTTP: true, MaxLatency: 200 * time.Millisecond},
+			statuses: mockStatuses(),
+			weights:  DefaultScoreWeights(),
+			want:     []string{"http://public1.com", "http://default2.com"},
+		},
+		{
+			name:     "WebSocket Allowed",
+			criteria: RPCCriteria{AllowHTTP: true, AllowWebSocket: true, Providers: []types.ProviderName{types.ProviderDefault}},
+			statuses: mockStatuses(),
+			weights:  ScoreWeights{Latency: 1},
+			want:     []string{"ws://default1.com", "http://default2.com", "http://default1.com"},
+		},
+		{
+			name:     "Unavailable And Syncing Excluded",
+			criteria: DefaultRPCCriteria(),
+			statuses: []types.RPCStatus{
+				{URL: "http://default1.com", IsAvailable: false},
+				{URL: "http://default2.com", IsAvailable: true, IsSyncing: true},
+				{URL: "http://public1.com", IsAvailable: true, Latency: time.Second},
+			},
+			weights: DefaultScoreWeights(),
+			want:    []string{"http://public1.com"},
+		},
+		{
+			name:     "Degraded Excluded",
+			criteria: DefaultRPCCriteria(),
+			statuses: []types.RPCStatus{
+				{URL: "http://default1.com", IsAvailable: true, Latency: time.Millisecond, IsDegraded: true, DegradedReason: "block lag 40"},
+				{URL: "http://default2.com", IsAvailable: true, Latency: 50 * time.Millisecond},
+				{URL: "http://public1.com", IsAvailable: true, Latency: 100 * time.Millisecond},
+			},
+			weights: ScoreWeights{Latency: 1},
+			want:    []string{"http://default2.com", "http://public1.com"},
+		},
+		{
+			name:     "No Healthy Endpoint",
+			criteria: DefaultRPCCriteria(),
+			statuses: []types.RPCStatus{{URL: "http://default1.com", IsAvailable: false}},
+			weights:  DefaultScoreWeights(),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked, err := RankRPCs(testChainID, tt.criteria, tt.statuses, tt.weights)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RankRPCs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, r := range ranked {
+				got = append(got, r.URL)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("RankRPCs() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHealthAwareSelection tests the fastest, healthiest and best selection functions.
+func TestHealthAwareSelection(t *testing.T) {
+	setupSelectorTest()
+	criteria := DefaultRPCCriteria()
+
+	if got, err := GetFastestRPC(testChainID, criteria, mockStatuses()); err != nil || got != "http://default2.com" {
+		t.Errorf("GetFastestRPC() got = %v, err = %v", got, err)
+	}
+	if got, err := GetHealthiestRPC(testChainID, criteria, mockStatuses()); err != nil || got != "http://default1.com" {
+		t.Errorf("GetHealthiestRPC() got = %v, err = %v", got, err)
+	}
+	if got, err := GetBestRPC(testChainID, criteria, mockStatuses()); err != nil || got != "http://public1.com" {
+		t.Errorf("GetBestRPC() got = %v, err = %v", got, err)
+	}
+
+	stats := []types.EndpointStats{
+		{URL: "http://default1.com", LastStatus: types.RPCStatus{IsAvailable: true}, LatencyEWMA: 100 * time.Millisecond, SuccessRatio: 0.5},
+		{URL: "http://public1.com", LastStatus: types.RPCStatus{IsAvailable: true}, LatencyEWMA: 200 * time.Millisecond, SuccessRatio: 1},
+	}
+	ranked, err := RankRPCsByStats(testChainID, criteria, stats, DefaultScoreWeights())
+	if err != nil || len(ranked) != 2 || ranked[0].URL != "http://public1.com" {
+		t.Errorf("RankRPCsByStats() got = %v, err = %v", ranked, err)
+	}
+}
+
+// TestSelector_RoundRobin tests that round-robin cycles through candidates in order.
+func TestSelector_RoundRobin(t *testing.T) {
+	setupSelectorTest()
+
+	sel, err := NewSelector(testChainID, DefaultRPCCriteria(), RoundRobin())
+	if err != nil {
+		t.Fatalf("NewSelector() error = %v", err)
+	}
+
+	want := []string{"http://default1.com", "http://default2.com", "http://public1.com", "http://default1.com"}
+	for i, w := range want {
+		got, err := sel.Next()
+		if err != nil || got != w {
+			t.Errorf("Next() #%d got = %v (err %v), want %v", i, got, err, w)
+		}
+	}
+}
+
+// TestSelector_WeightedRandom tests that weights shape the distribution and zero weights exclude endpoints.
+func TestSelector_WeightedRandom(t *testing.T) {
+	setupSelectorTest()
+
+	strategy := WeightedRandom(map[string]float64{
+		"http://default1.com": 9,
+		"http://default2.com": 0,
+	})
+	sel, err := NewSelector(testChainID, DefaultRPCCriteria(), strategy)
+	if err != nil {
+		t.Fatalf("NewSelector() error = %v", err)
+	}
+
+	counts := make(map[string]int)
+	for i := 0; i < 2000; i++ {
+		got, err := sel.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		counts[got]++
+	}
+	if counts["http://default2.com"] != 0 {
+		t.Errorf("zero-weight endpoint selected %d times", counts["http://default2.com"])
+	}
+	if counts["http://default1.com"] < 5*counts["http://public1.com"] {
+		t.Errorf("weighted distribution off: %v", counts)
+	}
+
+	_, err = WeightedRandom(map[string]float64{"a": 0}).Select([]Endpoint{{URL: "a"}}, "")
+	if err == nil {
+		t.Errorf("expected error when all weights are zero")
+	}
+}
+
+// TestSelector_PowerOfTwoChoices tests that the less loaded endpoint wins.
+func TestSelector_PowerOfTwoChoices(t *testing.T) {
+	setupSelectorTest()
+
+	criteria := RPCCriteria{AllowHTTP: true, Providers: []types.ProviderName{types.ProviderDefault}}
+	sel, err := NewSelector(testChainID, criteria, PowerOfTwoChoices())
+	if err != nil {
+		t.Fatalf("NewSelector() error = %v", err)
+	}
+
+	first, release, err := sel.Acquire("")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		got, err := sel.Next()
+		if err != nil || got == first {
+			t.Fatalf("Next() got = %v (err %v), expected the idle endpoint", got, err)
+		}
+	}
+	release()
+	release() // Releasing twice must not underflow.
+
+	candidates, _ := sel.candidates()
+	for _, c := range candidates {
+		if c.InFlight != 0 {
+			t.Errorf("in-flight count for %s = %d, want 0", c.URL, c.InFlight)
+		}
+	}
+}
+
+// TestSelector_ConsistentHash tests that keys stick to endpoints and mostly survive removals.
+func TestSelector_ConsistentHash(t *testing.T) {
+	strategy := ConsistentHash(0)
+	all := []Endpoint{{URL: "http://a"}, {URL: "http://b"}, {URL: "http://c"}, {URL: "http://d"}}
+
+	assignments := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("session-%d", i)
+		got, err := strategy.Select(all, key)
+		if err != nil {
+			t.Fatalf("Select() error = %v", err)
+		}
+		again, _ := strategy.Select(all, key)
+		if got.URL != again.URL {
+			t.Fatalf("key %s not sticky: %s then %s", key, got.URL, again.URL)
+		}
+		assignments[key] = got.URL
+	}
+
+	reduced := all[:3]
+	moved := 0
+	for key, before := range assignments {
+		got, _ := strategy.Select(reduced, key)
+		if before != "http://d" && got.URL != before {
+			moved++
+		}
+	}
+	if moved != 0 {
+		t.Errorf("%d keys moved although their endpoint was not removed", moved)
+	}
+}
+
+// TestSelector_Concurrent exercises all strategies from many goroutines (run with -race).
+func TestSelector_Concurrent(t *testing.T) {
+	setupSelectorTest()
+
+	strategies := map[string]Strategy{
+		"round-robin": RoundRobin(),
+		"weighted":    WeightedRandom(nil),
+		"p2c":         PowerOfTwoChoices(),
+		"hash":        ConsistentHash(10),
+	}
+	for name, strategy := range strategies {
+		t.Run(name, func(t *testing.T) {
+			sel, err := NewSelector(testChainID, DefaultRPCCriteria(), strategy)
+			if err != nil {
+				t.Fatalf("NewSelector() error = %v", err)
+			}
+			var wg sync.WaitGroup
+			for g := 0; g < 8; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < 100; i++ {
+						_, release, err := sel.Acquire(fmt.Sprintf("key-%d-%d", g, i))
+						if err != nil {
+							t.Errorf("Acquire() error = %v", err)
+							return
+						}
+						release()
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
+
 func setupSelectorTest() {
 	registry.RegisterChain(testChain)
 }
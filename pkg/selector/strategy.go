@@ -0,0 +1,281 @@
+package selector
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNoCandidates is returned by a Strategy when there is nothing to choose from.
+var ErrNoCandidates = errors.New("no candidate endpoints")
+
+// Endpoint is a selection candidate as seen by a Strategy.
+type Endpoint struct {
+	URL string
+	// InFlight is the number of requests currently acquired against this endpoint through the Selector.
+	InFlight int64
+}
+
+// Strategy picks one endpoint out of a non-empty candidate list.
+// Implementations must be safe for concurrent use.
+type Strategy interface {
+	// Select returns one of the candidates. key is an optional caller-supplied affinity key.
+	Select(candidates []Endpoint, key string) (Endpoint, error)
+}
+
+// Selector hands out endpoints of a chain that match criteria using a Strategy.
+// Candidates are re-read from the registry on every call, so RPC updates are picked up.
+type Selector struct {
+	identifier any
+	criteria   RPCCriteria
+	strategy   Strategy
+
+	mu       sync.Mutex
+	inFlight map[string]*atomic.Int64
+}
+
+// NewSelector creates a Selector bound to a chain identified by ID or name.
+func NewSelector(identifier any, criteria RPCCriteria, strategy Strategy) (*Selector, error) {
+	if strategy == nil {
+		return nil, fmt.Errorf("selector strategy cannot be nil")
+	}
+	if _, err := GetRPCs(identifier, criteria); err != nil {
+		return nil, err
+	}
+	return &Selector{
+		identifier: identifier,
+		criteria:   criteria,
+		strategy:   strategy,
+		inFlight:   make(map[string]*atomic.Int64),
+	}, nil
+}
+
+// Next returns the next endpoint chosen by the strategy.
+func (s *Selector) Next() (string, error) {
+	return s.NextForKey("")
+}
+
+// NextForKey returns the endpoint chosen by the strategy for an affinity key.
+func (s *Selector) NextForKey(key string) (string, error) {
+	candidates, err := s.candidates()
+	if err != nil {
+		return "", err
+	}
+	chosen, err := s.strategy.Select(candidates, key)
+	if err != nil {
+		return "", err
+	}
+	return chosen.URL, nil
+}
+
+// Acquire chooses an endpoint and counts it as in flight until release is called.
+func (s *Selector) Acquire(key string) (url string, release func(), err error) {
+	url, err = s.NextForKey(key)
+	if err != nil {
+		return "", nil, err
+	}
+	counter := s.counter(url)
+	counter.Add(1)
+	var once sync.Once
+	return url, func() { once.Do(func() { counter.Add(-1) }) }, nil
+}
+
+// candidates builds the strategy input from the registry and in-flight counters.
+func (s *Selector) candidates() ([]Endpoint, error) {
+	urls, err := GetRPCs(s.identifier, s.criteria)
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]Endpoint, len(urls))
+	for i, u := range urls {
+		candidates[i] = Endpoint{URL: u, InFlight: s.counter(u).Load()}
+	}
+	return candidates, nil
+}
+
+// counter returns the in-flight counter of an endpoint, creating it if needed.
+func (s *Selector) counter(url string) *atomic.Int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.inFlight[url]
+	if !ok {
+		c = new(atomic.Int64)
+		s.inFlight[url] = c
+	}
+	return c
+}
+
+// roundRobin cycles through candidates in order.
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+// RoundRobin returns a Strategy that cycles through the candidates in order.
+func RoundRobin() Strategy {
+	return &roundRobin{}
+}
+
+func (r *roundRobin) Select(candidates []Endpoint, _ string) (Endpoint, error) {
+	if len(candidates) == 0 {
+		return Endpoint{}, ErrNoCandidates
+	}
+	n := r.next.Add(1) - 1
+	return candidates[n%uint64(len(candidates))], nil
+}
+
+// weightedRandom picks candidates with probability proportional to their weight.
+type weightedRandom struct {
+	weights map[string]float64
+}
+
+// WeightedRandom returns a Strategy that picks candidates at random, proportionally to their
+// weight. Endpoints without an entry in weights get weight 1; zero or negative weights exclude them.
+func WeightedRandom(weights map[string]float64) Strategy {
+	copied := make(map[string]float64, len(weights))
+	for u, w := range weights {
+		copied[u] = w
+	}
+	return &weightedRandom{weights: copied}
+}
+
+func (w *weightedRandom) weight(url string) float64 {
+	if weight, ok := w.weights[url]; ok {
+		return max(weight, 0)
+	}
+	return 1
+}
+
+func (w *weightedRandom) Select(candidates []Endpoint, _ string) (Endpoint, error) {
+	var total float64
+	for _, c := range candidates {
+		total += w.weight(c.URL)
+	}
+	if total == 0 {
+		return Endpoint{}, ErrNoCandidates
+	}
+
+	target := rand.Float64() * total
+	for _, c := range candidates {
+		target -= w.weight(c.URL)
+		if target < 0 {
+			return c, nil
+		}
+	}
+	// Rounding may leave target at zero; fall back to the last weighted candidate.
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if w.weight(candidates[i].URL) > 0 {
+			return candidates[i], nil
+		}
+	}
+	return Endpoint{}, ErrNoCandidates
+}
+
+// powerOfTwo samples two candidates and keeps the less loaded one.
+type powerOfTwo struct{}
+
+// PowerOfTwoChoices returns a Strategy that samples two random candidates and picks the one
+// with fewer in-flight requests. Use it with Selector.Acquire so in-flight counts are tracked.
+func PowerOfTwoChoices() Strategy {
+	return powerOfTwo{}
+}
+
+func (powerOfTwo) Select(candidates []Endpoint, _ string) (Endpoint, error) {
+	switch len(candidates) {
+	case 0:
+		return Endpoint{}, ErrNoCandidates
+	case 1:
+		return candidates[0], nil
+	}
+	i := rand.IntN(len(candidates))
+	j := rand.IntN(len(candidates) - 1)
+	if j >= i {
+		j++
+	}
+	if candidates[j].InFlight < candidates[i].InFlight {
+		return candidates[j], nil
+	}
+	return candidates[i], nil
+}
+
+// consistentHash maps keys onto a hash ring of candidates.
+type consistentHash struct {
+	replicas int
+
+	mu        sync.Mutex
+	ringKey   string
+	ring      []uint64
+	ringOwner map[uint64]string
+}
+
+// ConsistentHash returns a Strategy that maps a caller-supplied key to the same endpoint for as
+// long as the candidate set is stable, and moves only a small share of keys when it changes.
+// replicas is the number of virtual nodes per endpoint (defaults to 100). An empty key picks
+// the first candidate.
+func ConsistentHash(replicas int) Strategy {
+	if replicas <= 0 {
+		replicas = 100
+	}
+	return &consistentHash{replicas: replicas}
+}
+
+func (c *consistentHash) Select(candidates []Endpoint, key string) (Endpoint, error) {
+	if len(candidates) == 0 {
+		return Endpoint{}, ErrNoCandidates
+	}
+	if key == "" {
+		return candidates[0], nil
+	}
+
+	byURL := make(map[string]Endpoint, len(candidates))
+	urls := make([]string, 0, len(candidates))
+	for _, cand := range candidates {
+		byURL[cand.URL] = cand
+		urls = append(urls, cand.URL)
+	}
+	sort.Strings(urls)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ringKey := strings.Join(urls, "\n"); ringKey != c.ringKey {
+		c.rebuild(ringKey, urls)
+	}
+
+	h := hashKey(key)
+	idx := sort.Search(len(c.ring), func(i int) bool { return c.ring[i] >= h })
+	if idx == len(c.ring) {
+		idx = 0
+	}
+	return byURL[c.ringOwner[c.ring[idx]]], nil
+}
+
+// rebuild recreates the hash ring for a new candidate set.
+func (c *consistentHash) rebuild(ringKey string, urls []string) {
+	c.ringKey = ringKey
+	c.ring = make([]uint64, 0, len(urls)*c.replicas)
+	c.ringOwner = make(map[uint64]string, len(urls)*c.replicas)
+	for _, u := range urls {
+		for r := 0; r < c.replicas; r++ {
+			h := hashKey(u + "#" + strconv.Itoa(r))
+			if _, taken := c.ringOwner[h]; taken {
+				continue
+			}
+			c.ring = append(c.ring, h)
+			c.ringOwner[h] = u
+		}
+	}
+	sort.Slice(c.ring, func(i, j int) bool { return c.ring[i] < c.ring[j] })
+}
+
+// hashKey hashes a string onto the ring.
+func hashKey(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint64(sum[:8])
+}
@@ -0,0 +1,175 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+
+	"github.com/gorilla/websocket"
+)
+
+// errConnClosed is the cause of a connection closed without a read or write error.
+var errConnClosed = errors.New("websocket connection closed")
+
+// connection is a single WebSocket connection with its in-flight calls and the subscriptions
+// bound to it.
+type connection struct {
+	url string
+	ws  *websocket.Conn
+
+	writeMu sync.Mutex
+	nextID  atomic.Int64
+
+	mu      sync.Mutex
+	pending map[types.JsonRPCID]pendingCall
+	err     error
+	done    chan struct{}
+
+	// subs maps server subscription IDs to subscriptions. It is guarded by Manager.mu.
+	subs map[string]*subscription
+}
+
+// pendingCall waits for the response of a request. hook, if set, runs on the read loop first.
+type pendingCall struct {
+	response chan types.JsonRPCMessage
+	hook     func(result json.RawMessage)
+}
+
+func newConnection(url string, ws *websocket.Conn) *connection {
+	return &connection{
+		url:     url,
+		ws:      ws,
+		pending: make(map[types.JsonRPCID]pendingCall),
+		done:    make(chan struct{}),
+		subs:    make(map[string]*subscription),
+	}
+}
+
+// call sends a request and waits for its result.
+func (c *connection) call(ctx context.Context, method string, params []any, hook func(result json.RawMessage)) (json.RawMessage, error) {
+	req := types.JsonRPCRequest{
+		Version: "2.0",
+		Method:  method,
+		Params:  params,
+		ID:      types.NumberID(c.nextID.Add(1)),
+	}
+	call := pendingCall{response: make(chan types.JsonRPCMessage, 1), hook: hook}
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.pending[req.ID] = call
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, req.ID)
+		c.mu.Unlock()
+	}()
+
+	c.writeMu.Lock()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.ws.SetWriteDeadline(deadline)
+	}
+	err := c.ws.WriteJSON(req)
+	_ = c.ws.SetWriteDeadline(time.Time{})
+	c.writeMu.Unlock()
+	if err != nil {
+		c.close(fmt.Errorf("websocket write failed: %w", err))
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.done:
+		return nil, c.err
+	case msg := <-call.response:
+		if msg.Error != nil {
+			return nil, msg.Error
+		}
+		return msg.Result, nil
+	}
+}
+
+// readLoop reads frames until the connection fails, routing responses to pending calls and
+// notifications to the manager.
+func (c *connection) readLoop(m *Manager) {
+	extend := func() {
+		if m.opts.PingInterval > 0 {
+			_ = c.ws.SetReadDeadline(time.Now().Add(m.opts.PingInterval + m.opts.PongTimeout))
+		}
+	}
+	extend()
+	c.ws.SetPongHandler(func(string) error {
+		extend()
+		return nil
+	})
+
+	for {
+		var msg types.JsonRPCMessage
+		if err := c.ws.ReadJSON(&msg); err != nil {
+			c.close(fmt.Errorf("websocket read failed: %w", err))
+			return
+		}
+		extend()
+
+		if msg.IsNotification() {
+			if n, err := msg.Notification(); err == nil {
+				m.dispatch(c, n)
+			}
+			continue
+		}
+
+		c.mu.Lock()
+		call, ok := c.pending[msg.ID]
+		c.mu.Unlock()
+		if !ok {
+			continue
+		}
+		if call.hook != nil && msg.Error == nil {
+			call.hook(msg.Result)
+		}
+		call.response <- msg
+	}
+}
+
+// keepAlive pings the server until the connection is closed. Missing pongs are detected by
+// the read deadline.
+func (c *connection) keepAlive(interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout)); err != nil {
+				c.close(fmt.Errorf("websocket ping failed: %w", err))
+				return
+			}
+		}
+	}
+}
+
+// close closes the connection once, recording the cause.
+func (c *connection) close(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if err == nil {
+		err = errConnClosed
+	}
+	c.err = err
+	close(c.done)
+	_ = c.ws.Close()
+}
@@ -0,0 +1,468 @@
+// Package subscription manages long-lived WebSocket connections for eth_subscribe. A Manager
+// multiplexes any number of subscriptions over one socket per chain, keeps it alive with pings,
+// reconnects with backoff, fails over between the chain's WebSocket endpoints and transparently
+// resubscribes after every reconnect.
+package subscription
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/rpc"
+	"go-ethereum-chains/pkg/selector"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrClosed ends subscriptions when the Manager stops, and is returned by Run if called twice.
+var ErrClosed = errors.New("subscription manager stopped")
+
+// ErrSlowConsumer ends a subscription whose buffer was full when a notification arrived.
+var ErrSlowConsumer = errors.New("subscription buffer full, subscriber too slow")
+
+// Options defines parameters for a subscription Manager.
+type Options struct {
+	// Criteria selects the chain's WebSocket endpoints, in failover order. HTTP URLs are ignored
+	// and gates are applied on every (re)connect.
+	Criteria selector.RPCCriteria
+	// Dialer opens connections (defaults to rpc.DefaultDialer).
+	Dialer *websocket.Dialer
+	// Header is sent with every handshake, along with the endpoint's RpcTarget.Auth headers.
+	Header http.Header
+	// RequestTimeout bounds eth_subscribe and eth_unsubscribe calls.
+	RequestTimeout time.Duration
+	// PingInterval is the delay between keepalive pings (0 disables them).
+	PingInterval time.Duration
+	// PongTimeout is how long to wait for a pong (or any message) before reconnecting.
+	PongTimeout time.Duration
+	// InitialBackoff is the delay before the first reconnect attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential reconnect backoff.
+	MaxBackoff time.Duration
+	// EventBuffer is the capacity of the event channel.
+	EventBuffer int
+}
+
+// DefaultOptions returns options with 30s keepalive pings and reconnects backing off from
+// 500ms to 30s.
+func DefaultOptions() Options {
+	return Options{
+		Criteria: selector.RPCCriteria{
+			AllowWebSocket: true,
+			Providers:      []types.ProviderName{types.ProviderDefault, types.ProviderPublic},
+		},
+		RequestTimeout: 10 * time.Second,
+		PingInterval:   30 * time.Second,
+		PongTimeout:    10 * time.Second,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		EventBuffer:    64,
+	}
+}
+
+// EventKind is the type of a connection Event.
+type EventKind int
+
+const (
+	// EventConnected is emitted when a connection is established, before resubscribing.
+	EventConnected EventKind = iota
+	// EventDisconnected is emitted when a connection attempt fails or a connection dies.
+	EventDisconnected
+	// EventHeadGap is emitted when a newHeads subscription skips block numbers, typically
+	// after a reconnect.
+	EventHeadGap
+)
+
+// String returns the name of the kind.
+func (k EventKind) String() string {
+	switch k {
+	case EventConnected:
+		return "connected"
+	case EventDisconnected:
+		return "disconnected"
+	case EventHeadGap:
+		return "head-gap"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// HeadGap is an inclusive range of block numbers that no newHeads notification covered.
+type HeadGap struct {
+	From uint64
+	To   uint64
+}
+
+// Event reports a change of the Manager's connection.
+type Event struct {
+	Kind EventKind
+	URL  string
+	// Err is the cause of an EventDisconnected.
+	Err error
+	// Gap is set for EventHeadGap.
+	Gap *HeadGap
+}
+
+// Manager maintains a WebSocket connection to one of a chain's endpoints and the subscriptions
+// made through it. Subscriptions may be created before or while Run is active.
+type Manager struct {
+	chain types.Chain
+	urls  []string
+	opts  Options
+
+	mu       sync.Mutex
+	subs     map[uint64]*subscription
+	conn     *connection
+	nextID   uint64
+	lastHead uint64
+	running  bool
+	stopped  bool
+	events   chan Event
+}
+
+// NewManager creates a Manager for a registered chain identified by ID or name.
+func NewManager(identifier any, opts Options) (*Manager, error) {
+	chain, err := registry.FindChain(identifier)
+	if err != nil {
+		return nil, err
+	}
+
+	criteria := opts.Criteria
+	criteria.AllowHTTP = false
+	criteria.AllowWebSocket = true
+	criteria.Gates = nil
+	urls, err := selector.GetRPCs(chain.ID, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Dialer == nil {
+		opts.Dialer = rpc.DefaultDialer()
+	}
+	if opts.RequestTimeout <= 0 {
+		opts.RequestTimeout = 10 * time.Second
+	}
+
+	return &Manager{
+		chain:  chain,
+		urls:   urls,
+		opts:   opts,
+		subs:   make(map[uint64]*subscription),
+		events: make(chan Event, opts.EventBuffer),
+	}, nil
+}
+
+// Chain returns the chain the manager was created for.
+func (m *Manager) Chain() types.Chain {
+	return m.chain
+}
+
+// Endpoints returns the WebSocket endpoints the manager fails over between, in order.
+func (m *Manager) Endpoints() []string {
+	return slices.Clone(m.urls)
+}
+
+// Events returns the channel on which connection events are published.
+// Events are dropped if the channel is full, and it is closed when Run returns.
+func (m *Manager) Events() <-chan Event {
+	return m.events
+}
+
+// Run connects and keeps a connection alive until ctx is done, then ends all subscriptions
+// with ErrClosed. After a connection dies it waits for the backoff and moves on to the next
+// permitted endpoint.
+func (m *Manager) Run(ctx context.Context) error {
+	m.mu.Lock()
+	if m.running || m.stopped {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	m.running = true
+	m.mu.Unlock()
+	defer m.shutdown()
+
+	var failures int
+	next := 0
+	for {
+		url, ok := m.nextPermitted(next)
+		err := fmt.Errorf("no websocket endpoint currently permitted")
+		if ok {
+			var conn *connection
+			conn, err = m.dial(ctx, url)
+			if err == nil {
+				failures = 0
+				m.emit(Event{Kind: EventConnected, URL: url})
+				err = m.serve(ctx, conn)
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		m.emit(Event{Kind: EventDisconnected, URL: url, Err: err})
+
+		// Fail over to the endpoint after the one that failed.
+		next = (slices.Index(m.urls, url) + 1) % len(m.urls)
+		failures++
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(m.backoff(failures)):
+		}
+	}
+}
+
+// nextPermitted returns the first endpoint from index start on, wrapping around, that every gate permits.
+func (m *Manager) nextPermitted(start int) (string, bool) {
+	for i := range m.urls {
+		url := m.urls[(start+i)%len(m.urls)]
+		if selector.Permitted(url, m.opts.Criteria.Gates) {
+			return url, true
+		}
+	}
+	return "", false
+}
+
+// backoff returns the delay before reconnect attempt n (starting at 1).
+func (m *Manager) backoff(n int) time.Duration {
+	d := m.opts.InitialBackoff
+	for i := 1; i < n && (m.opts.MaxBackoff <= 0 || d < m.opts.MaxBackoff); i++ {
+		d *= 2
+	}
+	if m.opts.MaxBackoff > 0 && d > m.opts.MaxBackoff {
+		d = m.opts.MaxBackoff
+	}
+	return d
+}
+
+// serve resubscribes everything on a fresh connection and blocks until it dies or ctx is done.
+func (m *Manager) serve(ctx context.Context, conn *connection) error {
+	m.mu.Lock()
+	m.conn = conn
+	subs := make([]*subscription, 0, len(m.subs))
+	for _, sub := range m.subs {
+		subs = append(subs, sub)
+	}
+	m.mu.Unlock()
+
+	// The read loop delivers notifications and events, so it must be gone before serve returns
+	// and Run closes the event channel.
+	readDone := make(chan struct{})
+	defer func() {
+		conn.close(nil)
+		<-readDone
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.conn = nil
+		for _, sub := range m.subs {
+			if sub.conn == conn {
+				sub.conn, sub.serverID = nil, ""
+			}
+		}
+	}()
+
+	go func() {
+		defer close(readDone)
+		conn.readLoop(m)
+	}()
+	if m.opts.PingInterval > 0 {
+		go conn.keepAlive(m.opts.PingInterval, m.opts.PongTimeout)
+	}
+
+	slices.SortFunc(subs, func(a, b *subscription) int { return cmp.Compare(a.id, b.id) })
+	for _, sub := range subs {
+		if err := m.activate(ctx, conn, sub); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-conn.done:
+		return conn.err
+	}
+}
+
+// activate sends eth_subscribe for sub on conn, unless it is already active there. JSON-RPC
+// errors end the subscription; transport errors are returned and left to the reconnect.
+func (m *Manager) activate(ctx context.Context, conn *connection, sub *subscription) error {
+	m.mu.Lock()
+	if _, active := m.subs[sub.id]; !active || sub.conn == conn {
+		m.mu.Unlock()
+		return nil
+	}
+	sub.conn = conn
+	m.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, m.opts.RequestTimeout)
+	defer cancel()
+
+	// The hook runs on the read loop, so notifications that follow the response immediately
+	// already find the subscription.
+	_, err := conn.call(ctx, "eth_subscribe", sub.params, func(result json.RawMessage) {
+		var serverID string
+		if json.Unmarshal(result, &serverID) != nil {
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, active := m.subs[sub.id]; active && sub.conn == conn {
+			sub.serverID = serverID
+			conn.subs[serverID] = sub
+		}
+	})
+
+	var rpcErr *types.JsonRPCError
+	if errors.As(err, &rpcErr) {
+		m.end(sub, fmt.Errorf("eth_subscribe %v: %w", sub.params[0], err))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	bound := sub.serverID != ""
+	m.mu.Unlock()
+	if !bound {
+		m.end(sub, fmt.Errorf("eth_subscribe %v: invalid subscription id", sub.params[0]))
+	}
+	return nil
+}
+
+// add registers a subscription and activates it if a connection is up.
+func (m *Manager) add(sub *subscription) {
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		sub.finish(ErrClosed)
+		return
+	}
+	m.nextID++
+	sub.id = m.nextID
+	m.subs[sub.id] = sub
+	conn := m.conn
+	m.mu.Unlock()
+
+	if conn != nil {
+		go func() { _ = m.activate(context.Background(), conn, sub) }()
+	}
+}
+
+// end removes a subscription, finishes it with err and unsubscribes on the server.
+func (m *Manager) end(sub *subscription, err error) {
+	m.mu.Lock()
+	if _, active := m.subs[sub.id]; !active {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.subs, sub.id)
+	conn, serverID := sub.conn, sub.serverID
+	if conn != nil && serverID != "" {
+		delete(conn.subs, serverID)
+	}
+	m.mu.Unlock()
+
+	sub.finish(err)
+	if conn != nil && serverID != "" {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), m.opts.RequestTimeout)
+			defer cancel()
+			_, _ = conn.call(ctx, "eth_unsubscribe", []any{serverID}, nil)
+		}()
+	}
+}
+
+// dispatch delivers a notification to the subscription it belongs to.
+func (m *Manager) dispatch(conn *connection, n types.JsonRPCNotification) {
+	m.mu.Lock()
+	sub := conn.subs[n.Params.Subscription]
+	m.mu.Unlock()
+	if sub == nil {
+		return
+	}
+
+	if sub.heads {
+		m.observeHead(conn.url, n.Params.Result)
+	}
+	if err := sub.deliver(n.Params.Result); err != nil {
+		m.end(sub, err)
+	}
+}
+
+// observeHead tracks the highest head seen and reports skipped block numbers.
+func (m *Manager) observeHead(url string, result json.RawMessage) {
+	var head struct {
+		Number ethtypes.Uint64 `json:"number"`
+	}
+	if json.Unmarshal(result, &head) != nil {
+		return
+	}
+	number := uint64(head.Number)
+
+	m.mu.Lock()
+	var gap *HeadGap
+	if m.lastHead != 0 && number > m.lastHead+1 {
+		gap = &HeadGap{From: m.lastHead + 1, To: number - 1}
+	}
+	m.lastHead = max(m.lastHead, number)
+	m.mu.Unlock()
+
+	if gap != nil {
+		m.emit(Event{Kind: EventHeadGap, URL: url, Gap: gap})
+	}
+}
+
+// emit publishes an event without blocking.
+func (m *Manager) emit(e Event) {
+	select {
+	case m.events <- e:
+	default:
+	}
+}
+
+// shutdown ends all subscriptions and closes the event channel.
+func (m *Manager) shutdown() {
+	m.mu.Lock()
+	m.stopped = true
+	subs := m.subs
+	m.subs = make(map[uint64]*subscription)
+	m.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.finish(ErrClosed)
+	}
+	close(m.events)
+}
+
+// dial opens a connection to url.
+func (m *Manager) dial(ctx context.Context, url string) (*connection, error) {
+	header, err := rpc.AuthHeaders(m.chain.AuthFor(url))
+	if err != nil {
+		return nil, fmt.Errorf("websocket dial %s: %w", url, err)
+	}
+	for name, values := range m.opts.Header {
+		if _, set := header[name]; !set {
+			header[name] = values
+		}
+	}
+
+	ws, resp, err := m.opts.Dialer.DialContext(ctx, url, header)
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("websocket dial %s failed (status: %s): %w", url, resp.Status, err)
+		}
+		return nil, fmt.Errorf("websocket dial %s failed: %w", url, err)
+	}
+	return newConnection(url, ws), nil
+}
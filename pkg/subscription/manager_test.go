@@ -0,0 +1,423 @@
+package subscription_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	chainstypes "go-ethereum-chains/internal/types"
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+	"go-ethereum-chains/pkg/registry"
+	"go-ethereum-chains/pkg/subscription"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wsNode is a local node that implements eth_subscribe and pushes notifications on demand.
+type wsNode struct {
+	URL string
+
+	connections  atomic.Int64
+	subscribes   atomic.Int64
+	unsubscribes chan string
+	// stall makes the node stop reading, and thus stop answering pings, after subscribing.
+	stall   atomic.Bool
+	stopped chan struct{}
+
+	mu    sync.Mutex
+	conns map[*nodeConn]bool
+}
+
+type nodeConn struct {
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+	subs    map[string]string // subscription ID -> kind
+}
+
+func (c *nodeConn) write(v any) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.ws.WriteJSON(v)
+}
+
+func newWSNode(t *testing.T) *wsNode {
+	t.Helper()
+	node := &wsNode{unsubscribes: make(chan string, 16), stopped: make(chan struct{}), conns: make(map[*nodeConn]bool)}
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		node.connections.Add(1)
+		conn := &nodeConn{ws: ws, subs: make(map[string]string)}
+		node.mu.Lock()
+		node.conns[conn] = true
+		node.mu.Unlock()
+		defer func() {
+			node.mu.Lock()
+			delete(node.conns, conn)
+			node.mu.Unlock()
+			ws.Close()
+		}()
+		node.serve(conn)
+	}))
+	t.Cleanup(func() {
+		close(node.stopped)
+		node.kill()
+		server.Close()
+	})
+	node.URL = "ws" + strings.TrimPrefix(server.URL, "http")
+	return node
+}
+
+func (n *wsNode) serve(conn *nodeConn) {
+	for {
+		var req chainstypes.JsonRPCRequest
+		if err := conn.ws.ReadJSON(&req); err != nil {
+			return
+		}
+		resp := chainstypes.JsonRPCResponse{Version: "2.0", ID: req.ID}
+		switch req.Method {
+		case "eth_subscribe":
+			kind := req.Params[0].(string)
+			if kind == "syncing" {
+				resp.Error = &chainstypes.JsonRPCError{Code: -32601, Message: "no such subscription"}
+				break
+			}
+			id := fmt.Sprintf("0x%x", n.subscribes.Add(1))
+			n.mu.Lock()
+			conn.subs[id] = kind
+			n.mu.Unlock()
+			resp.Result, _ = json.Marshal(id)
+		case "eth_unsubscribe":
+			id := req.Params[0].(string)
+			n.mu.Lock()
+			delete(conn.subs, id)
+			n.mu.Unlock()
+			n.unsubscribes <- id
+			resp.Result = json.RawMessage(`true`)
+		}
+		conn.write(resp)
+		if n.stall.Load() {
+			<-n.stopped
+			return
+		}
+	}
+}
+
+// push sends a notification to every subscription of kind on every open connection.
+func (n *wsNode) push(kind string, result string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for conn := range n.conns {
+		for id, k := range conn.subs {
+			if k == kind {
+				conn.write(chainstypes.JsonRPCNotification{
+					Version: "2.0",
+					Method:  "eth_subscription",
+					Params:  chainstypes.JsonRPCSubscription{Subscription: id, Result: json.RawMessage(result)},
+				})
+			}
+		}
+	}
+}
+
+// subscriptionCount returns the number of active subscriptions across connections.
+func (n *wsNode) subscriptionCount() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var count int
+	for conn := range n.conns {
+		count += len(conn.subs)
+	}
+	return count
+}
+
+// kill drops every open connection.
+func (n *wsNode) kill() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for conn := range n.conns {
+		conn.ws.Close()
+	}
+}
+
+func head(number uint64) string {
+	return fmt.Sprintf(`{"number":"0x%x","hash":"0x%064x","parentHash":"0x%064x","timestamp":"0x1"}`, number, number, number-1)
+}
+
+// startManager registers a chain served by the given WebSocket URLs and runs a manager for it.
+func startManager(t *testing.T, chainID int64, opts subscription.Options, urls ...string) *subscription.Manager {
+	t.Helper()
+	registry.RegisterChain(chainstypes.Chain{
+		ID:      big.NewInt(chainID),
+		Name:    t.Name(),
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {WebSocket: urls}},
+	})
+	manager, err := subscription.NewManager(chainID, opts)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- manager.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return manager
+}
+
+func fastOptions() subscription.Options {
+	opts := subscription.DefaultOptions()
+	opts.InitialBackoff = 10 * time.Millisecond
+	opts.MaxBackoff = 50 * time.Millisecond
+	opts.RequestTimeout = time.Second
+	return opts
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		require.True(t, ok, "channel closed")
+		return v
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for a value")
+	}
+	var zero T
+	return zero
+}
+
+// nextEvent returns the next event of the given kind.
+func nextEvent(t *testing.T, m *subscription.Manager, kind subscription.EventKind) subscription.Event {
+	t.Helper()
+	for {
+		if e := receive(t, m.Events()); e.Kind == kind {
+			return e
+		}
+	}
+}
+
+// TestManager_Multiplexing tests several subscriptions sharing one socket.
+func TestManager_Multiplexing(t *testing.T) {
+	node := newWSNode(t)
+	manager := startManager(t, 7800, fastOptions(), node.URL)
+
+	heads1 := manager.SubscribeNewHeads(8)
+	heads2 := manager.SubscribeNewHeads(8)
+	contract := ethtypes.MustParseAddress("0x00000000000000000000000000000000000000aa")
+	logs := manager.SubscribeLogs(ethclient.FilterQuery{Addresses: []ethtypes.Address{contract}}, 8)
+	pending := manager.SubscribePendingTransactions(8)
+
+	require.Eventually(t, func() bool { return node.subscriptionCount() == 4 }, 3*time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(1), node.connections.Load())
+
+	node.push("newHeads", head(10))
+	assert.Equal(t, uint64(10), receive(t, heads1.Chan()).Number)
+	assert.Equal(t, uint64(10), receive(t, heads2.Chan()).Number)
+
+	node.push("logs", `{"address":"0x00000000000000000000000000000000000000aa","topics":[],"data":"0x","blockNumber":"0xa","logIndex":"0x1"}`)
+	assert.Equal(t, contract, receive(t, logs.Chan()).Address)
+
+	txHash := fmt.Sprintf("0x%064x", 0xbeef)
+	node.push("newPendingTransactions", `"`+txHash+`"`)
+	assert.Equal(t, txHash, receive(t, pending.Chan()).Hex())
+
+	heads2.Unsubscribe()
+	receive(t, node.unsubscribes)
+	_, open := <-heads2.Chan()
+	assert.False(t, open)
+	assert.Equal(t, 3, node.subscriptionCount())
+
+	rejected := subscription.Subscribe[json.RawMessage](manager, 1, "syncing")
+	err := receive(t, rejected.Err())
+	assert.ErrorContains(t, err, "no such subscription")
+}
+
+// TestManager_ReconnectAndGap tests transparent resubscription and head gap reporting.
+func TestManager_ReconnectAndGap(t *testing.T) {
+	node := newWSNode(t)
+	manager := startManager(t, 7801, fastOptions(), node.URL)
+	heads := manager.SubscribeNewHeads(8)
+
+	require.Eventually(t, func() bool { return node.subscriptionCount() == 1 }, 3*time.Second, 5*time.Millisecond)
+	node.push("newHeads", head(100))
+	assert.Equal(t, uint64(100), receive(t, heads.Chan()).Number)
+
+	node.kill()
+	nextEvent(t, manager, subscription.EventDisconnected)
+	nextEvent(t, manager, subscription.EventConnected)
+	require.Eventually(t, func() bool { return node.subscriptionCount() == 1 }, 3*time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(2), node.connections.Load())
+
+	node.push("newHeads", head(104))
+	assert.Equal(t, uint64(104), receive(t, heads.Chan()).Number, "the subscription survives the reconnect")
+	gap := nextEvent(t, manager, subscription.EventHeadGap)
+	assert.Equal(t, &subscription.HeadGap{From: 101, To: 103}, gap.Gap)
+}
+
+// TestManager_Failover tests moving to the next endpoint when one is down or stops answering pings.
+func TestManager_Failover(t *testing.T) {
+	stalled := newWSNode(t)
+	stalled.stall.Store(true)
+	healthy := newWSNode(t)
+
+	opts := fastOptions()
+	opts.PingInterval = 50 * time.Millisecond
+	opts.PongTimeout = 50 * time.Millisecond
+	manager := startManager(t, 7802, opts, "ws://127.0.0.1:1", stalled.URL, healthy.URL)
+	heads := manager.SubscribeNewHeads(8)
+
+	// The first endpoint refuses connections; the second accepts the subscription but then
+	// stops answering pings.
+	e := nextEvent(t, manager, subscription.EventDisconnected)
+	assert.Equal(t, "ws://127.0.0.1:1", e.URL)
+	e = nextEvent(t, manager, subscription.EventDisconnected)
+	assert.Equal(t, stalled.URL, e.URL)
+
+	require.Eventually(t, func() bool { return healthy.subscriptionCount() == 1 }, 3*time.Second, 5*time.Millisecond)
+	healthy.push("newHeads", head(7))
+	assert.Equal(t, uint64(7), receive(t, heads.Chan()).Number)
+}
+
+// TestManager_Stop tests that subscriptions end with ErrClosed when the manager stops.
+func TestManager_Stop(t *testing.T) {
+	node := newWSNode(t)
+	registry.RegisterChain(chainstypes.Chain{
+		ID:      big.NewInt(7803),
+		Name:    t.Name(),
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {WebSocket: []string{node.URL}}},
+	})
+	manager, err := subscription.NewManager(7803, fastOptions())
+	require.NoError(t, err)
+	heads := manager.SubscribeNewHeads(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- manager.Run(ctx) }()
+	require.Eventually(t, func() bool { return node.subscriptionCount() == 1 }, 3*time.Second, 5*time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.ErrorIs(t, receive(t, heads.Err()), subscription.ErrClosed)
+	assert.ErrorIs(t, receive(t, manager.SubscribeNewHeads(1).Err()), subscription.ErrClosed)
+	assert.ErrorIs(t, manager.Run(context.Background()), subscription.ErrClosed)
+}
+
+// TestManager_StopWhileStreaming tests that stopping while heads with gaps stream in does not
+// send events on the closed event channel.
+func TestManager_StopWhileStreaming(t *testing.T) {
+	node := newWSNode(t)
+	registry.RegisterChain(chainstypes.Chain{
+		ID:      big.NewInt(7804),
+		Name:    t.Name(),
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {WebSocket: []string{node.URL}}},
+	})
+	opts := fastOptions()
+	opts.EventBuffer = 1024
+
+	for range 20 {
+		manager, err := subscription.NewManager(7804, opts)
+		require.NoError(t, err)
+		manager.SubscribeNewHeads(1024)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- manager.Run(ctx) }()
+		require.Eventually(t, func() bool { return node.subscriptionCount() == 1 }, 3*time.Second, time.Millisecond)
+
+		// Every head skips one, so every notification emits a head gap event.
+		streaming := make(chan struct{})
+		go func() {
+			defer close(streaming)
+			for number := uint64(1); number < 400; number += 2 {
+				node.push("newHeads", head(number))
+			}
+		}()
+		time.Sleep(time.Millisecond)
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+		for range manager.Events() {
+		}
+		<-streaming
+		require.Eventually(t, func() bool { return node.subscriptionCount() == 0 }, 3*time.Second, time.Millisecond)
+	}
+}
+
+// TestManager_Auth tests that the handshake carries the endpoint's auth headers.
+func TestManager_Auth(t *testing.T) {
+	node := newWSNode(t)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ws-token" || r.Header.Get("X-Client") != "test" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		proxyWebSocket(t, w, r, node.URL)
+	}))
+	t.Cleanup(gateway.Close)
+	url := "ws" + strings.TrimPrefix(gateway.URL, "http")
+
+	registry.RegisterChain(chainstypes.Chain{
+		ID:   big.NewInt(7809),
+		Name: t.Name(),
+		RPCUrls: map[string]chainstypes.RpcTarget{"default": {
+			WebSocket: []string{url},
+			Auth:      &chainstypes.AuthConfig{Bearer: &chainstypes.SecretRef{Value: "ws-token"}},
+		}},
+	})
+	opts := fastOptions()
+	opts.Header = http.Header{"X-Client": {"test"}}
+	manager, err := subscription.NewManager(7809, opts)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- manager.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	heads := manager.SubscribeNewHeads(1)
+	require.Eventually(t, func() bool { return node.subscriptionCount() == 1 }, 3*time.Second, 5*time.Millisecond)
+	node.push("newHeads", head(3))
+	assert.Equal(t, uint64(3), receive(t, heads.Chan()).Number)
+}
+
+// proxyWebSocket relays frames between an incoming handshake and a backend WebSocket URL.
+func proxyWebSocket(t *testing.T, w http.ResponseWriter, r *http.Request, backendURL string) {
+	backend, _, err := websocket.DefaultDialer.Dial(backendURL, nil)
+	require.NoError(t, err)
+	defer backend.Close()
+	upgrader := websocket.Upgrader{}
+	client, err := upgrader.Upgrade(w, r, nil)
+	require.NoError(t, err)
+	defer client.Close()
+
+	relay := func(dst, src *websocket.Conn, done chan<- struct{}) {
+		defer close(done)
+		for {
+			kind, data, err := src.ReadMessage()
+			if err != nil || dst.WriteMessage(kind, data) != nil {
+				return
+			}
+		}
+	}
+	up, down := make(chan struct{}), make(chan struct{})
+	go relay(backend, client, up)
+	go relay(client, backend, down)
+	select {
+	case <-up:
+	case <-down:
+	}
+}
@@ -0,0 +1,128 @@
+package subscription
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go-ethereum-chains/pkg/ethclient"
+	"go-ethereum-chains/pkg/ethtypes"
+)
+
+// Subscription delivers the notifications of one eth_subscribe subscription. It survives
+// reconnects; notifications sent while disconnected are lost, which EventHeadGap reports
+// for newHeads.
+type Subscription[T any] struct {
+	m    *Manager
+	sub  *subscription
+	ch   chan T
+	errc chan error
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// subscription is the type-independent state of a Subscription kept by the Manager.
+type subscription struct {
+	id      uint64
+	params  []any
+	heads   bool
+	deliver func(result json.RawMessage) error
+	finish  func(err error)
+
+	// conn and serverID bind the subscription to a connection. They are guarded by Manager.mu.
+	conn     *connection
+	serverID string
+}
+
+// SubscribeNewHeads subscribes to new block headers. buffer is the channel capacity; a
+// subscriber that falls further behind is dropped with ErrSlowConsumer.
+func (m *Manager) SubscribeNewHeads(buffer int) *Subscription[ethclient.Block] {
+	return subscribe[ethclient.Block](m, buffer, true, "newHeads")
+}
+
+// SubscribeLogs subscribes to logs matching the addresses and topics of query. Block ranges
+// are ignored by nodes for subscriptions. Removed logs are delivered with Removed set on reorgs.
+func (m *Manager) SubscribeLogs(query ethclient.FilterQuery, buffer int) *Subscription[ethclient.Log] {
+	query.FromBlock, query.ToBlock, query.BlockHash = "", "", nil
+	return subscribe[ethclient.Log](m, buffer, false, "logs", query)
+}
+
+// SubscribePendingTransactions subscribes to the hashes of transactions entering the node's mempool.
+func (m *Manager) SubscribePendingTransactions(buffer int) *Subscription[ethtypes.Hash] {
+	return subscribe[ethtypes.Hash](m, buffer, false, "newPendingTransactions")
+}
+
+// Subscribe subscribes with arbitrary eth_subscribe params, e.g. a node-specific subscription
+// type, and decodes notifications into T (json.RawMessage leaves them undecoded).
+func Subscribe[T any](m *Manager, buffer int, params ...any) *Subscription[T] {
+	return subscribe[T](m, buffer, false, params...)
+}
+
+// subscribe creates and registers a subscription decoding notifications into T.
+func subscribe[T any](m *Manager, buffer int, heads bool, params ...any) *Subscription[T] {
+	s := &Subscription[T]{
+		m:    m,
+		ch:   make(chan T, buffer),
+		errc: make(chan error, 1),
+	}
+	s.sub = &subscription{
+		params:  params,
+		heads:   heads,
+		deliver: s.deliver,
+		finish:  s.finish,
+	}
+	m.add(s.sub)
+	return s
+}
+
+// Chan returns the channel of notifications. It is closed when the subscription ends.
+func (s *Subscription[T]) Chan() <-chan T {
+	return s.ch
+}
+
+// Err returns a channel that receives the error that ended the subscription, if any, and is
+// then closed. It is closed without a value after Unsubscribe.
+func (s *Subscription[T]) Err() <-chan error {
+	return s.errc
+}
+
+// Unsubscribe ends the subscription and cancels it on the server.
+func (s *Subscription[T]) Unsubscribe() {
+	s.m.end(s.sub, nil)
+}
+
+// deliver decodes a notification and queues it without blocking.
+func (s *Subscription[T]) deliver(result json.RawMessage) error {
+	var v T
+	if err := json.Unmarshal(result, &v); err != nil {
+		return fmt.Errorf("failed to unmarshal %v notification (%s): %w", s.sub.params[0], string(result), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	select {
+	case s.ch <- v:
+		return nil
+	default:
+		return ErrSlowConsumer
+	}
+}
+
+// finish closes the channels once, reporting err.
+func (s *Subscription[T]) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	close(s.ch)
+	if err != nil {
+		s.errc <- err
+	}
+	close(s.errc)
+}